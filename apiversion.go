@@ -0,0 +1,98 @@
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package godeltastream
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/deltastreaminc/go-deltastream/apiv2"
+)
+
+// defaultAPIVersion is the api version used unless WithAPIVersion selects another one.
+const defaultAPIVersion = "v2"
+
+// supportedAPIVersions are the api versions the driver implements.
+var supportedAPIVersions = map[string]bool{"v2": true}
+
+// WithAPIVersion selects the version of the control plane and dataplane apis, e.g. v2. The version is the base path of
+// server urls without a path, including the default server, and of dataplanes unless set with WithDataplaneBasePath.
+// Connecting fails if the driver does not implement the version, or if the control plane does not serve it, which is
+// verified once per connector.
+func WithAPIVersion(version string) func(*connectionOptions) {
+	return func(o *connectionOptions) {
+		o.apiVersion = version
+		o.verifyAPIVersion = true
+	}
+}
+
+// WithDataplaneBasePath sets the base path of the dataplane api, /<api version> by default, e.g. for dataplanes served
+// behind a gateway under another path.
+func WithDataplaneBasePath(path string) func(*connectionOptions) {
+	return func(o *connectionOptions) {
+		o.dataplaneBasePath = path
+	}
+}
+
+// validateAPIVersion defaults the api version and the dataplane base path, and checks that the driver implements the
+// version.
+func (o *connectionOptions) validateAPIVersion() error {
+	if o.apiVersion == "" {
+		o.apiVersion = defaultAPIVersion
+	}
+	if !supportedAPIVersions[o.apiVersion] {
+		supported := make([]string, 0, len(supportedAPIVersions))
+		for v := range supportedAPIVersions {
+			supported = append(supported, v)
+		}
+		sort.Strings(supported)
+		return &ErrClientError{message: fmt.Sprintf("unsupported api version %q, supported versions: %s", o.apiVersion, strings.Join(supported, ", "))}
+	}
+	if o.dataplaneBasePath == "" {
+		o.dataplaneBasePath = "/" + o.apiVersion
+	}
+	return nil
+}
+
+// apiVersionCheck verifies once that the control plane serves the api version selected with WithAPIVersion. Failed
+// checks are attempted again on the next connection.
+type apiVersionCheck struct {
+	mu       sync.Mutex
+	verified bool
+}
+
+func (v *apiVersionCheck) verify(ctx context.Context, client *apiv2.ClientWithResponses, version string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.verified {
+		return nil
+	}
+
+	rsp, err := client.GetVersionWithResponse(ctx)
+	if err != nil {
+		return &ErrInterfaceError{wrapErr: err, message: "unable to send request to server"}
+	}
+	if rsp.StatusCode() != http.StatusOK {
+		return &ErrClientError{message: fmt.Sprintf("server does not serve api version %s. status code: %d", version, rsp.StatusCode())}
+	}
+	v.verified = true
+	return nil
+}
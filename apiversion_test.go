@@ -0,0 +1,94 @@
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package godeltastream
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	. "github.com/onsi/gomega"
+)
+
+func TestAPIVersion(t *testing.T) {
+	g := NewWithT(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("GET", "https://api.deltastream.io/v2/version", httpmock.NewStringResponder(http.StatusOK, `{"major":2,"minor":0,"patch":0}`))
+	httpmock.RegisterResponder("POST", "https://api.deltastream.io/v2/statements",
+		mockSubmitStatementsResponser(g, http.StatusOK, "sometoken", "SELECT * FROM mview_table;", map[string][]byte{}, "fixtures/dataplane-query-200-00000-0.json"),
+	)
+	httpmock.RegisterResponder("GET", "https://dpapi.deltastream.io/gateway/v2/statements/d789687d-4e1b-4649-846e-4f10b722f3ad?partitionID=0&timezone=UTC",
+		mockGetStatementResponser(g, http.StatusOK, "dataplanetoken", "fixtures/list-organizations-200-00000-1.json"),
+	)
+
+	// the api version is the path of servers without one
+	connector, err := ConnectorWithOptions(context.TODO(), WithServer("https://api.deltastream.io"), WithStaticToken("sometoken"),
+		WithAPIVersion("v2"), WithDataplaneBasePath("/gateway/v2"))
+	g.Expect(err).To(BeNil())
+	db := sql.OpenDB(connector)
+	defer db.Close()
+
+	for i := 0; i < 2; i++ {
+		rows, err := db.QueryContext(context.TODO(), "SELECT * FROM mview_table;")
+		g.Expect(err).To(BeNil())
+		for rows.Next() {
+		}
+		g.Expect(rows.Err()).To(BeNil())
+		g.Expect(rows.Close()).To(Succeed())
+	}
+
+	info := httpmock.GetCallCountInfo()
+	g.Expect(info["GET https://api.deltastream.io/v2/version"]).To(Equal(1))
+	g.Expect(info["POST https://api.deltastream.io/v2/statements"]).To(Equal(2))
+	g.Expect(info["GET https://dpapi.deltastream.io/gateway/v2/statements/d789687d-4e1b-4649-846e-4f10b722f3ad?partitionID=0&timezone=UTC"]).To(Equal(2))
+}
+
+func TestAPIVersionUnsupported(t *testing.T) {
+	g := NewWithT(t)
+
+	_, err := ConnectorWithOptions(context.TODO(), WithStaticToken("sometoken"), WithAPIVersion("v3"))
+	var clientErr *ErrClientError
+	g.Expect(errors.As(err, &clientErr)).To(BeTrue())
+	g.Expect(err.Error()).To(ContainSubstring(`unsupported api version "v3"`))
+}
+
+func TestAPIVersionNotServed(t *testing.T) {
+	g := NewWithT(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("GET", "https://api.deltastream.io/v2/version", httpmock.NewStringResponder(http.StatusNotFound, `{"message":"not found"}`))
+
+	connector, err := ConnectorWithOptions(context.TODO(), WithServer("https://api.deltastream.io"), WithStaticToken("sometoken"), WithAPIVersion("v2"))
+	g.Expect(err).To(BeNil())
+	db := sql.OpenDB(connector)
+	defer db.Close()
+
+	// failed checks are not cached
+	for i := 0; i < 2; i++ {
+		err = db.PingContext(context.TODO())
+		var clientErr *ErrClientError
+		g.Expect(errors.As(err, &clientErr)).To(BeTrue())
+		g.Expect(err.Error()).To(ContainSubstring("server does not serve api version v2"))
+	}
+	g.Expect(httpmock.GetCallCountInfo()["GET https://api.deltastream.io/v2/version"]).To(Equal(2))
+}
@@ -0,0 +1,403 @@
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package godeltastream
+
+import (
+	"compress/gzip"
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/textproto"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Attachment is a file uploaded along with a statement, e.g. the source file of a descriptor or function. Statements
+// reference attachments by name, as in CREATE DESCRIPTOR_SOURCE ds WITH ('file' = 'protos.desc').
+//
+// Attachments may be added to the context using WithAttachments or passed as statement arguments, optionally using
+// sql.Named to provide the attachment name.
+type Attachment struct {
+	// Name is the file name the statement uses to reference the attachment.
+	Name string
+	// ContentType of the attachment. Defaults to application/octet-stream.
+	ContentType string
+	// Size of the attachment in bytes. When non zero the number of bytes read from Reader must match.
+	Size int64
+	// Reader provides the attachment content.
+	Reader io.ReadCloser
+}
+
+var attachmentReferenceRegexp = regexp.MustCompile(`(?i)'file'\s*=\s*'([^']*)'`)
+
+// referencedAttachments returns the names of attachments referenced by the statement.
+func referencedAttachments(query string) []string {
+	names := []string{}
+	for _, m := range attachmentReferenceRegexp.FindAllStringSubmatch(query, -1) {
+		names = append(names, m[1])
+	}
+	return names
+}
+
+func (a *Attachment) validate() error {
+	switch {
+	case a.Name == "":
+		return &ErrClientError{message: "attachment name must not be empty"}
+	case strings.ContainsAny(a.Name, `/\`):
+		return &ErrClientError{message: fmt.Sprintf("invalid attachment name %q: name must not contain path separators", a.Name)}
+	case a.Reader == nil:
+		return &ErrClientError{message: fmt.Sprintf("attachment %q has no reader", a.Name)}
+	case a.Size < 0:
+		return &ErrClientError{message: fmt.Sprintf("attachment %q has negative size", a.Name)}
+	}
+	return nil
+}
+
+// statementAttachments collects attachments from the context and the statement arguments, in that order, and validates
+// them against the statement. Only attachments added with AddAttachments may share a name.
+func statementAttachments(ctx context.Context, query string, args []driver.NamedValue) ([]Attachment, error) {
+	var attachments []Attachment
+	names := map[string]bool{}
+	if v, ok := ctx.Value(sqlRequestAttachmentsKey).(*sqlRequestAttachments); ok {
+		for _, a := range v.attachments {
+			attachments = append(attachments, a)
+			names[a.Name] = true
+		}
+	}
+
+	for _, arg := range args {
+		var a Attachment
+		switch v := arg.Value.(type) {
+		case Attachment:
+			a = v
+		case *Attachment:
+			a = *v
+		default:
+			continue
+		}
+		if a.Name == "" {
+			a.Name = arg.Name
+		}
+		if names[a.Name] {
+			return nil, &ErrClientError{message: fmt.Sprintf("duplicate attachment %q", a.Name)}
+		}
+		attachments = append(attachments, a)
+		names[a.Name] = true
+	}
+
+	for _, a := range attachments {
+		if err := a.validate(); err != nil {
+			return nil, err
+		}
+	}
+	for _, name := range referencedAttachments(query) {
+		if !names[name] {
+			return nil, &ErrClientError{message: fmt.Sprintf("attachment %q referenced by statement was not provided", name)}
+		}
+	}
+	return attachments, nil
+}
+
+// uploadChunkSize is the largest chunk of an attachment read at once. Cancellation of the context is checked between
+// chunks.
+const uploadChunkSize = 32 * 1024
+
+// contextReader fails reads once ctx is done so copying large attachments can be aborted.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c *contextReader) Read(b []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	if len(b) > uploadChunkSize {
+		b = b[:uploadChunkSize]
+	}
+	return c.r.Read(b)
+}
+
+// uploadReader streams a statement request body to the server, reporting progress after every chunk.
+type uploadReader struct {
+	contextReader
+	sent     int64
+	total    int64
+	progress UploadProgressFunc
+}
+
+// newUploadReader returns a reader of body, whose size is total or -1 if unknown.
+func newUploadReader(ctx context.Context, body io.Reader, total int64) *uploadReader {
+	u := &uploadReader{
+		contextReader: contextReader{ctx: ctx, r: body},
+		total:         total,
+	}
+	if v, ok := ctx.Value(uploadProgressKey).(UploadProgressFunc); ok {
+		u.progress = v
+	}
+	return u
+}
+
+func (u *uploadReader) Read(b []byte) (int, error) {
+	n, err := u.contextReader.Read(b)
+	u.sent += int64(n)
+	if n > 0 && u.progress != nil {
+		u.progress(u.sent, u.total)
+	}
+	return n, err
+}
+
+// statementBody is the multipart body of a statement request: the request itself followed by the attachments, or the
+// request alone as json, see StatementEncodingAuto. The body is streamed to the server so that attachments are never
+// held in memory. It can be sent again, e.g. to retry
+// the statement, as long as the readers of all attachments implement io.Seeker.
+type statementBody struct {
+	request     []byte
+	attachments []Attachment
+	compressed  []bool // whether the attachment at the same index is sent gzipped, nil if none is
+	boundary    string
+	json        bool // whether the body is the json request, without attachments
+	// length is the size of the body, or -1 if the size of an attachment is not known
+	length int64
+	stream *bodyStream // last stream opened, nil before the body is sent
+	// computePool is the compute pool the statement runs on, empty if the server picks it
+	computePool string
+}
+
+// newStatementBody returns the body of a statement request with attachments, gzipping those flagged in compressed.
+func newStatementBody(request []byte, attachments []Attachment, compressed []bool) (*statementBody, error) {
+	b := &statementBody{
+		request:     request,
+		attachments: attachments,
+		compressed:  compressed,
+		boundary:    multipart.NewWriter(io.Discard).Boundary(),
+	}
+
+	// the size of the body is the size of the multipart framing plus the size of the attachments
+	counter := &countingWriter{}
+	w := multipart.NewWriter(counter)
+	if err := w.SetBoundary(b.boundary); err != nil {
+		return nil, &ErrClientError{message: "error building request", wrapErr: err}
+	}
+	var attachmentsSize int64
+	err := b.writeParts(w, func(_ io.Writer, _ int, a Attachment) error {
+		attachmentsSize += a.Size
+		return nil
+	})
+	if err == nil {
+		err = w.Close()
+	}
+	if err != nil {
+		return nil, &ErrClientError{message: "error building request", wrapErr: err}
+	}
+	b.length = counter.n + attachmentsSize
+	for i, a := range attachments {
+		// the size of compressed attachments is not known in advance
+		if a.Size == 0 || b.gzipped(i) {
+			b.length = -1
+		}
+	}
+	return b, nil
+}
+
+// newJSONStatementBody returns the body of a statement request without attachments, sent as json.
+func newJSONStatementBody(request []byte) *statementBody {
+	return &statementBody{request: request, json: true, length: int64(len(request))}
+}
+
+// gzipped returns whether the attachment at index i is sent gzipped.
+func (b *statementBody) gzipped(i int) bool {
+	return i < len(b.compressed) && b.compressed[i]
+}
+
+func (b *statementBody) contentType() string {
+	if b.json {
+		return "application/json"
+	}
+	return "multipart/form-data; boundary=" + b.boundary
+}
+
+// replayable returns whether the body can be sent again.
+func (b *statementBody) replayable() bool {
+	for _, a := range b.attachments {
+		if _, ok := a.Reader.(io.Seeker); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// open starts streaming the body. If the body was opened before, the previous stream is stopped and its attachments
+// are rewound once it no longer reads them.
+func (b *statementBody) open(ctx context.Context) (*bodyStream, error) {
+	if b.stream != nil {
+		b.stream.Close()
+		<-b.stream.done
+		for _, a := range b.attachments {
+			s, ok := a.Reader.(io.Seeker)
+			if !ok {
+				return nil, &ErrClientError{message: fmt.Sprintf("attachment %q cannot be sent again, its reader is not an io.Seeker", a.Name)}
+			}
+			if _, err := s.Seek(0, io.SeekStart); err != nil {
+				return nil, &ErrClientError{message: fmt.Sprintf("error rewinding attachment %q", a.Name), wrapErr: err}
+			}
+		}
+	}
+
+	pr, pw := io.Pipe()
+	stream := &bodyStream{PipeReader: pr, done: make(chan struct{})}
+	b.stream = stream
+	go func() {
+		defer close(stream.done)
+		var err error
+		if b.json {
+			_, err = pw.Write(b.request)
+		} else {
+			err = b.writeMultipart(ctx, pw)
+		}
+		if err != nil && !errors.Is(err, io.ErrClosedPipe) {
+			stream.setErr(err)
+		}
+		pw.CloseWithError(err)
+	}()
+	return stream, nil
+}
+
+// writeMultipart writes the multipart body to pw, gzipping the attachments flagged for compression.
+func (b *statementBody) writeMultipart(ctx context.Context, pw io.Writer) error {
+	w := multipart.NewWriter(pw)
+	err := w.SetBoundary(b.boundary)
+	if err == nil {
+		err = b.writeParts(w, func(part io.Writer, i int, a Attachment) error {
+			if !b.gzipped(i) {
+				return copyAttachment(ctx, part, a)
+			}
+			zw := gzip.NewWriter(part)
+			if err := copyAttachment(ctx, zw, a); err != nil {
+				return err
+			}
+			return zw.Close()
+		})
+	}
+	if err == nil {
+		err = w.Close()
+	}
+	return err
+}
+
+// writeParts writes the parts of the body to w, using copy to write the content of the attachment at each index.
+func (b *statementBody) writeParts(w *multipart.Writer, copy func(part io.Writer, i int, a Attachment) error) error {
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition", `form-data; name="request";`)
+	h.Set("Content-Type", "application/json")
+	part, err := w.CreatePart(h)
+	if err != nil {
+		return err
+	}
+	if _, err = part.Write(b.request); err != nil {
+		return err
+	}
+
+	for i, a := range b.attachments {
+		contentType := a.ContentType
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		h := make(textproto.MIMEHeader)
+		h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="attachments"; filename=%q`, a.Name))
+		h.Set("Content-Type", contentType)
+		if b.gzipped(i) {
+			h.Set("Content-Encoding", "gzip")
+		}
+		part, err := w.CreatePart(h)
+		if err != nil {
+			return err
+		}
+		if err = copy(part, i, a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyAttachment copies the content of a to w in chunks, checking ctx between chunks.
+func copyAttachment(ctx context.Context, w io.Writer, a Attachment) error {
+	var r io.Reader = &contextReader{ctx: ctx, r: a.Reader}
+	if a.Size > 0 {
+		// stop at one byte more than expected, so that the body does not exceed its announced length
+		r = io.LimitReader(r, a.Size+1)
+	}
+	n, err := io.Copy(w, r)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if errors.Is(err, io.ErrClosedPipe) {
+			return err
+		}
+		return &ErrClientError{message: fmt.Sprintf("error reading attachment %q", a.Name), wrapErr: err}
+	}
+	if a.Size > 0 && n != a.Size {
+		if n > a.Size {
+			return &ErrClientError{message: fmt.Sprintf("attachment %q size mismatch. expected %d bytes, read more", a.Name, a.Size)}
+		}
+		return &ErrClientError{message: fmt.Sprintf("attachment %q size mismatch. expected %d bytes, read %d", a.Name, a.Size, n)}
+	}
+	return nil
+}
+
+// bodyStream is the reading end of a statement body being streamed. Closing it stops the streaming.
+type bodyStream struct {
+	*io.PipeReader
+	done chan struct{} // closed once the body is no longer written
+	mu   sync.Mutex
+	err  error
+}
+
+// streamErr returns the error that stopped the streaming of the body, e.g. an attachment size mismatch.
+func (s *bodyStream) streamErr() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+func (s *bodyStream) setErr(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.err = err
+}
+
+// nopSeekCloser is an io.ReadSeeker with a no-op Close method, so that attachments buffered in memory can be sent
+// again.
+type nopSeekCloser struct {
+	io.ReadSeeker
+}
+
+func (nopSeekCloser) Close() error { return nil }
+
+type countingWriter struct {
+	n int64
+}
+
+func (c *countingWriter) Write(b []byte) (int, error) {
+	c.n += int64(len(b))
+	return len(b), nil
+}
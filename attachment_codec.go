@@ -0,0 +1,78 @@
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package godeltastream
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// Content types of the attachments serialized from Go values.
+const (
+	protobufContentType = "application/x-protobuf"
+	jsonContentType     = "application/json"
+)
+
+// ProtoDescriptorAttachment returns an attachment named name holding the binary encoding of the descriptor set fds, as
+// referenced by CREATE DESCRIPTOR_SOURCE, e.g. the set of the files registered with protoregistry.GlobalFiles.
+func ProtoDescriptorAttachment(name string, fds *descriptorpb.FileDescriptorSet) (Attachment, error) {
+	b, err := proto.Marshal(fds)
+	if err != nil {
+		return Attachment{}, &ErrClientError{message: fmt.Sprintf("unable to encode attachment %q", name), wrapErr: err}
+	}
+	return bufferedAttachment(name, protobufContentType, b), nil
+}
+
+// JSONAttachment returns an attachment named name holding the JSON encoding of v, e.g. an Avro schema. A
+// json.RawMessage is sent as is.
+func JSONAttachment(name string, v any) (Attachment, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return Attachment{}, &ErrClientError{message: fmt.Sprintf("unable to encode attachment %q", name), wrapErr: err}
+	}
+	return bufferedAttachment(name, jsonContentType, b), nil
+}
+
+// WithProtoDescriptorAttachment adds the descriptor set fds to statements executed using ctx as the attachment name,
+// see ProtoDescriptorAttachment and WithAttachments.
+func WithProtoDescriptorAttachment(ctx context.Context, name string, fds *descriptorpb.FileDescriptorSet) (context.Context, error) {
+	a, err := ProtoDescriptorAttachment(name, fds)
+	if err != nil {
+		return ctx, err
+	}
+	return WithAttachments(ctx, a), nil
+}
+
+// WithJSONAttachment adds the JSON encoding of v to statements executed using ctx as the attachment name, see
+// JSONAttachment and WithAttachments.
+func WithJSONAttachment(ctx context.Context, name string, v any) (context.Context, error) {
+	a, err := JSONAttachment(name, v)
+	if err != nil {
+		return ctx, err
+	}
+	return WithAttachments(ctx, a), nil
+}
+
+// bufferedAttachment returns an attachment of b, which can be sent again, e.g. on reauthentication.
+func bufferedAttachment(name, contentType string, b []byte) Attachment {
+	return Attachment{Name: name, ContentType: contentType, Size: int64(len(b)), Reader: nopSeekCloser{bytes.NewReader(b)}}
+}
@@ -0,0 +1,165 @@
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package godeltastream
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// BackfillPhase is the query the rows of a BackfillRows are read from.
+type BackfillPhase int
+
+const (
+	// BackfillPhaseSnapshot reads the rows of the snapshot query.
+	BackfillPhaseSnapshot BackfillPhase = iota
+	// BackfillPhaseTail reads the records of the tail query.
+	BackfillPhaseTail
+)
+
+func (p BackfillPhase) String() string {
+	if p == BackfillPhaseTail {
+		return "tail"
+	}
+	return "snapshot"
+}
+
+// BackfillOptions configure Backfill.
+type BackfillOptions struct {
+	// Overlap moves the snapshot point back, e.g. to account for the clock skew between the client and the server.
+	// Records of the tail query within the overlap are read even if the snapshot already contained them.
+	Overlap time.Duration
+}
+
+// BackfillRows reads the rows of a snapshot query followed by the records of a tail query, see Backfill. Its methods
+// follow those of sql.Rows.
+type BackfillRows struct {
+	ctx       context.Context
+	db        Querier
+	tailQuery string
+	since     time.Time
+	columns   []*sql.ColumnType
+	rows      *sql.Rows
+	phase     BackfillPhase
+	err       error
+}
+
+// Backfill reads the current state of a relation with snapshotQuery, e.g. SELECT * FROM a materialized view, then
+// switches to tailQuery, e.g. SELECT * FROM the changelog stream of the view, starting from the snapshot point. This is
+// the usual way to warm up a cache and keep it up to date.
+//
+// The snapshot point is the time snapshotQuery is submitted, minus opts.Overlap. Records of the tail with an earlier
+// event time are skipped, see WithStreamSince. Records at the snapshot point may be read from both queries, so
+// applying them must be idempotent, e.g. upserts by key. Both queries must return the same columns. The tail query is
+// submitted once the snapshot is read, so db should not be limited to a single connection used elsewhere.
+func Backfill(ctx context.Context, db Querier, snapshotQuery, tailQuery string, opts BackfillOptions) (*BackfillRows, error) {
+	since := time.Now().Add(-opts.Overlap)
+	rows, err := db.QueryContext(ctx, snapshotQuery)
+	if err != nil {
+		return nil, err
+	}
+	columns, err := rows.ColumnTypes()
+	if err != nil {
+		rows.Close()
+		return nil, err
+	}
+	return &BackfillRows{ctx: ctx, db: db, tailQuery: tailQuery, since: since, columns: columns, rows: rows}, nil
+}
+
+// Next prepares the next row for Scan, switching to the tail query once the rows of the snapshot are read. It returns
+// false when the tail ends or on error, see Err.
+func (b *BackfillRows) Next() bool {
+	if b.err != nil || b.rows == nil {
+		return false
+	}
+	if b.rows.Next() {
+		return true
+	}
+	if b.err = b.rows.Err(); b.err != nil || b.phase == BackfillPhaseTail {
+		return false
+	}
+	if b.err = b.rows.Close(); b.err != nil {
+		return false
+	}
+
+	b.phase = BackfillPhaseTail
+	if b.rows, b.err = b.db.QueryContext(WithStreamSince(b.ctx, b.since), b.tailQuery); b.err != nil {
+		return false
+	}
+	columns, err := b.rows.ColumnTypes()
+	if err != nil {
+		b.err = err
+		return false
+	}
+	if b.err = backfillSchema(b.columns).verify(backfillSchema(columns)); b.err != nil {
+		return false
+	}
+	return b.rows.Next()
+}
+
+// backfillSchema describes columns by name and type, as materialized views and their changelog may differ in the
+// nullability of columns.
+func backfillSchema(columns []*sql.ColumnType) resultSchema {
+	desc := make([]string, len(columns))
+	for i, c := range columns {
+		desc[i] = describeColumn(c.Name(), c.DatabaseTypeName(), true)
+	}
+	return newResultSchema(desc)
+}
+
+// Phase returns the query the current row was read from.
+func (b *BackfillRows) Phase() BackfillPhase {
+	return b.phase
+}
+
+// SnapshotPoint returns the time records of the tail query are read from.
+func (b *BackfillRows) SnapshotPoint() time.Time {
+	return b.since
+}
+
+// Columns returns the names of the columns.
+func (b *BackfillRows) Columns() []string {
+	names := make([]string, len(b.columns))
+	for i, c := range b.columns {
+		names[i] = c.Name()
+	}
+	return names
+}
+
+// Scan copies the values of the current row into dest, see sql.Rows.Scan.
+func (b *BackfillRows) Scan(dest ...any) error {
+	if b.rows == nil {
+		return &ErrClientError{message: "rows are closed"}
+	}
+	return b.rows.Scan(dest...)
+}
+
+// Err returns the error that ended Next, if any.
+func (b *BackfillRows) Err() error {
+	return b.err
+}
+
+// Close closes the rows of the current query.
+func (b *BackfillRows) Close() error {
+	if b.rows == nil {
+		return nil
+	}
+	err := b.rows.Close()
+	b.rows = nil
+	return err
+}
@@ -0,0 +1,140 @@
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package godeltastream
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+	. "github.com/onsi/gomega"
+
+	"github.com/deltastreaminc/go-deltastream/apiv2"
+	"github.com/deltastreaminc/go-deltastream/dstest"
+)
+
+// backfillResponder responds with the snapshot result set to the snapshot query and streams the tail from server.
+func backfillResponder(g *WithT, server *dstest.StreamingServer, snapshot string) httpmock.Responder {
+	return func(r *http.Request) (*http.Response, error) {
+		_, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		g.Expect(err).To(BeNil())
+		p, err := multipart.NewReader(r.Body, params["boundary"]).NextPart()
+		g.Expect(err).To(BeNil())
+		req := &apiv2.SubmitStatementJSONRequestBody{}
+		g.Expect(json.NewDecoder(p).Decode(req)).To(Succeed())
+		if req.Statement == "SELECT * FROM pageviews_view;" {
+			rsp := httpmock.NewStringResponse(http.StatusOK, snapshot)
+			rsp.Header.Set("Content-Type", "application/json")
+			return rsp, nil
+		}
+		g.Expect(req.Statement).To(Equal("SELECT * FROM pageviews_changelog;"))
+		return httpmock.NewJsonResponse(http.StatusOK, server.StatementResponse(nil))
+	}
+}
+
+func TestBackfill(t *testing.T) {
+	g := NewWithT(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	at := func(t time.Time) map[string]string {
+		return map[string]string{"timestamp": fmt.Sprint(t.UnixMilli())}
+	}
+	value := func(s string) *string { return &s }
+	now := time.Now()
+	server := dstest.NewStreamingServer(
+		dstest.Metadata(streamingColumns...),
+		dstest.DataWithHeaders(at(now.Add(-time.Hour)), value("1"), value("contained in the snapshot")),
+		dstest.DataWithHeaders(at(now.Add(time.Hour)), value("2"), value("after the snapshot")),
+		dstest.Row("3", "without event time"),
+		dstest.Error("3D007", "topic deleted"),
+	)
+	defer server.Close()
+	httpmock.RegisterResponder("POST", "https://api.deltastream.io/v2/statements", backfillResponder(g, server, `{
+		"sqlState": "00000",
+		"statementID": "d789687d-4e1b-4649-846e-4f10b722f3ad",
+		"createdOn": 1703907114,
+		"metadata": {"encoding": "json", "partitionInfo": [{"rowCount": 2}], "columns": [
+			{"name": "id", "type": "BIGINT", "nullable": false},
+			{"name": "name", "type": "VARCHAR", "nullable": true}
+		], "context": {}},
+		"data": [["1", "snapshot"], ["4", "snapshot"]]
+	}`))
+
+	db := compareDB(g, "https://api.deltastream.io/v2")
+	defer db.Close()
+	rows, err := Backfill(context.TODO(), db, "SELECT * FROM pageviews_view;", "SELECT * FROM pageviews_changelog;", BackfillOptions{})
+	g.Expect(err).To(BeNil())
+	defer rows.Close()
+	g.Expect(rows.Columns()).To(Equal([]string{"id", "name"}))
+	g.Expect(rows.SnapshotPoint()).To(BeTemporally("~", now, time.Second))
+
+	var read []string
+	for rows.Next() {
+		var (
+			id   int64
+			name string
+		)
+		g.Expect(rows.Scan(&id, &name)).To(Succeed())
+		read = append(read, fmt.Sprintf("%s %d %s", rows.Phase(), id, name))
+	}
+	g.Expect(read).To(Equal([]string{
+		"snapshot 1 snapshot",
+		"snapshot 4 snapshot",
+		"tail 2 after the snapshot",
+		"tail 3 without event time",
+	}))
+	var sqlErr ErrSQLError
+	g.Expect(errors.As(rows.Err(), &sqlErr)).To(BeTrue())
+	g.Expect(sqlErr.SQLCode).To(Equal(SqlState3D007))
+}
+
+func TestBackfillSchemaMismatch(t *testing.T) {
+	g := NewWithT(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	server := dstest.NewStreamingServer(dstest.Metadata(dstest.Column{Name: "id", Type: "VARCHAR"}, dstest.Column{Name: "name", Type: "VARCHAR"}))
+	defer server.Close()
+	httpmock.RegisterResponder("POST", "https://api.deltastream.io/v2/statements", backfillResponder(g, server, `{
+		"sqlState": "00000",
+		"statementID": "d789687d-4e1b-4649-846e-4f10b722f3ad",
+		"createdOn": 1703907114,
+		"metadata": {"encoding": "json", "partitionInfo": [{"rowCount": 0}], "columns": [
+			{"name": "id", "type": "BIGINT", "nullable": false},
+			{"name": "name", "type": "VARCHAR", "nullable": true}
+		], "context": {}},
+		"data": []
+	}`))
+
+	db := compareDB(g, "https://api.deltastream.io/v2")
+	defer db.Close()
+	rows, err := Backfill(context.TODO(), db, "SELECT * FROM pageviews_view;", "SELECT * FROM pageviews_changelog;", BackfillOptions{})
+	g.Expect(err).To(BeNil())
+	g.Expect(rows.Next()).To(BeFalse())
+	var schemaErr *ErrSchemaChanged
+	g.Expect(errors.As(rows.Err(), &schemaErr)).To(BeTrue())
+	g.Expect(schemaErr.Current).To(Equal([]string{"id VARCHAR", "name VARCHAR"}))
+	g.Expect(rows.Close()).To(Succeed())
+}
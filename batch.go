@@ -0,0 +1,194 @@
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package godeltastream
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Compile time validation that our types implement the expected interfaces
+var (
+	_ driver.ConnBeginTx = &Conn{}
+	_ driver.Tx          = &batchTx{}
+)
+
+// BatchMode selects how the statements of a transaction are submitted at commit.
+type BatchMode int
+
+const (
+	// BatchSequential submits statements one at a time and stops at the first failure.
+	BatchSequential BatchMode = iota
+	// BatchScript submits all statements as a single multi-statement script.
+	BatchScript
+)
+
+// ErrBatchStatement is returned by Commit when a statement of a transaction fails. Statements before it were applied.
+type ErrBatchStatement struct {
+	// Index of the failed statement in the transaction, starting at 0.
+	Index int
+	// Statement is the failed statement.
+	Statement string
+	wrapErr   error
+}
+
+func (e *ErrBatchStatement) Error() string {
+	return fmt.Sprintf("statement %d of transaction failed: %s", e.Index+1, e.wrapErr)
+}
+
+func (e *ErrBatchStatement) Unwrap() error {
+	return e.wrapErr
+}
+
+type batchStatement struct {
+	query       string
+	attachments []Attachment
+}
+
+// batchTx is a pseudo-transaction: DeltaStream has no transactions, so statements executed within it are buffered and
+// submitted at Commit. Rollback discards them. Statements already submitted are not undone on failure.
+type batchTx struct {
+	c          *Conn
+	ctx        context.Context
+	mode       BatchMode
+	statements []batchStatement
+}
+
+// BeginTx implements driver.ConnBeginTx. Exec statements are buffered until Commit, queries are not supported within a
+// transaction. Only the default isolation level is supported and transactions cannot be read only.
+func (c *Conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if c.client == nil {
+		return nil, driver.ErrBadConn
+	}
+	if opts.ReadOnly || sql.IsolationLevel(opts.Isolation) != sql.LevelDefault {
+		return nil, ErrNotSupported
+	}
+
+	c.Lock()
+	defer c.Unlock()
+	if c.tx != nil {
+		return nil, &ErrClientError{message: "transaction already in progress"}
+	}
+	mode, _ := ctx.Value(batchModeKey).(BatchMode)
+	c.tx = &batchTx{c: c, ctx: ctx, mode: mode}
+	return c.tx, nil
+}
+
+func (c *Conn) currentTx() *batchTx {
+	c.RLock()
+	defer c.RUnlock()
+	return c.tx
+}
+
+func (c *Conn) endTx() {
+	c.Lock()
+	defer c.Unlock()
+	c.tx = nil
+}
+
+// add buffers a statement. Attachments are read now, as their readers may not outlive the call to Exec.
+func (tx *batchTx) add(ctx context.Context, query string, attachments []Attachment) error {
+	buffered := make([]Attachment, len(attachments))
+	for i, a := range attachments {
+		b, err := io.ReadAll(&contextReader{ctx: ctx, r: a.Reader})
+		if err != nil {
+			return &ErrClientError{message: fmt.Sprintf("error reading attachment %q", a.Name), wrapErr: err}
+		}
+		a.Reader = nopSeekCloser{bytes.NewReader(b)}
+		a.Size = int64(len(b))
+		buffered[i] = a
+	}
+	tx.statements = append(tx.statements, batchStatement{query: query, attachments: buffered})
+	return nil
+}
+
+// Commit implements driver.Tx.
+func (tx *batchTx) Commit() error {
+	defer tx.c.endTx()
+
+	if len(tx.statements) == 0 {
+		return nil
+	}
+
+	if tx.mode == BatchScript {
+		var (
+			queries     []string
+			attachments []Attachment
+			seen        = map[string]struct{}{}
+		)
+		for _, s := range tx.statements {
+			queries = append(queries, terminateStatement(s.query))
+			// attachments of a statement may share a name, see AddAttachments
+			for _, a := range s.attachments {
+				if _, ok := seen[a.Name]; ok {
+					return &ErrClientError{message: fmt.Sprintf("attachment %q is used by more than one statement of the transaction", a.Name)}
+				}
+			}
+			for _, a := range s.attachments {
+				seen[a.Name] = struct{}{}
+				attachments = append(attachments, a)
+			}
+		}
+		_, err := tx.c.submitStatement(tx.ctx, attachments, strings.Join(queries, "\n"))
+		return err
+	}
+
+	for i, s := range tx.statements {
+		if _, err := tx.c.submitStatement(tx.ctx, s.attachments, s.query); err != nil {
+			return &ErrBatchStatement{Index: i, Statement: s.query, wrapErr: err}
+		}
+	}
+	return nil
+}
+
+// terminateStatement adds the ; ending query if it is missing, so that it does not run into the next statement of a
+// script. The ; goes on a new line if query ends with a -- comment.
+func terminateStatement(query string) string {
+	query = strings.TrimSpace(query)
+	segments := lexSQL(query)
+	var (
+		last    sqlSegment // last segment that is not a comment or whitespace
+		hasCode bool
+	)
+	for _, seg := range segments {
+		if seg.kind != sqlSegmentComment && strings.TrimSpace(query[seg.start:seg.end]) != "" {
+			last, hasCode = seg, true
+		}
+	}
+	switch {
+	case !hasCode:
+		return query
+	case last.kind == sqlSegmentCode && strings.HasSuffix(strings.TrimSpace(query[last.start:last.end]), ";"):
+		return query
+	case strings.HasPrefix(query[segments[len(segments)-1].start:], "--"):
+		return query + "\n;"
+	default:
+		return query + ";"
+	}
+}
+
+// Rollback implements driver.Tx.
+func (tx *batchTx) Rollback() error {
+	tx.statements = nil
+	tx.c.endTx()
+	return nil
+}
@@ -0,0 +1,266 @@
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package godeltastream
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// CatalogObjectType is the type of the catalog objects watched by a CatalogWatcher.
+type CatalogObjectType string
+
+// Types of catalog objects.
+const (
+	CatalogDatabase CatalogObjectType = "database"
+	CatalogSchema   CatalogObjectType = "schema"
+	CatalogStore    CatalogObjectType = "store"
+	CatalogRelation CatalogObjectType = "relation"
+)
+
+// catalogObjectTypes are all the types of catalog objects, in the order their events are delivered.
+var catalogObjectTypes = []CatalogObjectType{CatalogDatabase, CatalogSchema, CatalogRelation, CatalogStore}
+
+// CatalogChange is the change of a catalog object reported by a CatalogEvent.
+type CatalogChange string
+
+// Changes of catalog objects.
+const (
+	CatalogCreated CatalogChange = "created"
+	CatalogDropped CatalogChange = "dropped"
+	// CatalogAltered reports a change to the listed columns of an object, e.g. its owner or state.
+	CatalogAltered CatalogChange = "altered"
+)
+
+// CatalogEvent is a change of a catalog object found by a CatalogWatcher.
+type CatalogEvent struct {
+	Change CatalogChange
+	Type   CatalogObjectType
+	// Name is the fully qualified name of the object, e.g. "db"."public"."pageviews" for relations.
+	Name string
+	// Values are the columns listing the object by name, as scanned into any, the last ones listed for dropped objects.
+	Values map[string]any
+}
+
+// CatalogWatchOptions control how a CatalogWatcher polls the catalog.
+type CatalogWatchOptions struct {
+	// Interval is the delay between polls of Watch. Defaults to 30s.
+	Interval time.Duration
+	// Types are the types of objects to report events for. Defaults to all of them.
+	Types []CatalogObjectType
+}
+
+// CatalogWatcher reports the databases, schemas, stores and relations created, dropped or altered since it last
+// polled the catalog, e.g. to refresh cached LIST results. The API has no change feed for the catalog, so every poll
+// runs LIST DATABASES, LIST STORES, then LIST SCHEMAS for every database and LIST RELATIONS for every schema, and
+// compares their results with those of the previous poll. Changes between two polls that cancel out, e.g. a relation
+// dropped and created again with the same columns, are not reported.
+type CatalogWatcher struct {
+	db      Queryer
+	opts    CatalogWatchOptions
+	objects map[catalogObjectKey]map[string]any // listed by the last poll, nil before the first one
+}
+
+type catalogObjectKey struct {
+	objectType CatalogObjectType
+	name       string
+}
+
+// NewCatalogWatcher returns a CatalogWatcher listing the catalog with db.
+func NewCatalogWatcher(db Queryer, opts CatalogWatchOptions) *CatalogWatcher {
+	if opts.Interval <= 0 {
+		opts.Interval = 30 * time.Second
+	}
+	if len(opts.Types) == 0 {
+		opts.Types = catalogObjectTypes
+	}
+	return &CatalogWatcher{db: db, opts: opts}
+}
+
+// Poll lists the catalog and returns the events of the objects changed since the previous poll, ordered by type then
+// name. The first poll only records the catalog and returns no events. After an error the next poll compares the
+// catalog with the one listed before the failed poll.
+func (w *CatalogWatcher) Poll(ctx context.Context) ([]CatalogEvent, error) {
+	objects, err := w.listCatalog(ctx)
+	if err != nil {
+		return nil, err
+	}
+	previous := w.objects
+	w.objects = objects
+	if previous == nil {
+		return nil, nil
+	}
+
+	var events []CatalogEvent
+	for key, values := range objects {
+		if old, ok := previous[key]; !ok {
+			events = append(events, CatalogEvent{Change: CatalogCreated, Type: key.objectType, Name: key.name, Values: values})
+		} else if fmt.Sprint(old) != fmt.Sprint(values) {
+			events = append(events, CatalogEvent{Change: CatalogAltered, Type: key.objectType, Name: key.name, Values: values})
+		}
+	}
+	for key, values := range previous {
+		if _, ok := objects[key]; !ok {
+			events = append(events, CatalogEvent{Change: CatalogDropped, Type: key.objectType, Name: key.name, Values: values})
+		}
+	}
+	rank := map[CatalogObjectType]int{}
+	for i, t := range catalogObjectTypes {
+		rank[t] = i
+	}
+	sort.Slice(events, func(i, j int) bool {
+		if events[i].Type != events[j].Type {
+			return rank[events[i].Type] < rank[events[j].Type]
+		}
+		return events[i].Name < events[j].Name
+	})
+	return events, nil
+}
+
+// Watch polls the catalog every opts.Interval, see Poll, and calls handler with the events of every poll. It returns
+// the error of a poll or of handler, or the error of ctx once done.
+func (w *CatalogWatcher) Watch(ctx context.Context, handler func(CatalogEvent) error) error {
+	for {
+		events, err := w.Poll(ctx)
+		if err != nil {
+			return err
+		}
+		for _, e := range events {
+			if err := handler(e); err != nil {
+				return err
+			}
+		}
+
+		t := time.NewTimer(w.opts.Interval)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// listCatalog returns the objects of the types watched by their key, with the columns listing them.
+func (w *CatalogWatcher) listCatalog(ctx context.Context) (map[catalogObjectKey]map[string]any, error) {
+	watched := map[CatalogObjectType]bool{}
+	for _, t := range w.opts.Types {
+		watched[t] = true
+	}
+	objects := map[catalogObjectKey]map[string]any{}
+	add := func(objectType CatalogObjectType, name string, values map[string]any) {
+		if watched[objectType] {
+			objects[catalogObjectKey{objectType: objectType, name: name}] = values
+		}
+	}
+
+	if watched[CatalogStore] {
+		stores, err := listCatalogObjects(ctx, w.db, "LIST STORES;")
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range stores {
+			add(CatalogStore, quoteIdentifier(s.name), s.values)
+		}
+	}
+	if !watched[CatalogDatabase] && !watched[CatalogSchema] && !watched[CatalogRelation] {
+		return objects, nil
+	}
+	databases, err := listCatalogObjects(ctx, w.db, "LIST DATABASES;")
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range databases {
+		database := quoteIdentifier(d.name)
+		add(CatalogDatabase, database, d.values)
+		if !watched[CatalogSchema] && !watched[CatalogRelation] {
+			continue
+		}
+		schemas, err := listCatalogObjects(ctx, w.db, fmt.Sprintf("LIST SCHEMAS IN DATABASE %s;", database))
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range schemas {
+			schema := database + "." + quoteIdentifier(s.name)
+			add(CatalogSchema, schema, s.values)
+			if !watched[CatalogRelation] {
+				continue
+			}
+			relations, err := listCatalogObjects(ctx, w.db, fmt.Sprintf("LIST RELATIONS IN SCHEMA %s;", schema))
+			if err != nil {
+				return nil, err
+			}
+			for _, r := range relations {
+				add(CatalogRelation, schema+"."+quoteIdentifier(r.name), r.values)
+			}
+		}
+	}
+	return objects, nil
+}
+
+type catalogObject struct {
+	name   string
+	values map[string]any
+}
+
+// listCatalogObjects runs the LIST statement query and returns the objects listed, named by their name column.
+func listCatalogObjects(ctx context.Context, db Queryer, query string) ([]catalogObject, error) {
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	nameIdx := -1
+	for i, c := range columns {
+		if strings.EqualFold(c, "name") {
+			nameIdx = i
+		}
+	}
+	if nameIdx == -1 {
+		return nil, &ErrInterfaceError{message: fmt.Sprintf("result of %s has no name column", query)}
+	}
+
+	var objects []catalogObject
+	for rows.Next() {
+		values := make([]any, len(columns))
+		dest := make([]any, len(columns))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		o := catalogObject{name: fmt.Sprint(values[nameIdx]), values: make(map[string]any, len(columns))}
+		for i, c := range columns {
+			o.values[c] = values[i]
+		}
+		objects = append(objects, o)
+	}
+	return objects, rows.Err()
+}
+
+// quoteIdentifier returns name as a quoted SQL identifier.
+func quoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
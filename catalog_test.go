@@ -0,0 +1,164 @@
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package godeltastream
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+	. "github.com/onsi/gomega"
+
+	"github.com/deltastreaminc/go-deltastream/apiv2"
+)
+
+// catalogResponder lists the objects of catalog by LIST statement, each as its name and owner, and records the
+// statements run.
+func catalogResponder(g *WithT, statements *[]string, catalog map[string][]string) httpmock.Responder {
+	return func(r *http.Request) (*http.Response, error) {
+		_, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		g.Expect(err).To(BeNil())
+		// the body of requests canceled by the end of Watch cannot be read
+		p, err := multipart.NewReader(r.Body, params["boundary"]).NextPart()
+		if err != nil {
+			return nil, err
+		}
+		req := &apiv2.SubmitStatementJSONRequestBody{}
+		if err := json.NewDecoder(p).Decode(req); err != nil {
+			return nil, err
+		}
+		*statements = append(*statements, req.Statement)
+
+		var rows []string
+		for _, o := range catalog[req.Statement] {
+			name, owner, _ := strings.Cut(o, "/")
+			rows = append(rows, fmt.Sprintf("[%q, %q]", name, owner))
+		}
+		rsp := httpmock.NewStringResponse(http.StatusOK, fmt.Sprintf(`{
+			"sqlState": "00000",
+			"statementID": "d789687d-4e1b-4649-846e-4f10b722f3ad",
+			"createdOn": 1703907114,
+			"metadata": {"encoding": "json", "partitionInfo": [{"rowCount": %d}], "columns": [
+				{"name": "name", "type": "VARCHAR", "nullable": false},
+				{"name": "owner", "type": "VARCHAR", "nullable": false}
+			], "context": {}},
+			"data": [%s]
+		}`, len(rows), strings.Join(rows, ",")))
+		rsp.Header.Set("Content-Type", "application/json")
+		return rsp, nil
+	}
+}
+
+func TestCatalogWatcher(t *testing.T) {
+	g := NewWithT(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	var statements []string
+	catalog := map[string][]string{
+		"LIST STORES;":                             {"kafka/sysadmin"},
+		"LIST DATABASES;":                          {"db/sysadmin", "old/sysadmin"},
+		`LIST SCHEMAS IN DATABASE "db";`:           {"public/sysadmin"},
+		`LIST SCHEMAS IN DATABASE "old";`:          {"public/sysadmin"},
+		`LIST RELATIONS IN SCHEMA "db"."public";`:  {"pageviews/sysadmin", "users/sysadmin"},
+		`LIST RELATIONS IN SCHEMA "old"."public";`: {"archive/sysadmin"},
+	}
+	httpmock.RegisterResponder("POST", "https://api.deltastream.io/v2/statements", catalogResponder(g, &statements, catalog))
+	db := compareDB(g, "https://api.deltastream.io/v2")
+	defer db.Close()
+
+	w := NewCatalogWatcher(db, CatalogWatchOptions{})
+	events, err := w.Poll(context.TODO())
+	g.Expect(err).To(BeNil())
+	g.Expect(events).To(BeEmpty())
+	events, err = w.Poll(context.TODO())
+	g.Expect(err).To(BeNil())
+	g.Expect(events).To(BeEmpty())
+
+	catalog["LIST DATABASES;"] = []string{"db/sysadmin"}
+	catalog[`LIST RELATIONS IN SCHEMA "db"."public";`] = []string{"pageviews/analyst", "clicks/sysadmin"}
+	catalog["LIST STORES;"] = []string{"kafka/sysadmin", "kinesis/sysadmin"}
+	events, err = w.Poll(context.TODO())
+	g.Expect(err).To(BeNil())
+	var changes []string
+	for _, e := range events {
+		changes = append(changes, fmt.Sprintf("%s %s %s", e.Change, e.Type, e.Name))
+	}
+	g.Expect(changes).To(Equal([]string{
+		`dropped database "old"`,
+		`dropped schema "old"."public"`,
+		`created relation "db"."public"."clicks"`,
+		`altered relation "db"."public"."pageviews"`,
+		`dropped relation "db"."public"."users"`,
+		`dropped relation "old"."public"."archive"`,
+		`created store "kinesis"`,
+	}))
+	g.Expect(events[3].Values).To(Equal(map[string]any{"name": "pageviews", "owner": "analyst"}))
+	g.Expect(events[4].Values).To(Equal(map[string]any{"name": "users", "owner": "sysadmin"}))
+}
+
+func TestCatalogWatcherTypes(t *testing.T) {
+	g := NewWithT(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	var statements []string
+	catalog := map[string][]string{
+		"LIST STORES;":    {"kafka/sysadmin"},
+		"LIST DATABASES;": {"db/sysadmin"},
+	}
+	httpmock.RegisterResponder("POST", "https://api.deltastream.io/v2/statements", catalogResponder(g, &statements, catalog))
+	db := compareDB(g, "https://api.deltastream.io/v2")
+	defer db.Close()
+
+	// only the statements listing the types watched are run
+	w := NewCatalogWatcher(db, CatalogWatchOptions{Types: []CatalogObjectType{CatalogStore}, Interval: time.Millisecond})
+	events, err := w.Poll(context.TODO())
+	g.Expect(err).To(BeNil())
+	g.Expect(events).To(BeEmpty())
+	g.Expect(statements).To(Equal([]string{"LIST STORES;"}))
+
+	catalog["LIST DATABASES;"] = nil
+	catalog["LIST STORES;"] = []string{"kafka/sysadmin", "kinesis/sysadmin"}
+	ctx, cancel := context.WithTimeout(context.TODO(), 5*time.Second)
+	defer cancel()
+	errStop := errors.New("stop")
+	var watched []CatalogEvent
+	err = w.Watch(ctx, func(e CatalogEvent) error {
+		watched = append(watched, e)
+		return errStop
+	})
+	g.Expect(err).To(MatchError(errStop))
+	g.Expect(watched).To(HaveLen(1))
+	g.Expect(watched[0].Change).To(Equal(CatalogCreated))
+	g.Expect(watched[0].Name).To(Equal(`"kinesis"`))
+
+	// Watch polls until ctx is done
+	ctx, cancel = context.WithTimeout(context.TODO(), 50*time.Millisecond)
+	defer cancel()
+	err = w.Watch(ctx, func(e CatalogEvent) error { return errStop })
+	g.Expect(err).To(MatchError(context.DeadlineExceeded))
+	g.Expect(len(statements)).To(BeNumerically(">", 2))
+}
@@ -0,0 +1,73 @@
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package godeltastream
+
+import (
+	"context"
+	"database/sql/driver"
+
+	"github.com/google/uuid"
+
+	"github.com/deltastreaminc/go-deltastream/apiv2"
+)
+
+// Compile time validation that our types implement the expected interfaces
+var (
+	_ StatementSubmitter = &Conn{}
+	_ ResultFetcher      = &Conn{}
+	_ ResultFetcher      = &DPConn{}
+	_ Streamer           = &Conn{}
+)
+
+// StatementSubmitter submits statements, see Conn.SubmitRequest. Code depending on it rather than on Conn can be unit
+// tested with the mocks of the dsmock package.
+type StatementSubmitter interface {
+	SubmitRequest(ctx context.Context, req *StatementRequest) (driver.Rows, error)
+}
+
+// ResultFetcher fetches the result sets of submitted statements, one partition at a time. It is implemented by Conn for
+// results served by the control plane and by DPConn for results served by a dataplane.
+type ResultFetcher interface {
+	GetStatement(ctx context.Context, statementID uuid.UUID, partitionID int32) (*apiv2.ResultSet, error)
+}
+
+// Streamer opens the streaming results of submitted statements, see Conn.OpenStream.
+type Streamer interface {
+	OpenStream(ctx context.Context, statementID uuid.UUID, req apiv2.DataplaneRequest) (driver.Rows, error)
+}
+
+// GetStatement returns the partition partitionID of the result set of the statement statementID, waiting for the
+// statement to complete.
+func (c *Conn) GetStatement(ctx context.Context, statementID uuid.UUID, partitionID int32) (*apiv2.ResultSet, error) {
+	return c.getStatement(ctx, statementID, partitionID)
+}
+
+// GetStatement returns the partition partitionID of the result set of the statement statementID, waiting for the
+// statement to complete.
+func (c *DPConn) GetStatement(ctx context.Context, statementID uuid.UUID, partitionID int32) (*apiv2.ResultSet, error) {
+	return c.getStatement(ctx, statementID, partitionID)
+}
+
+// OpenStream opens the streaming result of the statement statementID served by the dataplane of req, as returned in the
+// metadata of its result set. Dialing is retried as configured with WithStreamDialRetry.
+func (c *Conn) OpenStream(ctx context.Context, statementID uuid.UUID, req apiv2.DataplaneRequest) (driver.Rows, error) {
+	rows, err := c.openStream(ctx, statementID, req)
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
@@ -0,0 +1,67 @@
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package godeltastream
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jarcoal/httpmock"
+	. "github.com/onsi/gomega"
+
+	"github.com/deltastreaminc/go-deltastream/dstest"
+)
+
+func TestConnGetStatementAndOpenStream(t *testing.T) {
+	g := NewWithT(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	statementID := uuid.MustParse("d789687d-4e1b-4649-846e-4f10b722f3ad")
+	httpmock.RegisterResponder("GET", `=~^https://api\.deltastream\.io/v2/statements/d789687d-4e1b-4649-846e-4f10b722f3ad`,
+		mockGetStatementResponser(g, 200, "sometoken", "fixtures/list-organizations-200-00000-1.json"))
+	server := dstest.NewStreamingServer(dstest.Metadata(streamingColumns...), dstest.Row("1", "a"))
+	defer server.Close()
+
+	connector, err := ConnectorWithOptions(context.TODO(), WithServer("https://api.deltastream.io/v2"), WithStaticToken("sometoken"))
+	g.Expect(err).To(BeNil())
+	conn, err := sql.OpenDB(connector).Conn(context.Background())
+	g.Expect(err).To(BeNil())
+	defer conn.Close()
+
+	g.Expect(conn.Raw(func(driverConn any) error {
+		var fetcher ResultFetcher = driverConn.(*Conn)
+		rs, err := fetcher.GetStatement(context.Background(), statementID, 0)
+		g.Expect(err).To(BeNil())
+		g.Expect(rs.StatementID).To(Equal(statementID))
+		g.Expect(*rs.Data).To(HaveLen(1))
+
+		var streamer Streamer = driverConn.(*Conn)
+		statement := server.StatementResponse(nil)
+		rows, err := streamer.OpenStream(context.Background(), statement.StatementID, *statement.Metadata.DataplaneRequest)
+		g.Expect(err).To(BeNil())
+		defer rows.Close()
+		g.Expect(rows.Columns()).To(Equal([]string{"id", "name"}))
+		dest := make([]driver.Value, 2)
+		g.Expect(rows.Next(dest)).To(Succeed())
+		g.Expect(dest).To(Equal([]driver.Value{int64(1), "a"}))
+		return nil
+	})).To(Succeed())
+}
@@ -0,0 +1,178 @@
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package godeltastream
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/deltastreaminc/go-deltastream/apiv2"
+	"github.com/deltastreaminc/go-deltastream/dpapiv2"
+)
+
+// JSONCodec encodes and decodes the JSON documents exchanged with the server. It allows replacing encoding/json with a
+// faster implementation such as jsoniter.ConfigCompatibleWithStandardLibrary or sonic.ConfigStd.
+type JSONCodec interface {
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+type stdlibJSONCodec struct{}
+
+func (stdlibJSONCodec) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (stdlibJSONCodec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+func readResponseBody(rsp *http.Response) ([]byte, error) {
+	defer func() { _ = rsp.Body.Close() }()
+	return io.ReadAll(rsp.Body)
+}
+
+func isJSONResponse(rsp *http.Response) bool {
+	return strings.Contains(rsp.Header.Get("Content-Type"), "json")
+}
+
+// unmarshalResponse decodes body into dest, which is a field of the response r.
+func unmarshalResponse[R any](codec JSONCodec, body []byte, r *R, dest any) (*R, error) {
+	if err := codec.Unmarshal(body, dest); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// parseSubmitStatementResponse is apiv2.ParseSubmitStatementResponse using codec.
+func parseSubmitStatementResponse(codec JSONCodec, rsp *http.Response) (*apiv2.SubmitStatementResponse, error) {
+	body, err := readResponseBody(rsp)
+	if err != nil {
+		return nil, err
+	}
+	r := &apiv2.SubmitStatementResponse{Body: body, HTTPResponse: rsp}
+	if !isJSONResponse(rsp) {
+		return r, nil
+	}
+	switch rsp.StatusCode {
+	case http.StatusOK:
+		r.JSON200 = &apiv2.ResultSet{}
+		return unmarshalResponse(codec, body, r, r.JSON200)
+	case http.StatusAccepted:
+		r.JSON202 = &apiv2.StatementStatus{}
+		return unmarshalResponse(codec, body, r, r.JSON202)
+	case http.StatusBadRequest:
+		r.JSON400 = &apiv2.ErrorResponse{}
+		return unmarshalResponse(codec, body, r, r.JSON400)
+	case http.StatusForbidden:
+		r.JSON403 = &apiv2.ErrorResponse{}
+		return unmarshalResponse(codec, body, r, r.JSON403)
+	case http.StatusNotFound:
+		r.JSON404 = &apiv2.ErrorResponse{}
+		return unmarshalResponse(codec, body, r, r.JSON404)
+	case http.StatusRequestTimeout:
+		r.JSON408 = &apiv2.ErrorResponse{}
+		return unmarshalResponse(codec, body, r, r.JSON408)
+	case http.StatusInternalServerError:
+		r.JSON500 = &apiv2.ErrorResponse{}
+		return unmarshalResponse(codec, body, r, r.JSON500)
+	case http.StatusServiceUnavailable:
+		r.JSON503 = &apiv2.ErrorResponse{}
+		return unmarshalResponse(codec, body, r, r.JSON503)
+	}
+	return r, nil
+}
+
+// parseGetStatementStatusResponse is apiv2.ParseGetStatementStatusResponse using codec.
+func parseGetStatementStatusResponse(codec JSONCodec, rsp *http.Response) (*apiv2.GetStatementStatusResponse, error) {
+	body, err := readResponseBody(rsp)
+	if err != nil {
+		return nil, err
+	}
+	r := &apiv2.GetStatementStatusResponse{Body: body, HTTPResponse: rsp}
+	if !isJSONResponse(rsp) {
+		return r, nil
+	}
+	switch rsp.StatusCode {
+	case http.StatusOK:
+		r.JSON200 = &apiv2.ResultSet{}
+		return unmarshalResponse(codec, body, r, r.JSON200)
+	case http.StatusAccepted:
+		r.JSON202 = &apiv2.StatementStatus{}
+		return unmarshalResponse(codec, body, r, r.JSON202)
+	case http.StatusBadRequest:
+		r.JSON400 = &apiv2.ErrorResponse{}
+		return unmarshalResponse(codec, body, r, r.JSON400)
+	case http.StatusForbidden:
+		r.JSON403 = &apiv2.ErrorResponse{}
+		return unmarshalResponse(codec, body, r, r.JSON403)
+	case http.StatusNotFound:
+		r.JSON404 = &apiv2.ErrorResponse{}
+		return unmarshalResponse(codec, body, r, r.JSON404)
+	case http.StatusRequestTimeout:
+		r.JSON408 = &apiv2.ErrorResponse{}
+		return unmarshalResponse(codec, body, r, r.JSON408)
+	case http.StatusInternalServerError:
+		r.JSON500 = &apiv2.ErrorResponse{}
+		return unmarshalResponse(codec, body, r, r.JSON500)
+	case http.StatusServiceUnavailable:
+		r.JSON503 = &apiv2.ErrorResponse{}
+		return unmarshalResponse(codec, body, r, r.JSON503)
+	}
+	return r, nil
+}
+
+// parseDPGetStatementStatusResponse is dpapiv2.ParseGetStatementStatusResponse using codec.
+func parseDPGetStatementStatusResponse(codec JSONCodec, rsp *http.Response) (*dpapiv2.GetStatementStatusResponse, error) {
+	body, err := readResponseBody(rsp)
+	if err != nil {
+		return nil, err
+	}
+	r := &dpapiv2.GetStatementStatusResponse{Body: body, HTTPResponse: rsp}
+	if !isJSONResponse(rsp) {
+		return r, nil
+	}
+	switch rsp.StatusCode {
+	case http.StatusOK:
+		r.JSON200 = &dpapiv2.ResultSet{}
+		return unmarshalResponse(codec, body, r, r.JSON200)
+	case http.StatusAccepted:
+		r.JSON202 = &dpapiv2.StatementStatus{}
+		return unmarshalResponse(codec, body, r, r.JSON202)
+	case http.StatusBadRequest:
+		r.JSON400 = &dpapiv2.ErrorResponse{}
+		return unmarshalResponse(codec, body, r, r.JSON400)
+	case http.StatusForbidden:
+		r.JSON403 = &dpapiv2.ErrorResponse{}
+		return unmarshalResponse(codec, body, r, r.JSON403)
+	case http.StatusNotFound:
+		r.JSON404 = &dpapiv2.ErrorResponse{}
+		return unmarshalResponse(codec, body, r, r.JSON404)
+	case http.StatusRequestTimeout:
+		r.JSON408 = &dpapiv2.ErrorResponse{}
+		return unmarshalResponse(codec, body, r, r.JSON408)
+	case http.StatusInternalServerError:
+		r.JSON500 = &dpapiv2.ErrorResponse{}
+		return unmarshalResponse(codec, body, r, r.JSON500)
+	case http.StatusServiceUnavailable:
+		r.JSON503 = &dpapiv2.ErrorResponse{}
+		return unmarshalResponse(codec, body, r, r.JSON503)
+	}
+	return r, nil
+}
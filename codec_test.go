@@ -0,0 +1,85 @@
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package godeltastream
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	. "github.com/onsi/gomega"
+)
+
+type countingJSONCodec struct {
+	stdlibJSONCodec
+	marshalled   int
+	unmarshalled int
+}
+
+func (c *countingJSONCodec) Marshal(v any) ([]byte, error) {
+	c.marshalled++
+	return c.stdlibJSONCodec.Marshal(v)
+}
+
+func (c *countingJSONCodec) Unmarshal(data []byte, v any) error {
+	c.unmarshalled++
+	return c.stdlibJSONCodec.Unmarshal(data, v)
+}
+
+func TestJSONCodec(t *testing.T) {
+	g := NewWithT(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("POST", "https://api.deltastream.io/v2/statements",
+		mockSubmitStatementsResponser(g, http.StatusOK, "sometoken", "SELECT * FROM mview_table;", map[string][]byte{}, "fixtures/dataplane-query-200-00000-0.json"),
+	)
+	httpmock.RegisterResponder("GET", "https://dpapi.deltastream.io/v2/statements/d789687d-4e1b-4649-846e-4f10b722f3ad?partitionID=0&timezone=UTC",
+		mockGetStatementResponser(g, http.StatusOK, "dataplanetoken", "fixtures/list-organizations-200-00000-1.json"),
+	)
+
+	codec := &countingJSONCodec{}
+	connector, err := ConnectorWithOptions(context.TODO(), WithServer("https://api.deltastream.io/v2"), WithStaticToken("sometoken"), WithJSONCodec(codec))
+	g.Expect(err).To(BeNil())
+	db := sql.OpenDB(connector)
+
+	rows, err := db.Query("SELECT * FROM mview_table;")
+	g.Expect(err).To(BeNil())
+	g.Expect(rows.Columns()).To(Equal([]string{"id", "name", "description", "profileImageURI", "createdAt"}))
+	g.Expect(rows.Close()).To(BeNil())
+	g.Expect(codec.marshalled).To(Equal(1))
+	g.Expect(codec.unmarshalled).To(Equal(2))
+}
+
+func TestNilJSONCodec(t *testing.T) {
+	g := NewWithT(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("POST", "https://api.deltastream.io/v2/statements",
+		mockSubmitStatementsResponser(g, http.StatusOK, "sometoken", "LIST ORGANIZATIONS;", map[string][]byte{}, "fixtures/list-organizations-200-00000-0.json"),
+	)
+
+	connector, err := ConnectorWithOptions(context.TODO(), WithServer("https://api.deltastream.io/v2"), WithStaticToken("sometoken"), WithJSONCodec(nil))
+	g.Expect(err).To(BeNil())
+	db := sql.OpenDB(connector)
+
+	_, err = db.Exec("LIST ORGANIZATIONS;")
+	g.Expect(err).To(BeNil())
+}
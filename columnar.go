@@ -0,0 +1,205 @@
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package godeltastream
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/deltastreaminc/go-deltastream/apiv2"
+)
+
+// Compile time validation that our types implement the expected interfaces
+var (
+	_ ColumnarReader = &resultSetRows{}
+)
+
+// ColumnarReader is implemented by the driver.Rows of result sets, to read them one partition at a time, column by
+// column, e.g. to build dataframes without boxing every value into an interface as Next does. database/sql does not
+// expose the driver.Rows it wraps, use sql.Conn.Raw and Conn.QueryContext to access it.
+type ColumnarReader interface {
+	// NextBlock returns the rows of the current partition not yet read, fetching the next partition first if all of
+	// them were read. It returns io.EOF once all rows were read. NextBlock and Next may be interleaved.
+	NextBlock() (*ColumnBlock, error)
+}
+
+// ColumnBlock holds rows of a result set column by column.
+type ColumnBlock struct {
+	// Columns are the names of the columns.
+	Columns []string
+	// Values holds a slice per column, of the type values of the column are decoded into: []int64 for TINYINT,
+	// SMALLINT, INTEGER and BIGINT columns, []float64 for FLOAT, DOUBLE and DECIMAL columns, []string or []Decimal for
+	// DECIMAL columns depending on WithDecimalMode, []bool for BOOLEAN columns, []TimeOfDay for TIME columns,
+	// []time.Time for TIMESTAMP columns and for TIME columns with WithLegacyTimeColumns, [][]byte for VARBINARY and
+	// BYTES columns, []json.RawMessage for ARRAY, MAP and STRUCT columns with WithRawJSONColumns and []string for all
+	// other columns. Null values are zero values, see Nulls.
+	Values []any
+	// Nulls holds, per column, whether the value of every row is null. It is nil for columns without null values.
+	Nulls [][]bool
+	// Len is the number of rows.
+	Len int
+}
+
+// ColumnValues returns the values of the column index of block, or nil if they are not of type T.
+func ColumnValues[T any](block *ColumnBlock, index int) []T {
+	if index < 0 || index >= len(block.Values) {
+		return nil
+	}
+	values, _ := block.Values[index].([]T)
+	return values
+}
+
+// NextBlock implements ColumnarReader.
+func (r *resultSetRows) NextBlock() (*ColumnBlock, error) {
+	if r.closed {
+		return nil, &ErrStreamClosed{}
+	}
+	rowIdx, partIdx := r.calcPartitionIdx(r.currentRowIdx + 1)
+	if partIdx == -1 {
+		return nil, io.EOF
+	}
+	if partIdx != r.currentPartitionIdx {
+		if err := r.fetchPartition(partIdx); err != nil {
+			return nil, err
+		}
+	}
+
+	rows := (*r.currentResultSet.Data)[rowIdx:min(r.currentResultSet.Metadata.PartitionInfo[partIdx].RowCount, int32(len(*r.currentResultSet.Data)))]
+	block, err := newColumnBlock(r.currentResultSet.Metadata.Columns, rows, r.decodeOptions)
+	if err != nil {
+		return nil, err
+	}
+	r.currentRowIdx += int32(len(rows))
+	r.rowsRead += int64(len(rows))
+	return block, nil
+}
+
+// newColumnBlock decodes rows into a block of columns. Conversion errors are returned as *ErrColumnDecode.
+func newColumnBlock(columns apiv2.ResultSetColumns, rows [][]*string, opts decodeOptions) (*ColumnBlock, error) {
+	block := &ColumnBlock{
+		Columns: make([]string, len(columns)),
+		Values:  make([]any, len(columns)),
+		Nulls:   make([][]bool, len(columns)),
+		Len:     len(rows),
+	}
+	for _, row := range rows {
+		if len(row) != len(columns) {
+			return nil, &ErrInterfaceError{message: fmt.Sprintf("number of values does not match number of columns. expected %d, got %d", len(columns), len(row))}
+		}
+	}
+	for i, col := range columns {
+		block.Columns[i] = col.Name
+		vector := newColumnVector(col.Type, len(rows), opts)
+		for j, row := range rows {
+			if row[i] == nil {
+				if block.Nulls[i] == nil {
+					block.Nulls[i] = make([]bool, len(rows))
+				}
+				block.Nulls[i][j] = true
+				continue
+			}
+			if err := vector.set(j, *row[i]); err != nil {
+				return nil, &ErrColumnDecode{Column: col.Name, Type: col.Type, RawValue: *row[i], Err: err}
+			}
+		}
+		block.Values[i] = vector.slice()
+	}
+	return block, nil
+}
+
+// columnVector decodes the values of a column into a typed slice.
+type columnVector interface {
+	set(i int, s string) error
+	slice() any
+}
+
+type typedVector[T any] struct {
+	values []T
+	decode func(s string) (T, error)
+}
+
+func (v *typedVector[T]) set(i int, s string) (err error) {
+	v.values[i], err = v.decode(s)
+	return err
+}
+
+func (v *typedVector[T]) slice() any {
+	return v.values
+}
+
+func newTypedVector[T any](n int, decode func(s string) (T, error)) columnVector {
+	return &typedVector[T]{values: make([]T, n), decode: decode}
+}
+
+// newColumnVector returns the vector of n values of colType, decoded as newColumnDecoder does without boxing them.
+func newColumnVector(colType string, n int, opts decodeOptions) columnVector {
+	switch {
+	case opts.rawJSONColumns && isJSONColumn(colType):
+		return newTypedVector(n, func(s string) (json.RawMessage, error) { return json.RawMessage(s), nil })
+	case
+		colType == "TINYINT",
+		colType == "SMALLINT",
+		colType == "INTEGER":
+		return newTypedVector(n, func(s string) (int64, error) { return strconv.ParseInt(s, 10, 64) })
+	case colType == "BIGINT":
+		return newTypedVector(n, decodeInt64)
+	case strings.HasPrefix(colType, "DECIMAL") && opts.decimalMode == DecimalModeString:
+		return newTypedVector(n, func(s string) (string, error) { return s, nil })
+	case strings.HasPrefix(colType, "DECIMAL") && opts.decimalMode == DecimalModeDecimal:
+		return newTypedVector(n, ParseDecimal)
+	case
+		colType == "FLOAT",
+		colType == "DOUBLE",
+		strings.HasPrefix(colType, "DECIMAL"):
+		return newTypedVector(n, func(s string) (float64, error) { return strconv.ParseFloat(s, 64) })
+	case colType == "BOOLEAN":
+		return newTypedVector(n, func(s string) (bool, error) { return strings.EqualFold(s, "true"), nil })
+	case !opts.legacyTimeColumns && isTimeOfDayColumn(colType):
+		return newTypedVector(n, ParseTimeOfDay)
+	case strings.HasPrefix(colType, "TIME"):
+		return newTypedVector(n, opts.parseTime(colType))
+	case
+		colType == "VARBINARY",
+		colType == "BYTES":
+		return newTypedVector(n, base64.StdEncoding.DecodeString)
+	default:
+		return newTypedVector(n, func(s string) (string, error) { return s, nil })
+	}
+}
+
+// decodeInt64 decodes BIGINT values, which the server may send in exponent notation, failing for values out of the
+// range of int64 rather than returning a *big.Int as decodeBigint does.
+func decodeInt64(s string) (int64, error) {
+	v, err := decodeBigint(s)
+	if err != nil {
+		return 0, err
+	}
+	switch v := v.(type) {
+	case int64:
+		return v, nil
+	case *big.Int:
+		if v.IsInt64() {
+			return v.Int64(), nil
+		}
+	}
+	return 0, fmt.Errorf("value out of range of int64")
+}
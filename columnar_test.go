@@ -0,0 +1,132 @@
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package godeltastream
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+	. "github.com/onsi/gomega"
+)
+
+func TestColumnarReader(t *testing.T) {
+	g := NewWithT(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("POST", "https://api.deltastream.io/v2/statements", compareResponder(`{
+		"sqlState": "00000",
+		"statementID": "d789687d-4e1b-4649-846e-4f10b722f3ad",
+		"createdOn": 1703907114,
+		"metadata": {"encoding": "json", "partitionInfo": [{"rowCount": 3}, {"rowCount": 1}], "columns": [
+			{"name": "id", "type": "BIGINT", "nullable": false},
+			{"name": "amount", "type": "DECIMAL(10, 2)", "nullable": true},
+			{"name": "updated", "type": "TIMESTAMP(3)", "nullable": false},
+			{"name": "active", "type": "BOOLEAN", "nullable": false},
+			{"name": "note", "type": "VARCHAR", "nullable": true}
+		], "context": {}},
+		"data": [
+			["1", "10.25", "2024-01-01 10:00:00.000", "true", "a"],
+			["2", null, "2024-01-02 10:00:00.000", "false", null],
+			["3e0", "30.00", "2024-01-03 10:00:00.000", "TRUE", "c"]
+		]
+	}`))
+	httpmock.RegisterResponder("GET", "https://api.deltastream.io/v2/statements/d789687d-4e1b-4649-846e-4f10b722f3ad?partitionID=1&timezone=UTC", compareResponder(`{
+		"sqlState": "00000",
+		"statementID": "d789687d-4e1b-4649-846e-4f10b722f3ad",
+		"createdOn": 1703907114,
+		"metadata": {"encoding": "json", "partitionInfo": [{"rowCount": 3}, {"rowCount": 1}], "columns": [], "context": {}},
+		"data": [["not a number", "40.00", "2024-01-04 10:00:00.000", "true", "d"]]
+	}`))
+
+	connector, err := ConnectorWithOptions(context.TODO(), WithServer("https://api.deltastream.io/v2"), WithStaticToken("sometoken"))
+	g.Expect(err).To(BeNil())
+	conn, err := sql.OpenDB(connector).Conn(context.Background())
+	g.Expect(err).To(BeNil())
+	defer conn.Close()
+
+	g.Expect(conn.Raw(func(driverConn any) error {
+		rows, err := driverConn.(*Conn).QueryContext(context.Background(), "SELECT * FROM orders;", nil)
+		g.Expect(err).To(BeNil())
+		defer rows.Close()
+		reader := rows.(ColumnarReader)
+
+		// rows read with Next are not part of the block
+		dest := make([]driver.Value, 5)
+		g.Expect(rows.Next(dest)).To(Succeed())
+		g.Expect(dest[0]).To(Equal(int64(1)))
+
+		block, err := reader.NextBlock()
+		g.Expect(err).To(BeNil())
+		g.Expect(block.Columns).To(Equal([]string{"id", "amount", "updated", "active", "note"}))
+		g.Expect(block.Len).To(Equal(2))
+		g.Expect(ColumnValues[int64](block, 0)).To(Equal([]int64{2, 3}))
+		g.Expect(ColumnValues[float64](block, 1)).To(Equal([]float64{0, 30}))
+		g.Expect(ColumnValues[time.Time](block, 2)).To(Equal([]time.Time{
+			time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC),
+			time.Date(2024, 1, 3, 10, 0, 0, 0, time.UTC),
+		}))
+		g.Expect(ColumnValues[bool](block, 3)).To(Equal([]bool{false, true}))
+		g.Expect(ColumnValues[string](block, 4)).To(Equal([]string{"", "c"}))
+		g.Expect(ColumnValues[string](block, 0)).To(BeNil())
+		g.Expect(block.Nulls).To(Equal([][]bool{nil, {true, false}, nil, nil, {true, false}}))
+
+		// the next partition fails to decode
+		_, err = reader.NextBlock()
+		var decodeErr *ErrColumnDecode
+		g.Expect(errors.As(err, &decodeErr)).To(BeTrue())
+		g.Expect(decodeErr.Column).To(Equal("id"))
+		g.Expect(decodeErr.RawValue).To(Equal("not a number"))
+		return nil
+	})).To(Succeed())
+
+	// blocks of every partition until io.EOF
+	httpmock.RegisterResponder("GET", "https://api.deltastream.io/v2/statements/d789687d-4e1b-4649-846e-4f10b722f3ad?partitionID=1&timezone=UTC", compareResponder(`{
+		"sqlState": "00000",
+		"statementID": "d789687d-4e1b-4649-846e-4f10b722f3ad",
+		"createdOn": 1703907114,
+		"metadata": {"encoding": "json", "partitionInfo": [{"rowCount": 3}, {"rowCount": 1}], "columns": [], "context": {}},
+		"data": [["4", "40.00", "2024-01-04 10:00:00.000", "true", "d"]]
+	}`))
+	g.Expect(conn.Raw(func(driverConn any) error {
+		rows, err := driverConn.(*Conn).QueryContext(context.Background(), "SELECT * FROM orders;", nil)
+		g.Expect(err).To(BeNil())
+		reader := rows.(ColumnarReader)
+		var ids []int64
+		for {
+			block, err := reader.NextBlock()
+			if err == io.EOF {
+				break
+			}
+			g.Expect(err).To(BeNil())
+			ids = append(ids, ColumnValues[int64](block, 0)...)
+		}
+		g.Expect(ids).To(Equal([]int64{1, 2, 3, 4}))
+		g.Expect(rows.(RowsStatsProvider).RowsStats().RowsRead).To(Equal(int64(4)))
+
+		g.Expect(rows.Close()).To(Succeed())
+		_, err = reader.NextBlock()
+		g.Expect(err).To(MatchError(&ErrStreamClosed{}))
+		return nil
+	})).To(Succeed())
+}
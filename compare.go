@@ -0,0 +1,340 @@
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package godeltastream
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CompareOptions configure how CompareRows matches and compares rows.
+type CompareOptions struct {
+	// KeyColumns are the columns identifying rows in both result sets. Keys must be unique within a result set.
+	KeyColumns []string
+	// NumericTolerance is the largest absolute difference of FLOAT, DOUBLE and DECIMAL values considered equal.
+	NumericTolerance float64
+	// TimeTolerance is the largest difference of DATE, TIMESTAMP and TIMESTAMP_LTZ values considered equal, e.g. to
+	// compare results of environments with different timestamp precisions.
+	TimeTolerance time.Duration
+}
+
+// RowChange is a row whose key is in both result sets but whose values differ.
+type RowChange struct {
+	// Key are the values of the key columns.
+	Key []any
+	// Old is the row of the left result set.
+	Old []any
+	// New is the row of the right result set, in the column order of the left result set.
+	New []any
+	// Columns are the names of the columns whose values differ.
+	Columns []string
+}
+
+// ResultSetDiff is the difference between two result sets, see CompareRows. Rows are in the column order of the left
+// result set.
+type ResultSetDiff struct {
+	// Columns are the names of the columns of the rows.
+	Columns []string
+	// Added are the rows of the right result set whose key is not in the left result set.
+	Added [][]any
+	// Removed are the rows of the left result set whose key is not in the right result set.
+	Removed [][]any
+	// Changed are the rows whose key is in both result sets but whose values differ.
+	Changed []RowChange
+}
+
+// Equal returns whether the result sets had the same rows.
+func (d *ResultSetDiff) Equal() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// Querier is implemented by *sql.DB, *sql.Conn and *sql.Tx.
+type Querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// CompareQueries runs leftQuery on left and rightQuery on right, e.g. the same query on two environments or the old
+// and new version of a migrated query, and compares their results with CompareRows.
+func CompareQueries(ctx context.Context, left Querier, leftQuery string, right Querier, rightQuery string, opts CompareOptions) (*ResultSetDiff, error) {
+	leftRows, err := left.QueryContext(ctx, leftQuery)
+	if err != nil {
+		return nil, err
+	}
+	defer leftRows.Close()
+	rightRows, err := right.QueryContext(ctx, rightQuery)
+	if err != nil {
+		return nil, err
+	}
+	defer rightRows.Close()
+	return CompareRows(leftRows, rightRows, opts)
+}
+
+// CompareRows reads both result sets and reports the rows added, removed and changed from left to right, matching rows
+// by opts.KeyColumns. Both result sets must have the same columns, in any order. Values are compared according to
+// their column types: numbers and times within the tolerances of opts, ARRAY, MAP and STRUCT values as json regardless
+// of the order of their keys. The rows are not closed.
+func CompareRows(left, right *sql.Rows, opts CompareOptions) (*ResultSetDiff, error) {
+	if len(opts.KeyColumns) == 0 {
+		return nil, &ErrClientError{message: "no key columns to compare rows by"}
+	}
+	leftCols, err := compareColumnsOf(left)
+	if err != nil {
+		return nil, err
+	}
+	rightCols, err := compareColumnsOf(right)
+	if err != nil {
+		return nil, err
+	}
+
+	// position of the columns of left in right
+	order := make([]int, len(leftCols))
+	rightIdx := make(map[string]int, len(rightCols))
+	for i, c := range rightCols {
+		rightIdx[c.name] = i
+	}
+	if len(leftCols) != len(rightCols) {
+		return nil, &ErrClientError{message: fmt.Sprintf("result sets have different columns. left has %d columns, right has %d", len(leftCols), len(rightCols))}
+	}
+	for i, c := range leftCols {
+		j, ok := rightIdx[c.name]
+		if !ok {
+			return nil, &ErrClientError{message: fmt.Sprintf("column %s is missing from the right result set", c.name)}
+		}
+		order[i] = j
+	}
+	keys := make([]int, len(opts.KeyColumns))
+	for i, k := range opts.KeyColumns {
+		idx := -1
+		for j, c := range leftCols {
+			if c.name == k {
+				idx = j
+			}
+		}
+		if idx == -1 {
+			return nil, &ErrClientError{message: fmt.Sprintf("unknown key column %s", k)}
+		}
+		keys[i] = idx
+	}
+
+	leftRows, leftIdx, err := readCompareRows(left, len(leftCols), identityOrder(len(leftCols)), keys)
+	if err != nil {
+		return nil, err
+	}
+	rightRows, rightKeys, err := readCompareRows(right, len(rightCols), order, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &ResultSetDiff{Columns: make([]string, len(leftCols))}
+	for i, c := range leftCols {
+		diff.Columns[i] = c.name
+	}
+	for _, row := range leftRows {
+		k := compareKey(row, keys)
+		j, ok := rightKeys[k]
+		if !ok {
+			diff.Removed = append(diff.Removed, row)
+			continue
+		}
+		other := rightRows[j]
+		var changed []string
+		for i, c := range leftCols {
+			if !c.equal(row[i], other[i], opts) {
+				changed = append(changed, c.name)
+			}
+		}
+		if len(changed) > 0 {
+			key := make([]any, len(keys))
+			for i, idx := range keys {
+				key[i] = row[idx]
+			}
+			diff.Changed = append(diff.Changed, RowChange{Key: key, Old: row, New: other, Columns: changed})
+		}
+	}
+	for _, row := range rightRows {
+		if _, ok := leftIdx[compareKey(row, keys)]; !ok {
+			diff.Added = append(diff.Added, row)
+		}
+	}
+	return diff, nil
+}
+
+// compareColumn is a column of a compared result set.
+type compareColumn struct {
+	name     string
+	typeName string
+}
+
+func compareColumnsOf(rows *sql.Rows) ([]compareColumn, error) {
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+	cols := make([]compareColumn, len(types))
+	for i, t := range types {
+		// strip display hints, see WithColumnDisplayHints
+		typeName, _, _ := strings.Cut(t.DatabaseTypeName(), ";")
+		cols[i] = compareColumn{name: t.Name(), typeName: typeName}
+	}
+	return cols, nil
+}
+
+func identityOrder(n int) []int {
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	return order
+}
+
+// readCompareRows reads the rows, reordering their values by order, and indexes them by their key.
+func readCompareRows(rows *sql.Rows, n int, order []int, keys []int) ([][]any, map[string]int, error) {
+	var ret [][]any
+	index := map[string]int{}
+	values := make([]any, n)
+	dest := make([]any, n)
+	for i := range dest {
+		dest[i] = &values[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return nil, nil, err
+		}
+		row := make([]any, n)
+		for i, j := range order {
+			row[i] = values[j]
+		}
+		k := compareKey(row, keys)
+		if _, ok := index[k]; ok {
+			return nil, nil, &ErrClientError{message: fmt.Sprintf("duplicate key %s", strings.ReplaceAll(k, "\x00", ", "))}
+		}
+		index[k] = len(ret)
+		ret = append(ret, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+	return ret, index, nil
+}
+
+// compareKey returns the string identifying the key of row.
+func compareKey(row []any, keys []int) string {
+	parts := make([]string, len(keys))
+	for i, idx := range keys {
+		switch v := row[idx].(type) {
+		case nil:
+			parts[i] = "NULL"
+		case time.Time:
+			parts[i] = v.UTC().Format(time.RFC3339Nano)
+		case []byte:
+			parts[i] = fmt.Sprintf("%x", v)
+		default:
+			parts[i] = fmt.Sprint(v)
+		}
+	}
+	return strings.Join(parts, "\x00")
+}
+
+// equal compares values of the column.
+func (c compareColumn) equal(a, b any, opts CompareOptions) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	switch {
+	case c.typeName == "FLOAT", c.typeName == "DOUBLE", strings.HasPrefix(c.typeName, "DECIMAL"):
+		if x, ok := a.(Decimal); ok {
+			if y, ok := b.(Decimal); ok && x.Cmp(y) == 0 {
+				return true
+			}
+		}
+		x, okx := numericValue(a)
+		y, oky := numericValue(b)
+		if okx && oky {
+			return x == y || math.Abs(x-y) <= opts.NumericTolerance
+		}
+	case strings.HasPrefix(c.typeName, "ARRAY"), strings.HasPrefix(c.typeName, "MAP"), strings.HasPrefix(c.typeName, "STRUCT"):
+		x, okx := jsonText(a)
+		y, oky := jsonText(b)
+		if okx && oky {
+			return x == y || jsonEqual(x, y)
+		}
+	}
+
+	switch x := a.(type) {
+	case time.Time:
+		y, ok := b.(time.Time)
+		if !ok {
+			return false
+		}
+		d := x.Sub(y)
+		if d < 0 {
+			d = -d
+		}
+		return d <= opts.TimeTolerance
+	case []byte:
+		y, ok := b.([]byte)
+		return ok && bytes.Equal(x, y)
+	case *big.Int:
+		y, ok := b.(*big.Int)
+		return ok && x.Cmp(y) == 0
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// numericValue returns FLOAT, DOUBLE and DECIMAL values as float64, decoding DECIMAL values as configured with
+// WithDecimalMode.
+func numericValue(v any) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case Decimal:
+		return v.Float64(), true
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// jsonText returns the json of ARRAY, MAP and STRUCT values, decoded as strings or as bytes with WithRawJSONColumns.
+func jsonText(v any) (string, bool) {
+	switch v := v.(type) {
+	case string:
+		return v, true
+	case []byte:
+		return string(v), true
+	}
+	return "", false
+}
+
+// jsonEqual returns whether two json documents have the same values.
+func jsonEqual(a, b string) bool {
+	var x, y any
+	if json.Unmarshal([]byte(a), &x) != nil || json.Unmarshal([]byte(b), &y) != nil {
+		return false
+	}
+	return reflect.DeepEqual(x, y)
+}
@@ -0,0 +1,139 @@
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package godeltastream
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+	. "github.com/onsi/gomega"
+)
+
+// compareResultSet returns a result set of orders with the rows, in the order of columns.
+func compareResultSet(columns []string, rows ...string) string {
+	types := map[string]string{"id": "BIGINT", "amount": "DECIMAL(10, 2)", "updated": "TIMESTAMP(3)", "tags": "MAP<VARCHAR, INTEGER>", "note": "VARCHAR"}
+	cols := make([]string, len(columns))
+	for i, c := range columns {
+		cols[i] = fmt.Sprintf(`{"name": %q, "type": %q, "nullable": true}`, c, types[c])
+	}
+	return fmt.Sprintf(`{
+		"sqlState": "00000",
+		"statementID": "d789687d-4e1b-4649-846e-4f10b722f3ad",
+		"createdOn": 1703907114,
+		"metadata": {"encoding": "json", "partitionInfo": [{"rowCount": %d}], "columns": [%s], "context": {}},
+		"data": [%s]
+	}`, len(rows), strings.Join(cols, ","), strings.Join(rows, ","))
+}
+
+func compareResponder(body string) httpmock.Responder {
+	return httpmock.NewStringResponder(http.StatusOK, body).HeaderSet(http.Header{"Content-Type": []string{"application/json"}})
+}
+
+func compareDB(g *WithT, server string) *sql.DB {
+	connector, err := ConnectorWithOptions(context.TODO(), WithServer(server), WithStaticToken("sometoken"))
+	g.Expect(err).To(BeNil())
+	return sql.OpenDB(connector)
+}
+
+func TestCompareQueries(t *testing.T) {
+	g := NewWithT(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("POST", "https://old.deltastream.io/v2/statements", compareResponder(compareResultSet(
+		[]string{"id", "amount", "updated", "tags", "note"},
+		`["1", "10.25", "2024-01-01 10:00:00.000", "{\"a\": 1, \"b\": 2}", "same"]`,
+		`["2", "20.00", "2024-01-01 10:00:00.000", "{}", "removed"]`,
+		`["3", "30.00", "2024-01-01 10:00:00.000", "{}", "old"]`,
+		`["4", "40.00", "2024-01-01 10:00:00.000", null, null]`,
+	)))
+	// columns in another order, rounding and precision differences within the tolerances
+	httpmock.RegisterResponder("POST", "https://new.deltastream.io/v2/statements", compareResponder(compareResultSet(
+		[]string{"note", "id", "updated", "amount", "tags"},
+		`["same", "1", "2024-01-01 10:00:00.001", "10.2500001", "{\"b\": 2, \"a\": 1}"]`,
+		`["new", "3", "2024-01-01 10:00:00.000", "31.00", "{}"]`,
+		`[null, "4", "2024-01-01 10:00:00.000", "40.00", null]`,
+		`["added", "5", "2024-01-01 10:00:00.000", "50.00", "{}"]`,
+	)))
+
+	oldDB := compareDB(g, "https://old.deltastream.io/v2")
+	defer oldDB.Close()
+	newDB := compareDB(g, "https://new.deltastream.io/v2")
+	defer newDB.Close()
+
+	diff, err := CompareQueries(context.TODO(), oldDB, "SELECT * FROM orders_v1;", newDB, "SELECT * FROM orders_v2;", CompareOptions{
+		KeyColumns:       []string{"id"},
+		NumericTolerance: 0.001,
+		TimeTolerance:    time.Millisecond,
+	})
+	g.Expect(err).To(BeNil())
+	g.Expect(diff.Equal()).To(BeFalse())
+	g.Expect(diff.Columns).To(Equal([]string{"id", "amount", "updated", "tags", "note"}))
+
+	updated := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	g.Expect(diff.Removed).To(Equal([][]any{{int64(2), 20.0, updated, "{}", "removed"}}))
+	g.Expect(diff.Added).To(Equal([][]any{{int64(5), 50.0, updated, "{}", "added"}}))
+	g.Expect(diff.Changed).To(HaveLen(1))
+	g.Expect(diff.Changed[0].Key).To(Equal([]any{int64(3)}))
+	g.Expect(diff.Changed[0].Columns).To(Equal([]string{"amount", "note"}))
+	g.Expect(diff.Changed[0].Old).To(Equal([]any{int64(3), 30.0, updated, "{}", "old"}))
+	g.Expect(diff.Changed[0].New).To(Equal([]any{int64(3), 31.0, updated, "{}", "new"}))
+
+	// without tolerances the rounding differences are changes
+	diff, err = CompareQueries(context.TODO(), oldDB, "SELECT * FROM orders_v1;", newDB, "SELECT * FROM orders_v2;", CompareOptions{KeyColumns: []string{"id"}})
+	g.Expect(err).To(BeNil())
+	g.Expect(diff.Changed).To(HaveLen(2))
+	g.Expect(diff.Changed[0].Columns).To(Equal([]string{"amount", "updated"}))
+}
+
+func TestCompareQueriesErrors(t *testing.T) {
+	g := NewWithT(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("POST", "https://old.deltastream.io/v2/statements", compareResponder(compareResultSet(
+		[]string{"id", "note"}, `["1", "a"]`, `["1", "b"]`,
+	)))
+	httpmock.RegisterResponder("POST", "https://new.deltastream.io/v2/statements", compareResponder(compareResultSet(
+		[]string{"id", "amount"}, `["1", "1.00"]`,
+	)))
+
+	oldDB := compareDB(g, "https://old.deltastream.io/v2")
+	defer oldDB.Close()
+	newDB := compareDB(g, "https://new.deltastream.io/v2")
+	defer newDB.Close()
+
+	var clientErr *ErrClientError
+	_, err := CompareQueries(context.TODO(), oldDB, "SELECT 1;", newDB, "SELECT 1;", CompareOptions{})
+	g.Expect(errors.As(err, &clientErr)).To(BeTrue())
+	g.Expect(err.Error()).To(ContainSubstring("no key columns"))
+
+	_, err = CompareQueries(context.TODO(), oldDB, "SELECT 1;", newDB, "SELECT 1;", CompareOptions{KeyColumns: []string{"id"}})
+	g.Expect(errors.As(err, &clientErr)).To(BeTrue())
+	g.Expect(err.Error()).To(ContainSubstring("column note is missing from the right result set"))
+
+	_, err = CompareQueries(context.TODO(), oldDB, "SELECT 1;", oldDB, "SELECT 1;", CompareOptions{KeyColumns: []string{"id"}})
+	g.Expect(errors.As(err, &clientErr)).To(BeTrue())
+	g.Expect(err.Error()).To(ContainSubstring("duplicate key 1"))
+}
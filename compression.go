@@ -0,0 +1,99 @@
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package godeltastream
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"github.com/deltastreaminc/go-deltastream/apiv2"
+)
+
+// WithAttachmentCompression gzips the attachments of statements, setting Content-Encoding on their parts, when the
+// server advertises support for gzip request content with the Accept-Encoding header of its responses (RFC 7694).
+// Attachments smaller than minSize are sent as is, those of unknown size are always compressed. The server is asked
+// for its version to learn whether it supports compression before the first statement with attachments, unless a
+// previous response already told.
+func WithAttachmentCompression(minSize int64) func(*connectionOptions) {
+	return func(o *connectionOptions) {
+		o.attachmentCompression = &attachmentCompression{minSize: minSize}
+	}
+}
+
+// attachmentCompression tracks whether the server accepts gzipped attachments. It is shared by the connections of a
+// connector.
+type attachmentCompression struct {
+	minSize  int64
+	known    atomic.Bool // set once a response told whether gzip is accepted
+	accepted atomic.Bool
+}
+
+// observe records the content codings advertised by the Accept-Encoding header of rsp, if any.
+func (c *attachmentCompression) observe(rsp *http.Response) {
+	if c == nil || rsp == nil {
+		return
+	}
+	v := rsp.Header.Values("Accept-Encoding")
+	if len(v) == 0 {
+		return
+	}
+	accepted := false
+	for _, coding := range strings.Split(strings.Join(v, ","), ",") {
+		coding, _, _ = strings.Cut(coding, ";")
+		if strings.EqualFold(strings.TrimSpace(coding), "gzip") {
+			accepted = true
+		}
+	}
+	c.accepted.Store(accepted)
+	c.known.Store(true)
+}
+
+// eligible returns whether a is large enough to be compressed.
+func (c *attachmentCompression) eligible(a Attachment) bool {
+	return c != nil && (a.Size == 0 || a.Size >= c.minSize)
+}
+
+// compressed returns whether to gzip each of the attachments, asking the server for its version if it is not known yet
+// whether it supports compression. Attachments are sent as is if the server cannot be asked.
+func (c *attachmentCompression) compressed(ctx context.Context, client *apiv2.ClientWithResponses, attachments []Attachment) []bool {
+	var flags []bool
+	for i, a := range attachments {
+		if c.eligible(a) {
+			if flags == nil {
+				flags = make([]bool, len(attachments))
+			}
+			flags[i] = true
+		}
+	}
+	if flags == nil {
+		return nil
+	}
+	if !c.known.Load() {
+		if rsp, err := client.GetVersion(ctx); err == nil {
+			drainBody(rsp)
+			c.observe(rsp)
+			// servers not advertising Accept-Encoding do not accept compressed content
+			c.known.Store(true)
+		}
+	}
+	if !c.accepted.Load() {
+		return nil
+	}
+	return flags
+}
@@ -0,0 +1,123 @@
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package godeltastream
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	. "github.com/onsi/gomega"
+)
+
+// receivedAttachment is an attachment part as received by the server, decompressed if needed.
+type receivedAttachment struct {
+	contentEncoding string
+	data            []byte
+}
+
+func mockCompressedAttachmentsResponder(g *WithT, received map[string]receivedAttachment) httpmock.Responder {
+	return func(r *http.Request) (*http.Response, error) {
+		_, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		g.Expect(err).To(BeNil())
+		mr := multipart.NewReader(r.Body, params["boundary"])
+		for {
+			p, err := mr.NextPart()
+			if err == io.EOF {
+				break
+			}
+			g.Expect(err).To(BeNil())
+			if p.FormName() != "attachments" {
+				continue
+			}
+			var body io.Reader = p
+			encoding := p.Header.Get("Content-Encoding")
+			if encoding == "gzip" {
+				body, err = gzip.NewReader(p)
+				g.Expect(err).To(BeNil())
+			}
+			data, err := io.ReadAll(body)
+			g.Expect(err).To(BeNil())
+			received[p.FileName()] = receivedAttachment{contentEncoding: encoding, data: data}
+		}
+		rsp := httpmock.NewBytesResponse(http.StatusOK, httpmock.File("fixtures/list-organizations-200-00000-1.json").Bytes())
+		rsp.Header.Set("Content-Type", "application/json")
+		return rsp, nil
+	}
+}
+
+func TestAttachmentCompression(t *testing.T) {
+	g := NewWithT(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("GET", "https://api.deltastream.io/v2/version", func(r *http.Request) (*http.Response, error) {
+		rsp := httpmock.NewStringResponse(http.StatusOK, `{"major":2,"minor":0,"patch":0}`)
+		rsp.Header.Set("Accept-Encoding", "gzip, br")
+		return rsp, nil
+	})
+	received := map[string]receivedAttachment{}
+	httpmock.RegisterResponder("POST", "https://api.deltastream.io/v2/statements", mockCompressedAttachmentsResponder(g, received))
+
+	connector, err := ConnectorWithOptions(context.TODO(), WithServer("https://api.deltastream.io/v2"), WithStaticToken("sometoken"), WithAttachmentCompression(1024))
+	g.Expect(err).To(BeNil())
+	db := sql.OpenDB(connector)
+
+	small := []byte("syntax = \"proto3\";")
+	_, err = db.Exec("LIST ORGANIZATIONS;",
+		Attachment{Name: "test.blob", Reader: io.NopCloser(bytes.NewReader(attachmentData)), Size: int64(len(attachmentData))},
+		Attachment{Name: "small.proto", Reader: io.NopCloser(bytes.NewReader(small)), Size: int64(len(small))},
+	)
+	g.Expect(err).To(BeNil())
+	g.Expect(received).To(Equal(map[string]receivedAttachment{
+		"test.blob":   {contentEncoding: "gzip", data: attachmentData},
+		"small.proto": {data: small},
+	}))
+
+	// the server is asked only once
+	_, err = db.Exec("LIST ORGANIZATIONS;", Attachment{Name: "test.blob", Reader: io.NopCloser(bytes.NewReader(attachmentData))})
+	g.Expect(err).To(BeNil())
+	g.Expect(received["test.blob"].contentEncoding).To(Equal("gzip"))
+	g.Expect(httpmock.GetCallCountInfo()["GET https://api.deltastream.io/v2/version"]).To(Equal(1))
+}
+
+func TestAttachmentCompressionNotAdvertised(t *testing.T) {
+	g := NewWithT(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("GET", "https://api.deltastream.io/v2/version", httpmock.NewStringResponder(http.StatusOK, `{"major":2,"minor":0,"patch":0}`))
+	received := map[string]receivedAttachment{}
+	httpmock.RegisterResponder("POST", "https://api.deltastream.io/v2/statements", mockCompressedAttachmentsResponder(g, received))
+
+	connector, err := ConnectorWithOptions(context.TODO(), WithServer("https://api.deltastream.io/v2"), WithStaticToken("sometoken"), WithAttachmentCompression(0))
+	g.Expect(err).To(BeNil())
+	db := sql.OpenDB(connector)
+	for i := 0; i < 2; i++ {
+		_, err = db.Exec("LIST ORGANIZATIONS;", Attachment{Name: "test.blob", Reader: io.NopCloser(bytes.NewReader(attachmentData))})
+		g.Expect(err).To(BeNil())
+		g.Expect(received).To(Equal(map[string]receivedAttachment{"test.blob": {data: attachmentData}}))
+	}
+	g.Expect(httpmock.GetCallCountInfo()["GET https://api.deltastream.io/v2/version"]).To(Equal(1))
+}
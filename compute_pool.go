@@ -0,0 +1,40 @@
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package godeltastream
+
+import (
+	"context"
+)
+
+// ComputePoolSelector returns the compute pool a statement runs on, or an empty string to keep the compute pool of the
+// context of the connection. It is called for every statement submitted, so it must be safe for concurrent use and
+// return quickly.
+type ComputePoolSelector func(ctx context.Context, statement string) string
+
+// WithComputePoolSelector runs each statement on the compute pool selector returns, e.g. to route heavy analytical
+// queries to a large pool and interactive metadata queries to a small one from the same *sql.DB. A pool set with
+// StatementRequest.ComputePool takes precedence. The pool of each statement is reported by the
+// ConnectionEventStatementSubmitted events, see WithConnectionEventHandler, and logged at debug level, see WithLogger.
+//
+// An empty selection falls back to the compute pool of the context of the connection, which follows the context
+// returned by the server for the last statement, i.e. possibly the pool selected for it. Selectors that route some
+// statements should return a pool for all of them.
+func WithComputePoolSelector(selector ComputePoolSelector) func(*connectionOptions) {
+	return func(o *connectionOptions) {
+		o.computePoolSelector = selector
+	}
+}
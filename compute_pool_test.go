@@ -0,0 +1,113 @@
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package godeltastream
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"log/slog"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	. "github.com/onsi/gomega"
+)
+
+func TestComputePoolSelector(t *testing.T) {
+	g := NewWithT(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	var pools []any
+	httpmock.RegisterResponder("POST", "https://api.deltastream.io/v2/statements", func(r *http.Request) (*http.Response, error) {
+		_, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		g.Expect(err).To(BeNil())
+		p, err := multipart.NewReader(r.Body, params["boundary"]).NextPart()
+		g.Expect(err).To(BeNil())
+		req := map[string]any{}
+		g.Expect(json.NewDecoder(p).Decode(&req)).To(Succeed())
+		pools = append(pools, req["computePool"])
+		rsp := httpmock.NewBytesResponse(http.StatusOK, httpmock.File("fixtures/list-organizations-200-00000-1.json").Bytes())
+		rsp.Header.Set("Content-Type", "application/json")
+		return rsp, nil
+	})
+
+	type ctxKey struct{}
+	var (
+		mu     sync.Mutex
+		events []ConnectionEvent
+		logs   bytes.Buffer
+	)
+	selector := func(ctx context.Context, statement string) string {
+		if ctx.Value(ctxKey{}) != nil {
+			return ctx.Value(ctxKey{}).(string)
+		}
+		if strings.HasPrefix(statement, "SELECT") {
+			return "large"
+		}
+		return ""
+	}
+	connector, err := ConnectorWithOptions(context.TODO(), WithServer("https://api.deltastream.io/v2"), WithStaticToken("sometoken"),
+		WithComputePoolSelector(selector),
+		WithLogger(slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))),
+		WithConnectionEventHandler(func(e ConnectionEvent) {
+			mu.Lock()
+			defer mu.Unlock()
+			if e.Type == ConnectionEventStatementSubmitted {
+				events = append(events, e)
+			}
+		}))
+	g.Expect(err).To(BeNil())
+	db := sql.OpenDB(connector)
+	defer db.Close()
+
+	for _, query := range []string{"SELECT * FROM pageviews;", "LIST ORGANIZATIONS;"} {
+		rows, err := db.QueryContext(context.TODO(), query)
+		g.Expect(err).To(BeNil())
+		g.Expect(rows.Close()).To(Succeed())
+	}
+	rows, err := db.QueryContext(context.WithValue(context.TODO(), ctxKey{}, "small"), "LIST ORGANIZATIONS;")
+	g.Expect(err).To(BeNil())
+	g.Expect(rows.Close()).To(Succeed())
+
+	// a pool set on the request takes precedence
+	conn, err := db.Conn(context.TODO())
+	g.Expect(err).To(BeNil())
+	defer conn.Close()
+	g.Expect(conn.Raw(func(driverConn any) error {
+		rows, err := driverConn.(*Conn).SubmitRequest(context.TODO(), NewStatementRequest("SELECT 1;").ComputePool("adhoc"))
+		g.Expect(err).To(BeNil())
+		defer rows.Close()
+		return rows.Next(make([]driver.Value, len(rows.Columns())))
+	})).To(Succeed())
+
+	g.Expect(pools).To(Equal([]any{"large", nil, "small", "adhoc"}))
+	var selected []string
+	for _, e := range events {
+		selected = append(selected, e.ComputePool)
+	}
+	g.Expect(selected).To(Equal([]string{"large", "", "small", "adhoc"}))
+	g.Expect(logs.String()).To(ContainSubstring("computePool=large"))
+	g.Expect(logs.String()).To(ContainSubstring("computePool=small"))
+}
@@ -0,0 +1,304 @@
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package conformance validates the driver against a live DeltaStream organization: the decoding of the columns of
+// result sets, results spanning several partitions, streaming results, attachments and the errors of failing
+// statements. Run it to validate the compatibility of the driver and the server before upgrading either, e.g. in CI:
+//
+//	DELTASTREAM_CONFORMANCE_DSN=https://_:<token>@api.deltastream.io/v2 go test github.com/deltastreaminc/go-deltastream/conformance
+//
+// The suite is skipped unless DELTASTREAM_CONFORMANCE_DSN is set. Cases that need objects of the organization, e.g. a
+// relation to stream, are skipped unless configured, see Config and ConfigFromEnv.
+package conformance
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	godeltastream "github.com/deltastreaminc/go-deltastream"
+)
+
+// Config configures the conformance suite.
+type Config struct {
+	// DSN is the data source name of the organization to run the suite against, as accepted by sql.Open, e.g.
+	// https://_:<token>@api.deltastream.io/v2.
+	DSN string
+	// DatatypesQuery returns columns of the types to validate the decoding of, e.g. SELECT * FROM a relation with a
+	// column of every type. LIST ORGANIZATIONS; is used if empty.
+	DatatypesQuery string
+	// PartitionedQuery returns a result set large enough to be split into several partitions. The case is skipped if
+	// empty.
+	PartitionedQuery string
+	// StreamingQuery returns a streaming result, e.g. SELECT * FROM a stream with records. The case is skipped if
+	// empty.
+	StreamingQuery string
+	// StreamingRows is the number of rows read from the streaming result. Defaults to 1.
+	StreamingRows int
+	// AttachmentStatement is a statement referencing AttachmentFile, e.g. CREATE DESCRIPTOR_SOURCE ... WITH ('file' =
+	// 'protos.desc'). The case is skipped if empty.
+	AttachmentStatement string
+	// AttachmentFile is the path of the file attached to AttachmentStatement, attached by its base name.
+	AttachmentFile string
+	// AttachmentCleanup is run after AttachmentStatement, e.g. to drop the object it created.
+	AttachmentCleanup string
+	// Timeout bounds every case. Defaults to 2 minutes.
+	Timeout time.Duration
+}
+
+// ConfigFromEnv returns the configuration in the environment: DELTASTREAM_CONFORMANCE_DSN and the optional
+// DELTASTREAM_CONFORMANCE_DATATYPES_QUERY, DELTASTREAM_CONFORMANCE_PARTITIONED_QUERY,
+// DELTASTREAM_CONFORMANCE_STREAMING_QUERY, DELTASTREAM_CONFORMANCE_STREAMING_ROWS,
+// DELTASTREAM_CONFORMANCE_ATTACHMENT_STATEMENT, DELTASTREAM_CONFORMANCE_ATTACHMENT_FILE,
+// DELTASTREAM_CONFORMANCE_ATTACHMENT_CLEANUP and DELTASTREAM_CONFORMANCE_TIMEOUT, a duration such as 5m.
+func ConfigFromEnv() (Config, error) {
+	cfg := Config{
+		DSN:                 os.Getenv("DELTASTREAM_CONFORMANCE_DSN"),
+		DatatypesQuery:      os.Getenv("DELTASTREAM_CONFORMANCE_DATATYPES_QUERY"),
+		PartitionedQuery:    os.Getenv("DELTASTREAM_CONFORMANCE_PARTITIONED_QUERY"),
+		StreamingQuery:      os.Getenv("DELTASTREAM_CONFORMANCE_STREAMING_QUERY"),
+		AttachmentStatement: os.Getenv("DELTASTREAM_CONFORMANCE_ATTACHMENT_STATEMENT"),
+		AttachmentFile:      os.Getenv("DELTASTREAM_CONFORMANCE_ATTACHMENT_FILE"),
+		AttachmentCleanup:   os.Getenv("DELTASTREAM_CONFORMANCE_ATTACHMENT_CLEANUP"),
+	}
+	var err error
+	if v := os.Getenv("DELTASTREAM_CONFORMANCE_STREAMING_ROWS"); v != "" {
+		if cfg.StreamingRows, err = strconv.Atoi(v); err != nil {
+			return Config{}, fmt.Errorf("invalid DELTASTREAM_CONFORMANCE_STREAMING_ROWS: %w", err)
+		}
+	}
+	if v := os.Getenv("DELTASTREAM_CONFORMANCE_TIMEOUT"); v != "" {
+		if cfg.Timeout, err = time.ParseDuration(v); err != nil {
+			return Config{}, fmt.Errorf("invalid DELTASTREAM_CONFORMANCE_TIMEOUT: %w", err)
+		}
+	}
+	return cfg, nil
+}
+
+// Run runs the conformance suite as subtests of t. It skips t if cfg.DSN is empty.
+func Run(t *testing.T, cfg Config) {
+	if cfg.DSN == "" {
+		t.Skip("DELTASTREAM_CONFORMANCE_DSN is not set")
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 2 * time.Minute
+	}
+	if cfg.StreamingRows <= 0 {
+		cfg.StreamingRows = 1
+	}
+	if cfg.DatatypesQuery == "" {
+		cfg.DatatypesQuery = "LIST ORGANIZATIONS;"
+	}
+
+	db, err := sql.Open("deltastream", cfg.DSN)
+	if err != nil {
+		t.Fatalf("unable to open %s: %v", redactDSN(cfg.DSN), err)
+	}
+	defer db.Close()
+
+	run := func(name string, skip bool, f func(t *testing.T, ctx context.Context)) {
+		t.Run(name, func(t *testing.T) {
+			if skip {
+				t.Skip("not configured")
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), cfg.Timeout)
+			defer cancel()
+			f(t, ctx)
+		})
+	}
+	run("Datatypes", false, func(t *testing.T, ctx context.Context) {
+		checkDatatypes(t, ctx, db, cfg.DatatypesQuery)
+	})
+	run("Partitions", cfg.PartitionedQuery == "", func(t *testing.T, ctx context.Context) {
+		checkPartitions(t, ctx, db, cfg.PartitionedQuery)
+	})
+	run("Streaming", cfg.StreamingQuery == "", func(t *testing.T, ctx context.Context) {
+		checkStreaming(t, ctx, db, cfg.StreamingQuery, cfg.StreamingRows)
+	})
+	run("Attachment", cfg.AttachmentStatement == "", func(t *testing.T, ctx context.Context) {
+		checkAttachment(t, ctx, db, cfg.AttachmentStatement, cfg.AttachmentFile, cfg.AttachmentCleanup)
+	})
+	run("Errors", false, func(t *testing.T, ctx context.Context) {
+		checkErrors(t, ctx, db, cfg.DSN)
+	})
+}
+
+// scanRows scans at most limit rows of rows into the scan types of their columns, or all rows if limit is negative, and
+// returns the number of rows scanned. Null values are scanned into nil pointers.
+func scanRows(t *testing.T, rows *sql.Rows, limit int) int {
+	t.Helper()
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		t.Fatalf("unable to read column types: %v", err)
+	}
+	n := 0
+	for (limit < 0 || n < limit) && rows.Next() {
+		dest := make([]any, len(types))
+		for i, ct := range types {
+			if ct.ScanType() == nil {
+				t.Fatalf("column %s of type %s has no scan type", ct.Name(), ct.DatabaseTypeName())
+			}
+			dest[i] = reflect.New(reflect.PointerTo(ct.ScanType())).Interface()
+		}
+		if err := rows.Scan(dest...); err != nil {
+			t.Fatalf("unable to scan row %d into the scan types of its columns: %v", n, err)
+		}
+		for i, ct := range types {
+			if nullable, ok := ct.Nullable(); ok && !nullable && reflect.ValueOf(dest[i]).Elem().IsNil() {
+				t.Errorf("row %d: column %s is not nullable but is null", n, ct.Name())
+			}
+		}
+		n++
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("error reading rows: %v", err)
+	}
+	return n
+}
+
+// checkDatatypes validates that the values of every column of query decode into the scan type of the column.
+func checkDatatypes(t *testing.T, ctx context.Context, db *sql.DB, query string) {
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		t.Fatalf("unable to run %s: %v", query, err)
+	}
+	defer rows.Close()
+	if n := scanRows(t, rows, -1); n == 0 {
+		t.Logf("%s returned no rows, only the column types were validated", query)
+	}
+}
+
+// checkPartitions validates that all rows of a result set spanning several partitions are read.
+func checkPartitions(t *testing.T, ctx context.Context, db *sql.DB, query string) {
+	var stats godeltastream.RowsStats
+	rows, err := db.QueryContext(godeltastream.WithRowsStats(ctx, &stats), query)
+	if err != nil {
+		t.Fatalf("unable to run %s: %v", query, err)
+	}
+	n := scanRows(t, rows, -1)
+	if err := rows.Close(); err != nil {
+		t.Fatalf("unable to close rows: %v", err)
+	}
+	if stats.RowsRead != int64(n) || stats.RowsUnconsumed != 0 {
+		t.Errorf("read %d rows, rows stats report %d read and %d unconsumed", n, stats.RowsRead, stats.RowsUnconsumed)
+	}
+	if stats.PartitionsFetched < 2 {
+		t.Errorf("%s returned %d partitions, expected a result set of several partitions", query, stats.PartitionsFetched)
+	}
+}
+
+// checkStreaming validates that rows of a streaming result are read and that closing the rows ends the stream.
+func checkStreaming(t *testing.T, ctx context.Context, db *sql.DB, query string, n int) {
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		t.Fatalf("unable to run %s: %v", query, err)
+	}
+	defer rows.Close()
+	if read := scanRows(t, rows, n); read != n {
+		t.Fatalf("read %d rows of %s, expected %d", read, query, n)
+	}
+	closed := make(chan error, 1)
+	go func() { closed <- rows.Close() }()
+	select {
+	case err := <-closed:
+		if err != nil {
+			t.Errorf("unable to close streaming rows: %v", err)
+		}
+	case <-ctx.Done():
+		t.Errorf("closing streaming rows did not return: %v", ctx.Err())
+	}
+}
+
+// checkAttachment validates that a statement referencing an attachment is accepted.
+func checkAttachment(t *testing.T, ctx context.Context, db *sql.DB, statement, file, cleanup string) {
+	f, err := os.Open(file)
+	if err != nil {
+		t.Fatalf("unable to open attachment: %v", err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		t.Fatalf("unable to stat attachment: %v", err)
+	}
+	attachment := godeltastream.Attachment{Name: info.Name(), Size: info.Size(), Reader: f}
+	if _, err := db.ExecContext(ctx, statement, attachment); err != nil {
+		t.Fatalf("unable to run %s: %v", statement, err)
+	}
+	if cleanup != "" {
+		if _, err := db.ExecContext(ctx, cleanup); err != nil {
+			t.Errorf("unable to clean up with %s: %v", cleanup, err)
+		}
+	}
+}
+
+// checkErrors validates the errors of failing statements.
+func checkErrors(t *testing.T, ctx context.Context, db *sql.DB, dsn string) {
+	expectSQLState := func(statement string, expected godeltastream.SqlState) {
+		_, err := db.ExecContext(ctx, statement)
+		var sqlErr godeltastream.ErrSQLError
+		switch {
+		case !errors.As(err, &sqlErr):
+			t.Errorf("%s: expected an ErrSQLError with SqlState %s, got %v", statement, expected, err)
+		case sqlErr.SQLCode != expected:
+			t.Errorf("%s: expected SqlState %s (%s), got %s (%s): %v", statement, expected, expected.Info().Description, sqlErr.SQLCode, sqlErr.SQLCode.Info().Description, err)
+		}
+	}
+	expectSQLState("SELEC 1;", godeltastream.SqlStateSyntaxError)
+	expectSQLState(fmt.Sprintf("USE DATABASE conformance_missing_%d;", time.Now().UnixNano()), godeltastream.SqlStateInvalidDatabase)
+
+	// statements submitted with an invalid token are rejected
+	u, err := url.Parse(dsn)
+	if err != nil {
+		t.Fatalf("unable to parse DSN: %v", err)
+	}
+	u.User = url.UserPassword("_", "invalid-conformance-token")
+	invalid, err := sql.Open("deltastream", u.String())
+	if err != nil {
+		t.Fatalf("unable to open DSN with an invalid token: %v", err)
+	}
+	defer invalid.Close()
+	_, err = invalid.ExecContext(ctx, "LIST ORGANIZATIONS;")
+	if !isAuthError(err) {
+		t.Errorf("expected an authentication error with an invalid token, got %v", err)
+	}
+}
+
+// isAuthError returns whether err reports credentials rejected by the server.
+func isAuthError(err error) bool {
+	var unexpected *godeltastream.ErrUnexpectedResponse
+	if errors.As(err, &unexpected) {
+		return unexpected.StatusCode == http.StatusUnauthorized || unexpected.StatusCode == http.StatusForbidden
+	}
+	return errors.Is(err, godeltastream.ErrAuthenticationError) ||
+		errors.Is(err, godeltastream.ErrSQLError{SQLCode: godeltastream.SqlStateInvalidApiToken})
+}
+
+// redactDSN returns dsn without its token.
+func redactDSN(dsn string) string {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return "the DSN"
+	}
+	return u.Redacted()
+}
@@ -17,16 +17,12 @@ limitations under the License.
 package godeltastream
 
 import (
-	"bytes"
 	"context"
 	"database/sql"
 	"database/sql/driver"
-	"encoding/json"
 	"fmt"
-	"io"
-	"mime/multipart"
+	"log/slog"
 	"net/http"
-	"net/textproto"
 	"os"
 	"sync"
 	"time"
@@ -40,59 +36,163 @@ import (
 
 // Compile time validation that our types implement the expected interfaces
 var (
-	_ driver.Conn           = &Conn{} // Conn is a connection to a database. Stateful and not multi-goroutine safe.
-	_ driver.Pinger         = &Conn{} // Check DB connection. Used for pooling. Returns ErrBadConn if in bad state.
-	_ driver.Execer         = &Conn{} // Provide exec function on conn without having to prepare a statement
-	_ driver.ExecerContext  = &Conn{} // ditto with context
-	_ driver.Queryer        = &Conn{} // Provide query function on conn without having to prepare a statement
-	_ driver.QueryerContext = &Conn{} // ditto with context
+	_ driver.Conn               = &Conn{} // Conn is a connection to a database. Stateful and not multi-goroutine safe.
+	_ driver.Pinger             = &Conn{} // Check DB connection. Used for pooling. Returns ErrBadConn if in bad state.
+	_ driver.Execer             = &Conn{} // Provide exec function on conn without having to prepare a statement
+	_ driver.ExecerContext      = &Conn{} // ditto with context
+	_ driver.Queryer            = &Conn{} // Provide query function on conn without having to prepare a statement
+	_ driver.QueryerContext     = &Conn{} // ditto with context
+	_ driver.NamedValueChecker  = &Conn{} // Accept attachments as statement arguments
+	_ driver.ConnPrepareContext = &Conn{} // Prepare with context
 )
 
 type Conn struct {
+	id                       string // see ConnectionEvent
 	client                   *apiv2.ClientWithResponses
+	endpoints                *endpointClients // see WithEndpointResolver
 	rsctx                    *apiv2.ResultSetContext
 	httpClient               *http.Client
+	dataplaneClient          *http.Client // see WithDataplaneTLSConfig
+	dataplaneBasePath        string
+	maxResponseBytes         int64 // see WithMaxResponseBytes
 	sessionID                *string
 	enableColumnDisplayHints bool
+	notReadyRetry            *notReadyRetryPolicy
+	streamDialRetry          *streamDialRetryPolicy
+	partitionFetchRetry      *partitionFetchRetryPolicy
+	rowBufferPooling         bool
+	legacyTimeColumns        bool
+	rawJSONColumns           bool
+	decimalMode              DecimalMode
+	noQueryHistoryLookups    bool           // see WithQueryHistoryLookups
+	timezone                 *time.Location // session timezone, UTC if nil
+	maintenanceMode          bool
+	jsonCodec                JSONCodec
+	metricsCollector         MetricsCollector
+	pinnedContext            bool
+	serverRsctx              *apiv2.ResultSetContext // context returned by the server but not adopted, see WithPinnedContext
+	tx                       *batchTx
+	interceptors             []StatementInterceptor
+	pollInterval             pollInterval
+	dataplanePollInterval    pollInterval
+	goroutines               goroutineGroup
+	tokenManager             TokenManager
+	authHeaderProvider       AuthHeaderProvider
+	decodeWorkers            int
+	compression              *attachmentCompression // see WithAttachmentCompression
+	pingCache                *pingCache             // see WithPingCacheTTL
+	eventHandler             ConnectionEventHandler
+	logger                   *slog.Logger // see WithLogger, nil if not logging
+	defaultQueryTimeout      time.Duration
+	computePoolSelector      ComputePoolSelector
+	sessionRecovery          bool // see WithSessionRecoveryHandler
+	sessionRecoveryHandler   SessionRecoveryHandler
+	timeParser               TimeParser // see WithTimeParser, nil for the default parsing
+	statementNotFoundGrace   time.Duration
+	multiStatementMode       MultiStatementMode
+	lastStatementID          string // see LastStatementID
+	statementEncoding        StatementEncoding
+	strictSQLStates          bool // see WithStrictSQLStates
 	sync.RWMutex
 }
 
 // region driver.Conn
 
-func (*Conn) Begin() (driver.Tx, error) {
-	return nil, ErrNotSupported
+func (c *Conn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
 }
 
-// Close implements driver.Conn.
+// Close implements driver.Conn. Streaming results still open are closed, and their goroutines awaited.
 func (c *Conn) Close() error {
+	// streaming readers may still submit statements, stop them before the client is released
+	c.goroutines.Close()
+	if c.client != nil {
+		c.emit(ConnectionEvent{Type: ConnectionEventConnClosed})
+	}
 	c.client = nil
 	return nil
 }
 
+// logWarn logs a failure the connection recovered from, see WithLogger.
+func (c *Conn) logWarn(msg string, args ...any) {
+	if c.logger == nil {
+		return
+	}
+	c.logger.Warn(msg, append([]any{"connectionID", c.id}, args...)...)
+}
+
+// logDebug logs a decision of the connection, see WithLogger.
+func (c *Conn) logDebug(msg string, args ...any) {
+	if c.logger == nil {
+		return
+	}
+	c.logger.Debug(msg, append([]any{"connectionID", c.id}, args...)...)
+}
+
+// SessionID returns the ID of the session statements of the connection run in, see WithSessionID and
+// WithAutoSessionID, or an empty string if they do not run in a session.
+func (c *Conn) SessionID() string {
+	return ptr.Deref(c.session(), "")
+}
+
+// ActiveGoroutines returns the names of the background goroutines of the connection that are still running, e.g.
+// "streaming rows <statement id>" for open streaming results. It is meant to detect leaks in tests.
+func (c *Conn) ActiveGoroutines() []string {
+	return c.goroutines.Active()
+}
+
 func (c *Conn) GetContext() apiv2.ResultSetContext {
 	return *c.rsctx
 }
 
+// SessionContext returns the context statements of the connection run in.
+func (c *Conn) SessionContext() SessionContext {
+	rsctx := c.getResultSetContext()
+	if rsctx == nil {
+		return SessionContext{}
+	}
+	return NewSessionContext(*rsctx)
+}
+
 func (c *Conn) SetContext(rsctx apiv2.ResultSetContext) {
 	c.rsctx = &rsctx
 }
 
 // Prepare implements driver.Conn.
 func (c *Conn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+// PrepareContext implements driver.ConnPrepareContext. Statements are not sent to the server until they are executed,
+// ctx is passed to the statement interceptors.
+func (c *Conn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
 	if c.client == nil {
 		return nil, driver.ErrBadConn
 	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	// reject statements early, they are intercepted again when executed
+	if _, err := c.interceptStatement(ctx, query); err != nil {
+		return nil, err
+	}
 
 	return &statement{
-		c:     c,
-		query: query,
+		c:        c,
+		query:    query,
+		numInput: countPlaceholders(query),
+		isOpen:   true,
 	}, nil
 }
 
 // endregion
 
 func (c *Conn) DownloadFile(ctx context.Context, resourceType apiv2.ResourceType, resourName, destFile string) error {
-	resp, err := c.client.DownloadResourceWithResponse(ctx, apiv2.DownloadResourceParamsResourceType(resourceType), *c.rsctx.OrganizationID, resourName)
+	client, err := c.apiClient()
+	if err != nil {
+		return err
+	}
+	resp, err := client.DownloadResourceWithResponse(ctx, apiv2.DownloadResourceParamsResourceType(resourceType), *c.rsctx.OrganizationID, resourName)
 	if err != nil {
 		return &ErrInterfaceError{wrapErr: err, message: "unable to send request to server"}
 	}
@@ -110,20 +210,25 @@ func (c *Conn) DownloadFile(ctx context.Context, resourceType apiv2.ResourceType
 	case resp.JSON400 != nil:
 		return &ErrInterfaceError{message: resp.JSON400.Message}
 	case resp.JSON403 != nil:
-		return errors.Errorf(resp.JSON403.Message+": %w", ErrAuthenticationError)
+		return fmt.Errorf("%s: %w", resp.JSON403.Message, ErrAuthenticationError)
 	case resp.JSON404 != nil:
 		return &ErrInterfaceError{message: resp.JSON404.Message}
 	case resp.JSON408 != nil:
-		return errors.Errorf(resp.JSON408.Message+": %w", ErrDeadlineExceeded)
+		return fmt.Errorf("%s: %w", resp.JSON408.Message, ErrDeadlineExceeded)
 	case resp.JSON500 != nil:
-		return &ErrServerError{message: resp.JSON500.Message}
+		return &ErrServerError{message: resp.JSON500.Message, StatusCode: http.StatusInternalServerError}
 	case resp.JSON503 != nil:
-		return errors.Errorf(resp.JSON500.Message+": %w", ErrServiceUnavailable)
+		return fmt.Errorf("%s: %w", resp.JSON503.Message, ErrServiceUnavailable)
 	default:
-		return &ErrInterfaceError{message: fmt.Sprintf("unexpected response from server. status code: %d", resp.HTTPResponse.StatusCode)}
+		return newUnexpectedResponse(resp.HTTPResponse, resp.Body)
 	}
 }
 
+// The query and exec methods below bind args on the client, see bindArguments, exactly as prepared statements do, so
+// they never return driver.ErrSkip: database/sql would prepare the statement only to bind args the same way. Arguments
+// that cannot be bound fail the statement rather than being ignored: values without a placeholder, placeholders without
+// a value, named values other than attachments and values of unsupported types, see CheckNamedValue.
+
 func (c *Conn) Query(query string, args []driver.Value) (driver.Rows, error) {
 	return c.QueryContext(context.TODO(), query, convertArgs(args))
 }
@@ -137,19 +242,50 @@ func (c *Conn) ExecContext(ctx context.Context, query string, args []driver.Name
 		return nil, driver.ErrBadConn
 	}
 
-	var attchments map[string]io.ReadCloser
-	if v := ctx.Value(sqlRequestAttachmentsKey); v != nil {
-		if v, ok := v.(*sqlRequestAttachments); ok {
-			attchments = v.attachments
+	query, err := bindArguments(query, args)
+	if err != nil {
+		return nil, err
+	}
+	if query, err = c.interceptStatement(ctx, query); err != nil {
+		return nil, err
+	}
+
+	attachments, err := statementAttachments(ctx, query, args)
+	if err != nil {
+		return nil, err
+	}
+
+	statements, split, err := c.splitQuery(query, attachments)
+	if err != nil {
+		return nil, err
+	}
+	if statements != nil {
+		return c.execStatements(ctx, statements, split)
+	}
+	return c.exec(ctx, query, attachments)
+}
+
+// exec executes a single statement, or buffers it within a transaction.
+func (c *Conn) exec(ctx context.Context, query string, attachments []Attachment) (driver.Result, error) {
+	if tx := c.currentTx(); tx != nil {
+		if err := tx.add(ctx, query, attachments); err != nil {
+			return nil, err
 		}
+		return &ExecResult{}, nil
 	}
 
-	_, err := c.submitStatement(ctx, attchments, query)
+	ctx, cancel := c.withDefaultQueryTimeout(ctx)
+	defer cancel()
+	rs, err := c.submitStatement(ctx, attachments, query)
 	if err != nil {
 		return nil, err
 	}
 
-	return &result{}, nil
+	res := newExecResult(rs, c.decodeOptions())
+	if dest, ok := ctx.Value(execResultKey).(*ExecResult); ok {
+		*dest = *res
+	}
+	return res, nil
 }
 
 func (c *Conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
@@ -157,113 +293,401 @@ func (c *Conn) QueryContext(ctx context.Context, query string, args []driver.Nam
 		return nil, driver.ErrBadConn
 	}
 
-	var attchments map[string]io.ReadCloser
-	if v := ctx.Value(sqlRequestAttachmentsKey); v != nil {
-		if v, ok := v.(*sqlRequestAttachments); ok {
-			attchments = v.attachments
-		}
+	if c.currentTx() != nil {
+		return nil, &ErrClientError{message: "queries are not supported within a transaction"}
+	}
+
+	query, err := bindArguments(query, args)
+	if err != nil {
+		return nil, err
+	}
+	if query, err = c.interceptStatement(ctx, query); err != nil {
+		return nil, err
 	}
 
-	rs, err := c.submitStatement(ctx, attchments, query)
+	attachments, err := statementAttachments(ctx, query, args)
 	if err != nil {
 		return nil, err
 	}
 
+	statements, split, err := c.splitQuery(query, attachments)
+	if err != nil {
+		return nil, err
+	}
+	if statements == nil {
+		return c.query(ctx, query, attachments)
+	}
+	last := len(statements) - 1
+	if _, err := c.execStatements(ctx, statements[:last], split[:last]); err != nil {
+		return nil, err
+	}
+	rows, err := c.query(ctx, statements[last], split[last])
+	if err != nil {
+		return nil, &ErrSequentialStatement{Index: last, Statement: statements[last], wrapErr: err}
+	}
+	return rows, nil
+}
+
+// query submits a single statement and returns its rows.
+func (c *Conn) query(ctx context.Context, query string, attachments []Attachment) (driver.Rows, error) {
+	ctx, cancel := c.withDefaultQueryTimeout(withResultVersion(withBytesReceived(ctx)))
+	rs, err := c.submitStatement(ctx, attachments, query)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	rows, err := c.queryRows(ctx, rs)
+	return releaseOnClose(rows, err, cancel)
+}
+
+// SubmitRequest submits req and returns its rows, as QueryContext does for statements. It gives access to the
+// parameters of statement requests not covered by the connection options, e.g. to run a single statement with another
+// role or with parameters not known to this version of the driver. Statement interceptors and the attachments added to
+// ctx apply as usual. database/sql does not expose it, use sql.Conn.Raw to access it.
+func (c *Conn) SubmitRequest(ctx context.Context, req *StatementRequest) (driver.Rows, error) {
+	if c == nil {
+		return nil, driver.ErrBadConn
+	}
+
+	if c.currentTx() != nil {
+		return nil, &ErrClientError{message: "queries are not supported within a transaction"}
+	}
+
+	query, err := c.interceptStatement(ctx, req.statement)
+	if err != nil {
+		return nil, err
+	}
+	args := make([]driver.NamedValue, len(req.attachments))
+	for i, a := range req.attachments {
+		args[i] = driver.NamedValue{Ordinal: i + 1, Value: a}
+	}
+	attachments, err := statementAttachments(ctx, query, args)
+	if err != nil {
+		return nil, err
+	}
+
+	r := *req
+	r.statement = query
+	r.attachments = attachments
+	ctx, cancel := c.withDefaultQueryTimeout(withResultVersion(withBytesReceived(ctx)))
+	rs, err := c.submitRequest(ctx, &r)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	rows, err := c.queryRows(ctx, rs)
+	return releaseOnClose(rows, err, cancel)
+}
+
+// queryRows returns the rows of the result set rs of a query, fetching them from the path requested by ctx.
+func (c *Conn) queryRows(ctx context.Context, rs *apiv2.ResultSet) (driver.Rows, error) {
+	var err error
+	transport := resultTransport(ctx)
+	if rs.Metadata.DataplaneRequest != nil && transport == ResultTransportControlPlane {
+		if rs.Metadata.DataplaneRequest.RequestType != apiv2.DataplaneRequestRequestTypeResultSet {
+			return nil, &ErrClientError{message: "streaming results are not available from the control plane"}
+		}
+		if rs, err = c.getStatement(ctx, rs.StatementID, 0); err != nil {
+			return nil, err
+		}
+		if rs.Metadata.DataplaneRequest != nil {
+			return nil, &ErrClientError{message: "results are not available from the control plane"}
+		}
+	}
+	if rs.Metadata.DataplaneRequest == nil && transport == ResultTransportDataplane {
+		return nil, &ErrClientError{message: "results are not available from the dataplane"}
+	}
+
 	if rs.Metadata.DataplaneRequest != nil {
 		if rs.Metadata.DataplaneRequest.RequestType == apiv2.DataplaneRequestRequestTypeResultSet {
-			dpconn, err := NewDPConn(*rs.Metadata.DataplaneRequest, c.sessionID, c.httpClient)
+			dpconn, err := c.newDPConn(*rs.Metadata.DataplaneRequest)
 			if err != nil {
 				return nil, &ErrClientError{message: err.Error()}
 			}
+			if resultVersionFrom(ctx) != nil {
+				// dataplanes identify the versions of their results on their own
+				ctx = withResultVersion(ctx)
+			}
 			rs, err := dpconn.getStatement(ctx, rs.StatementID, 0)
 			if err != nil {
 				return nil, err
 			}
-			return &resultSetRows{ctx: ctx, conn: dpconn, currentRowIdx: -1, currentPartitionIdx: 0, currentResultSet: rs, enableColumnDisplayHints: c.enableColumnDisplayHints}, nil
+			return c.newResultSetRows(ctx, dpconn, rs)
 		}
-		return newStreamingRows(ctx, c, *rs.Metadata.DataplaneRequest, c.httpClient, c.sessionID, c.enableColumnDisplayHints)
+		return c.openStream(ctx, rs.StatementID, *rs.Metadata.DataplaneRequest)
 	}
 
-	return &resultSetRows{ctx: ctx, conn: c, currentRowIdx: -1, currentPartitionIdx: 0, currentResultSet: rs, enableColumnDisplayHints: c.enableColumnDisplayHints}, nil
+	return c.newResultSetRows(ctx, c, rs)
+}
+
+// newResultSetRows returns the rows of the result set rs, whose further partitions are fetched from conn.
+func (c *Conn) newResultSetRows(ctx context.Context, conn ResultSetConn, rs *apiv2.ResultSet) (driver.Rows, error) {
+	if err := normalizeResultSet(rs); err != nil {
+		return nil, err
+	}
+	return &resultSetRows{ctx: ctx, conn: conn, currentRowIdx: -1, currentPartitionIdx: 0, partitionsFetched: 1, currentResultSet: rs, enableColumnDisplayHints: c.enableColumnDisplayHints, decodeOptions: c.decodeOptions(), partitionFetchRetry: c.partitionFetchRetry, rowBufferPooling: c.rowBufferPooling}, nil
+}
+
+// CheckNamedValue implements driver.NamedValueChecker. Attachments and literals are accepted as is, slices, maps and
+// structs as well as TimeOfDay and Decimal values are encoded with FormatLiteral, and all other values use the default
+// conversion, which rejects unsupported types. Values other than attachments are bound to the placeholders of the
+// statement.
+func (c *Conn) CheckNamedValue(nv *driver.NamedValue) error {
+	switch nv.Value.(type) {
+	case Attachment, *Attachment, Literal:
+		return nil
+	case TimeOfDay, Decimal:
+		// their driver.Value is a string, which would bind as a string literal
+		l, err := FormatLiteral(nv.Value)
+		if err != nil {
+			return err
+		}
+		nv.Value = l
+		return nil
+	}
+	if isCompositeValue(nv.Value) {
+		l, err := FormatLiteral(nv.Value)
+		if err != nil {
+			return err
+		}
+		nv.Value = l
+		return nil
+	}
+	return driver.ErrSkip
+}
+
+// interceptStatement applies the statement interceptors of the connection to query.
+func (c *Conn) interceptStatement(ctx context.Context, query string) (string, error) {
+	var err error
+	for _, intercept := range c.interceptors {
+		if query, err = intercept(ctx, query); err != nil {
+			return "", err
+		}
+	}
+	return query, nil
+}
+
+func (c *Conn) decodeOptions() decodeOptions {
+	return decodeOptions{legacyTimeColumns: c.legacyTimeColumns, rawJSONColumns: c.rawJSONColumns, decimalMode: c.decimalMode, location: c.timezone, timeParser: c.timeParser}
+}
+
+// Timezone returns the session timezone of the connection, see WithTimezone.
+func (c *Conn) Timezone() *time.Location {
+	if c.timezone == nil {
+		return time.UTC
+	}
+	return c.timezone
+}
+
+// dataplaneHTTPClient returns the http client for requests to dataplanes.
+func (c *Conn) dataplaneHTTPClient() *http.Client {
+	if c.dataplaneClient != nil {
+		return c.dataplaneClient
+	}
+	return c.httpClient
+}
+
+// newDPConn returns a dataplane connection sharing the settings of this connection.
+func (c *Conn) newDPConn(dpreq apiv2.DataplaneRequest) (*DPConn, error) {
+	dpconn, err := newDPConnAt(dpreq, c.session(), c.dataplaneHTTPClient(), c.dataplaneBasePath, c.maxResponseBytes)
+	if err != nil {
+		return nil, err
+	}
+	c.emit(ConnectionEvent{Type: ConnectionEventDataplaneAttached, StatementID: dpreq.StatementID, Dataplane: dpreq.Uri})
+	dpconn.maintenanceMode = c.maintenanceMode
+	dpconn.authHeaderProvider = c.authHeaderProvider
+	dpconn.jsonCodec = c.jsonCodec
+	dpconn.timezone = c.Timezone().String()
+	dpconn.strictSQLStates = c.strictSQLStates
+	if c.dataplanePollInterval > 0 {
+		dpconn.pollInterval = c.dataplanePollInterval
+	}
+	return dpconn, nil
 }
 
 func (c *Conn) Ping(ctx context.Context) error {
-	resp, err := c.client.GetVersion(ctx)
+	client, err := c.apiClient()
 	if err != nil {
 		return err
 	}
+	if c.pingCache.fresh(client) {
+		return nil
+	}
+	resp, err := client.GetVersion(ctx)
+	if err != nil {
+		return err
+	}
+	drainBody(resp)
+	c.compression.observe(resp)
 	if resp.StatusCode != 200 {
 		return driver.ErrBadConn
 	}
+	c.pingCache.success(client)
 	return nil
 }
 
 func (c *Conn) setResultSetContext(rsctx *apiv2.ResultSetContext) {
+	if rsctx == nil {
+		return
+	}
 	c.Lock()
 	defer c.Unlock()
+	if c.pinnedContext {
+		c.serverRsctx = rsctx
+		return
+	}
 	c.rsctx = rsctx
 }
 
+// AcceptServerContext adopts the context returned by the server for the last statement, e.g. after a USE statement,
+// on connections using WithPinnedContext. It returns false if there is no context to adopt.
+func (c *Conn) AcceptServerContext() bool {
+	c.Lock()
+	defer c.Unlock()
+	if c.serverRsctx == nil {
+		return false
+	}
+	c.rsctx = c.serverRsctx
+	c.serverRsctx = nil
+	return true
+}
+
 func (c *Conn) getResultSetContext() (rsctx *apiv2.ResultSetContext) {
 	c.RLock()
 	defer c.RUnlock()
 	return c.rsctx
 }
 
-func (c *Conn) submitStatement(ctx context.Context, attachments map[string]io.ReadCloser, query string) (rs *apiv2.ResultSet, err error) {
+// apiClient returns the client for the control plane of the organization of the connection, see WithEndpointResolver.
+func (c *Conn) apiClient() (*apiv2.ClientWithResponses, error) {
+	if c.endpoints == nil {
+		return c.client, nil
+	}
+	rsctx := c.getResultSetContext()
+	if rsctx == nil || rsctx.OrganizationID == nil {
+		return c.client, nil
+	}
+	return c.endpoints.client(*rsctx.OrganizationID)
+}
+
+func (c *Conn) submitStatement(ctx context.Context, attachments []Attachment, query string) (rs *apiv2.ResultSet, err error) {
+	return c.submitRequest(ctx, &StatementRequest{statement: query, attachments: attachments})
+}
+
+func (c *Conn) submitRequest(ctx context.Context, req *StatementRequest) (rs *apiv2.ResultSet, err error) {
 	if c.client == nil {
 		return nil, sql.ErrConnDone
 	}
 
-	rsctx := c.getResultSetContext()
-
-	request := &apiv2.SubmitStatementJSONRequestBody{
-		Statement:   query,
-		Role:        rsctx.RoleName,
-		Database:    rsctx.DatabaseName,
-		Schema:      rsctx.SchemaName,
-		Store:       rsctx.StoreName,
-		ComputePool: rsctx.ComputePoolName,
-		Parameters: &struct {
-			SessionID *string "json:\"sessionID,omitempty\""
-			Timezone  *string "json:\"timezone,omitempty\""
-		}{},
+	body, err := c.buildStatementRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	lost := ptr.Deref(c.session(), "")
+	rs, err = c.sendRequestBody(ctx, body)
+	var rejected *sessionRejectedError
+	if !errors.As(err, &rejected) {
+		return rs, err
 	}
-	if rsctx.OrganizationID != nil {
-		request.Organization = ptr.To(rsctx.OrganizationID.String())
+	if !body.replayable() {
+		return nil, rejected.err
 	}
-	if c.sessionID != nil {
-		request.Parameters.SessionID = c.sessionID
+	// the statement did not run, submit it again in a new session
+	if err := c.recoverSession(ctx, lost, ""); err != nil {
+		return nil, err
+	}
+	if body, err = c.buildStatementRequest(ctx, req); err != nil {
+		return nil, err
+	}
+	rs, err = c.sendRequestBody(ctx, body)
+	if errors.As(err, &rejected) {
+		return nil, rejected.err
 	}
+	return rs, err
+}
 
-	body := new(bytes.Buffer)
-	writer := multipart.NewWriter(body)
+func (c *Conn) sendRequestBody(ctx context.Context, body *statementBody) (*apiv2.ResultSet, error) {
+	if c.notReadyRetry == nil {
+		return c.sendStatementWithReauth(ctx, body)
+	}
+	return c.notReadyRetry.do(ctx, body.replayable, func() (*apiv2.ResultSet, error) {
+		return c.sendStatementWithReauth(ctx, body)
+	})
+}
 
-	h := make(textproto.MIMEHeader)
-	h.Set("Content-Disposition", `form-data; name="request";`)
-	h.Set("Content-Type", "application/json")
-	part, err := writer.CreatePart(h)
+func (c *Conn) buildStatementRequest(ctx context.Context, req *StatementRequest) (*statementBody, error) {
+	rb := req.body(c.getResultSetContext(), c.session(), c.timezone)
+	if req.computePool == nil && c.computePoolSelector != nil {
+		if pool := c.computePoolSelector(ctx, req.statement); pool != "" {
+			rb.ComputePool = &pool
+			c.logDebug("selected compute pool", "computePool", pool)
+		}
+	}
+	b, err := c.jsonCodec.Marshal(rb)
 	if err != nil {
 		return nil, &ErrClientError{message: "error building request", wrapErr: err}
 	}
-	if err = json.NewEncoder(part).Encode(request); err != nil {
-		return nil, &ErrClientError{message: "error building request", wrapErr: err}
+	attachments := req.attachments
+	if len(attachments) == 0 && c.statementEncoding == StatementEncodingAuto {
+		body := newJSONStatementBody(b)
+		body.computePool = ptr.Deref(rb.ComputePool, "")
+		return body, nil
 	}
-
-	for k, f := range attachments {
-		w, err := writer.CreateFormFile("attachments", k)
-		if err != nil {
-			return nil, &ErrClientError{message: "error building request", wrapErr: err}
-		}
-		_, err = io.Copy(w, f)
+	var compressed []bool
+	if c.compression != nil && len(attachments) > 0 {
+		client, err := c.apiClient()
 		if err != nil {
-			return nil, &ErrClientError{message: "error building request", wrapErr: err}
+			return nil, err
 		}
+		compressed = c.compression.compressed(ctx, client, attachments)
 	}
+	body, err := newStatementBody(b, attachments, compressed)
+	if err != nil {
+		return nil, err
+	}
+	body.computePool = ptr.Deref(rb.ComputePool, "")
+	return body, nil
+}
 
-	writer.Close()
-
-	resp, err := c.client.SubmitStatementWithBodyWithResponse(ctx, writer.FormDataContentType(), body)
+func (c *Conn) sendStatement(ctx context.Context, body *statementBody) (rs *apiv2.ResultSet, err error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	defer func() {
+		var sqlErr ErrSQLError
+		switch {
+		case rs != nil:
+			c.statementAccepted(ctx, rs.StatementID.String())
+			c.emit(ConnectionEvent{Type: ConnectionEventStatementSubmitted, StatementID: rs.StatementID.String(), ComputePool: body.computePool})
+		case errors.As(err, &sqlErr) && sqlErr.StatementID != uuid.Nil:
+			c.statementAccepted(ctx, sqlErr.StatementID.String())
+			c.emit(ConnectionEvent{Type: ConnectionEventStatementSubmitted, StatementID: sqlErr.StatementID.String(), ComputePool: body.computePool})
+		}
+	}()
+	client, err := c.apiClient()
+	if err != nil {
+		return nil, err
+	}
+	stream, err := body.open(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer stream.Close()
+	rsp, err := client.SubmitStatementWithBody(ctx, body.contentType(), newUploadReader(ctx, stream, body.length), func(ctx context.Context, req *http.Request) error {
+		req.ContentLength = body.length
+		return nil
+	})
+	// errors streaming the attachments, e.g. a size mismatch, are reported as is
+	if serr := stream.streamErr(); serr != nil {
+		return nil, serr
+	}
+	if err != nil {
+		return nil, &ErrInterfaceError{wrapErr: err, message: "unable to send request to server"}
+	}
+	c.compression.observe(rsp)
+	resultVersionFrom(ctx).observe(0, rsp)
+	resp, err := parseSubmitStatementResponse(c.jsonCodec, rsp)
 	if err != nil {
 		return nil, &ErrInterfaceError{wrapErr: err, message: "unable to send request to server"}
 	}
@@ -271,42 +695,68 @@ func (c *Conn) submitStatement(ctx context.Context, attachments map[string]io.Re
 	case resp.JSON200 != nil:
 		if resp.JSON200.SqlState == string(SqlStateSuccessfulCompletion) {
 			c.setResultSetContext(resp.JSON200.Metadata.Context)
+			recordBytesReceived(ctx, len(resp.Body))
 			return resp.JSON200, nil
 		}
-		return nil, ErrSQLError{
+		sqlErr := ErrSQLError{
 			SQLCode:     SqlState(resp.JSON200.SqlState),
 			Message:     ptr.Deref(resp.JSON200.Message, ""),
 			StatementID: resp.JSON200.StatementID,
+			strict:      c.strictSQLStates,
+		}
+		if c.recovers(ctx, sqlErr) {
+			return nil, &sessionRejectedError{err: sqlErr}
 		}
+		return nil, sqlErr
 	case resp.JSON202 != nil:
-		return c.getStatement(ctx, resp.JSON202.StatementID, 0)
+		return c.pollStatement(ctx, resp.JSON202.StatementID, 0, time.Now().Add(c.statementNotFoundGrace))
 	case resp.JSON400 != nil:
 		return nil, &ErrInterfaceError{message: resp.JSON400.Message}
 	case resp.JSON403 != nil:
-		return nil, errors.Errorf(resp.JSON403.Message+": %w", ErrAuthenticationError)
+		return nil, fmt.Errorf("%s: %w", resp.JSON403.Message, ErrAuthenticationError)
 	case resp.JSON404 != nil:
 		return nil, &ErrInterfaceError{message: resp.JSON404.Message}
 	case resp.JSON408 != nil:
-		return nil, errors.Errorf(resp.JSON408.Message+": %w", ErrDeadlineExceeded)
+		return nil, fmt.Errorf("%s: %w", resp.JSON408.Message, ErrDeadlineExceeded)
 	case resp.JSON500 != nil:
-		return nil, &ErrServerError{message: resp.JSON500.Message}
+		return nil, &ErrServerError{message: resp.JSON500.Message, StatusCode: http.StatusInternalServerError}
 	case resp.JSON503 != nil:
-		return nil, errors.Errorf(resp.JSON503.Message+": %w", ErrServiceUnavailable)
+		return nil, fmt.Errorf("%s: %w", resp.JSON503.Message, ErrServiceUnavailable)
 	default:
-		return nil, &ErrInterfaceError{message: fmt.Sprintf("unexpected response from server. status code: %d", resp.HTTPResponse.StatusCode)}
+		return nil, newUnexpectedResponse(resp.HTTPResponse, resp.Body)
 	}
 }
 
 func (c *Conn) getStatement(ctx context.Context, statementID uuid.UUID, partitionID int32) (rs *apiv2.ResultSet, err error) {
+	return c.pollStatement(ctx, statementID, partitionID, time.Time{})
+}
+
+// pollStatement polls the partition partitionID of the statement statementID until it completes. Responses that the
+// statement is not found are polled again until graceUntil, see WithStatementNotFoundGrace.
+func (c *Conn) pollStatement(ctx context.Context, statementID uuid.UUID, partitionID int32, graceUntil time.Time) (rs *apiv2.ResultSet, err error) {
 	if c.client == nil {
 		return nil, sql.ErrConnDone
 	}
 
-	t := time.NewTicker(time.Second)
-	defer t.Stop()
+	poll := c.pollInterval
+	if poll <= 0 {
+		poll = pollInterval(defaultControlPlanePollInterval)
+	}
 
+	client, err := c.apiClient()
+	if err != nil {
+		return nil, err
+	}
+	recovered := false
 	for {
-		resp, err := c.client.GetStatementStatusWithResponse(ctx, statementID, &apiv2.GetStatementStatusParams{PartitionID: &partitionID, SessionID: c.sessionID, Timezone: ptr.To("UTC")})
+		version := resultVersionFrom(ctx)
+		sessionID := c.session()
+		rsp, err := client.GetStatementStatus(ctx, statementID, &apiv2.GetStatementStatusParams{PartitionID: &partitionID, SessionID: sessionID, Timezone: ptr.To(c.Timezone().String())}, version.ifMatch(partitionID))
+		if err != nil {
+			return nil, &ErrInterfaceError{wrapErr: err, message: "unable to send request to server"}
+		}
+		version.observe(partitionID, rsp)
+		resp, err := parseGetStatementStatusResponse(responseCodec(ctx, c.jsonCodec), rsp)
 		if err != nil {
 			return nil, &ErrInterfaceError{wrapErr: err, message: "unable to send request to server"}
 		}
@@ -314,34 +764,48 @@ func (c *Conn) getStatement(ctx context.Context, statementID uuid.UUID, partitio
 		case resp.JSON200 != nil:
 			if resp.JSON200.SqlState == string(SqlStateSuccessfulCompletion) {
 				c.setResultSetContext(resp.JSON200.Metadata.Context)
+				recordBytesReceived(ctx, len(resp.Body))
 				return resp.JSON200, nil
 			}
-			return nil, ErrSQLError{
+			sqlErr := ErrSQLError{
 				SQLCode:     SqlState(resp.JSON200.SqlState),
 				Message:     ptr.Deref(resp.JSON200.Message, ""),
 				StatementID: resp.JSON200.StatementID,
+				strict:      c.strictSQLStates,
 			}
+			if recovered || !c.recovers(ctx, sqlErr) {
+				return nil, sqlErr
+			}
+			// the statement is still known, poll it again in a new session
+			if err := c.recoverSession(ctx, *sessionID, ""); err != nil {
+				return nil, err
+			}
+			recovered = true
+			continue
 		case resp.JSON202 != nil:
 			// drop out of switch to sleep and retry
 		case resp.JSON400 != nil:
 			return nil, &ErrInterfaceError{message: resp.JSON400.Message}
 		case resp.JSON403 != nil:
-			return nil, errors.Errorf(resp.JSON403.Message+": %w", ErrAuthenticationError)
+			return nil, fmt.Errorf("%s: %w", resp.JSON403.Message, ErrAuthenticationError)
 		case resp.JSON404 != nil:
+			if time.Now().Before(graceUntil) {
+				// the statement was just accepted, its status may not have propagated yet
+				break
+			}
 			return nil, &ErrInterfaceError{message: resp.JSON404.Message}
 		case resp.JSON408 != nil:
-			return nil, errors.Errorf(resp.JSON408.Message+": %w", ErrDeadlineExceeded)
+			return nil, fmt.Errorf("%s: %w", resp.JSON408.Message, ErrDeadlineExceeded)
 		case resp.JSON500 != nil:
-			return nil, &ErrServerError{message: resp.JSON500.Message}
+			return nil, &ErrServerError{message: resp.JSON500.Message, StatusCode: http.StatusInternalServerError}
 		case resp.JSON503 != nil:
-			return nil, errors.Errorf(resp.JSON503.Message+": %w", ErrServiceUnavailable)
+			return nil, fmt.Errorf("%s: %w", resp.JSON503.Message, ErrServiceUnavailable)
+		default:
+			return nil, newUnexpectedResponse(resp.HTTPResponse, resp.Body)
 		}
 
-		select {
-		case <-ctx.Done():
-			return nil, ctx.Err()
-		case <-t.C:
-			continue
+		if err := poll.wait(ctx); err != nil {
+			return nil, err
 		}
 	}
 }
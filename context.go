@@ -19,22 +19,252 @@ package godeltastream
 import (
 	"context"
 	"io"
+	"net/http"
+	"time"
 )
 
 type ctxkey string
 
 var sqlRequestAttachmentsKey ctxkey = "sqlRequestAttachmentsKey"
+var uploadProgressKey ctxkey = "uploadProgressKey"
+var maintenanceModeKey ctxkey = "maintenanceModeKey"
+var execResultKey ctxkey = "execResultKey"
+var resultTransportKey ctxkey = "resultTransportKey"
+var httpClientOverrideKey ctxkey = "httpClientOverrideKey"
+var batchModeKey ctxkey = "batchModeKey"
+var debugCaptureKey ctxkey = "debugCaptureKey"
+var rowsStatsKey ctxkey = "rowsStatsKey"
+var bytesReceivedKey ctxkey = "bytesReceivedKey"
+var streamSinceKey ctxkey = "streamSinceKey"
+var noDefaultQueryTimeoutKey ctxkey = "noDefaultQueryTimeoutKey"
+var httpTraceKey ctxkey = "httpTraceKey"
+var resultVersionKey ctxkey = "resultVersionKey"
+var rowBufferKey ctxkey = "rowBufferKey"
+var streamMessageTapKey ctxkey = "streamMessageTapKey"
+var sessionRecoveryKey ctxkey = "sessionRecoveryKey"
+var statementIDsKey ctxkey = "statementIDsKey"
+
+// maintenanceModeHeader marks requests sent while the caller operates in maintenance mode.
+const maintenanceModeHeader = "deltastream-maintenance"
 
 type sqlRequestAttachments struct {
-	attachments map[string]io.ReadCloser
+	attachments []Attachment // in the order they were added
 }
 
+// WithAttachment adds an attachment named paramName to statements executed using ctx.
 func WithAttachment(ctx context.Context, paramName string, r io.ReadCloser) context.Context {
-	if v := ctx.Value(sqlRequestAttachmentsKey); v != nil {
-		if v, ok := v.(*sqlRequestAttachments); ok {
-			v.attachments[paramName] = r
-			return ctx
+	return WithAttachments(ctx, Attachment{Name: paramName, Reader: r})
+}
+
+// WithAttachments adds attachments to statements executed using ctx. Attachments replace previously added attachments
+// with the same name.
+func WithAttachments(ctx context.Context, attachments ...Attachment) context.Context {
+	return addAttachments(ctx, attachments, true)
+}
+
+// AddAttachments adds attachments to statements executed using ctx, after those added before. Unlike WithAttachments,
+// attachments with the name of a previously added attachment are added as well, e.g. the source files of a function
+// that share a name. Attachments are sent in the order they were added.
+func AddAttachments(ctx context.Context, attachments ...Attachment) context.Context {
+	return addAttachments(ctx, attachments, false)
+}
+
+func addAttachments(ctx context.Context, attachments []Attachment, replace bool) context.Context {
+	v, ok := ctx.Value(sqlRequestAttachmentsKey).(*sqlRequestAttachments)
+	if !ok {
+		v = &sqlRequestAttachments{}
+		ctx = context.WithValue(ctx, sqlRequestAttachmentsKey, v)
+	}
+	for _, a := range attachments {
+		if replace {
+			v.remove(a.Name)
+		}
+		v.attachments = append(v.attachments, a)
+	}
+	return ctx
+}
+
+// remove removes the attachments named name.
+func (v *sqlRequestAttachments) remove(name string) {
+	kept := v.attachments[:0]
+	for _, a := range v.attachments {
+		if a.Name != name {
+			kept = append(kept, a)
+		}
+	}
+	v.attachments = kept
+}
+
+// UploadProgressFunc receives the number of bytes of a statement request sent so far and its total size. total is -1
+// if the size of an attachment is not known.
+type UploadProgressFunc func(sent, total int64)
+
+// WithUploadProgress reports the upload progress of statements executed using ctx, which is mostly useful for
+// statements with large attachments.
+func WithUploadProgress(ctx context.Context, progress UploadProgressFunc) context.Context {
+	return context.WithValue(ctx, uploadProgressKey, progress)
+}
+
+// WithMaintenanceModeOverride enables or disables the deltastream-maintenance header for statements executed using ctx,
+// regardless of the WithMaintenanceMode connection option.
+func WithMaintenanceModeOverride(ctx context.Context, enabled bool) context.Context {
+	return context.WithValue(ctx, maintenanceModeKey, enabled)
+}
+
+func maintenanceMode(ctx context.Context, defaultEnabled bool) bool {
+	if v, ok := ctx.Value(maintenanceModeKey).(bool); ok {
+		return v
+	}
+	return defaultEnabled
+}
+
+// WithExecResult stores the ExecResult of statements executed with ExecContext using ctx into dest.
+func WithExecResult(ctx context.Context, dest *ExecResult) context.Context {
+	return context.WithValue(ctx, execResultKey, dest)
+}
+
+// WithStatementIDs stores the ids of the connection and of the last statement submitted using ctx into dest, for both
+// ExecContext and QueryContext, e.g. to log them along with the errors of statements. dest is left unchanged if the
+// server accepted no statement.
+func WithStatementIDs(ctx context.Context, dest *StatementIDs) context.Context {
+	return context.WithValue(ctx, statementIDsKey, dest)
+}
+
+// WithRowsStats stores the consumption statistics of the rows of queries executed using ctx into dest when the rows
+// are closed.
+func WithRowsStats(ctx context.Context, dest *RowsStats) context.Context {
+	return context.WithValue(ctx, rowsStatsKey, dest)
+}
+
+// WithStreamSince makes streaming queries executed using ctx skip the records whose event time is before since, e.g. to
+// resume a stream or to tail a changelog from a snapshot, see Backfill. Records without an event time are not skipped.
+func WithStreamSince(ctx context.Context, since time.Time) context.Context {
+	return context.WithValue(ctx, streamSinceKey, since)
+}
+
+func streamSince(ctx context.Context) time.Time {
+	since, _ := ctx.Value(streamSinceKey).(time.Time)
+	return since
+}
+
+// StreamMessageTap receives a copy of a raw frame of a streaming result, see WithStreamMessageTap.
+type StreamMessageTap func(messageType string, raw []byte)
+
+// WithStreamMessageTap passes copies of the websocket frames of streaming queries executed using ctx to tap, in the
+// order they are sent and received, e.g. to capture protocol issues between the driver and the dataplane. messageType
+// is the type field of the frame, empty if the frame is not JSON. The authentication message is passed with its token
+// redacted. tap is called from the goroutine reading the stream, so it must return quickly.
+func WithStreamMessageTap(ctx context.Context, tap StreamMessageTap) context.Context {
+	return context.WithValue(ctx, streamMessageTapKey, tap)
+}
+
+func streamMessageTap(ctx context.Context) StreamMessageTap {
+	tap, _ := ctx.Value(streamMessageTapKey).(StreamMessageTap)
+	return tap
+}
+
+// WithoutDefaultQueryTimeout exempts statements executed using ctx from the timeout set with WithDefaultQueryTimeout,
+// e.g. for streaming queries read for as long as the application runs.
+func WithoutDefaultQueryTimeout(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noDefaultQueryTimeoutKey, true)
+}
+
+func defaultQueryTimeoutDisabled(ctx context.Context) bool {
+	disabled, _ := ctx.Value(noDefaultQueryTimeoutKey).(bool)
+	return disabled
+}
+
+// ResultTransport is the path results of a query are fetched through.
+type ResultTransport int
+
+const (
+	// ResultTransportAuto fetches results through the path chosen by the server.
+	ResultTransportAuto ResultTransport = iota
+	// ResultTransportControlPlane fetches results from the control plane api.
+	ResultTransportControlPlane
+	// ResultTransportDataplane fetches results from the dataplane the statement ran on.
+	ResultTransportDataplane
+)
+
+func (t ResultTransport) String() string {
+	switch t {
+	case ResultTransportControlPlane:
+		return "control plane"
+	case ResultTransportDataplane:
+		return "dataplane"
+	default:
+		return "auto"
+	}
+}
+
+// WithResultTransport pins the path results of queries executed using ctx are fetched through, which is mostly useful
+// to debug networking issues. Queries fail if their results are not available through the requested path, e.g.
+// streaming results are only served by the dataplane.
+func WithResultTransport(ctx context.Context, transport ResultTransport) context.Context {
+	return context.WithValue(ctx, resultTransportKey, transport)
+}
+
+func resultTransport(ctx context.Context) ResultTransport {
+	if v, ok := ctx.Value(resultTransportKey).(ResultTransport); ok {
+		return v
+	}
+	return ResultTransportAuto
+}
+
+// WithHTTPClientOverride sends the control plane, dataplane and streaming requests of statements executed using ctx
+// through client instead of the connection's http client, e.g. to use a per-tenant proxy.
+func WithHTTPClientOverride(ctx context.Context, client *http.Client) context.Context {
+	return context.WithValue(ctx, httpClientOverrideKey, client)
+}
+
+func httpClientOverride(ctx context.Context, defaultClient *http.Client) *http.Client {
+	if v, ok := ctx.Value(httpClientOverrideKey).(*http.Client); ok && v != nil {
+		return v
+	}
+	return defaultClient
+}
+
+// contextHTTPClient sends requests through the http client overriding client in the request context, if any.
+type contextHTTPClient struct {
+	client           *http.Client
+	maxResponseBytes int64 // see WithMaxResponseBytes
+}
+
+func (c contextHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	client := httpClientOverride(req.Context(), c.client)
+	send := client.Do
+	if t, ok := req.Context().Value(httpTraceKey).(*HTTPTrace); ok && t != nil {
+		send = func(req *http.Request) (*http.Response, error) {
+			return t.do(client.Do, req)
 		}
 	}
-	return context.WithValue(ctx, sqlRequestAttachmentsKey, &sqlRequestAttachments{attachments: map[string]io.ReadCloser{paramName: r}})
+	do := func(req *http.Request) (*http.Response, error) {
+		rsp, err := send(req)
+		if err != nil {
+			return nil, err
+		}
+		return limitResponse(rsp, c.maxResponseBytes)
+	}
+	if d, ok := req.Context().Value(debugCaptureKey).(*DebugCapture); ok && d != nil {
+		return d.do(do, req)
+	}
+	return do(req)
+}
+
+// WithDebugCapture records the control plane and dataplane requests of statements executed using ctx into dest, with
+// their raw response bodies, status codes and timings.
+func WithDebugCapture(ctx context.Context, dest *DebugCapture) context.Context {
+	return context.WithValue(ctx, debugCaptureKey, dest)
+}
+
+// WithHTTPTrace records the timings of the control plane and dataplane requests of statements executed using ctx into
+// dest: DNS lookup, connection, TLS handshake and time to first byte.
+func WithHTTPTrace(ctx context.Context, dest *HTTPTrace) context.Context {
+	return context.WithValue(ctx, httpTraceKey, dest)
+}
+
+// WithBatchMode selects how transactions begun using ctx submit their statements. Transactions use BatchSequential by
+// default.
+func WithBatchMode(ctx context.Context, mode BatchMode) context.Context {
+	return context.WithValue(ctx, batchModeKey, mode)
 }
@@ -18,14 +18,27 @@ package godeltastream
 
 import (
 	"bytes"
+	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"io"
+	"math/big"
+	"mime"
+	"mime/multipart"
 	"net/http"
+	"reflect"
 	"testing"
 	"time"
+	_ "time/tzdata"
 
+	"github.com/gorilla/websocket"
 	"github.com/jarcoal/httpmock"
 	. "github.com/onsi/gomega"
+	"k8s.io/utils/ptr"
+
+	"github.com/deltastreaminc/go-deltastream/apiv2"
+	"github.com/deltastreaminc/go-deltastream/dstest"
 )
 
 func TestDatatypes(t *testing.T) {
@@ -103,7 +116,7 @@ func TestDatatypes(t *testing.T) {
 		decimal                float64
 		timestamp              time.Time
 		date                   string
-		timev                  time.Time
+		timev                  TimeOfDay
 		timestamp_ltz          time.Time
 		varbinary              []byte
 		bytes                  []byte
@@ -121,7 +134,7 @@ func TestDatatypes(t *testing.T) {
 		decimal_nullable       *float64
 		timestamp_nullable     *time.Time
 		date_nullable          *string
-		time_nullable          *time.Time
+		time_nullable          *TimeOfDay
 		timestamp_ltz_nullable *time.Time
 		varbinary_nullable     *[]byte
 		bytes_nullable         *[]byte
@@ -171,4 +184,423 @@ func TestDatatypes(t *testing.T) {
 		g.Expect(err).To(BeNil())
 	}
 	g.Expect(rows.Err()).To(BeNil())
+	g.Expect(timev).To(Equal(NewTimeOfDay(13, 10, 2, 47438100)))
+	g.Expect(timev.String()).To(Equal("13:10:02.0474381"))
+}
+
+func TestLegacyTimeColumns(t *testing.T) {
+	g := NewWithT(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("POST", "https://api.deltastream.io/v2/statements",
+		mockSubmitStatementsResponser(g, http.StatusOK, "sometoken", "TEST DATATYPES;", map[string][]byte{}, "fixtures/test-datatypes-200-00000-4.json"),
+	)
+
+	connector, err := ConnectorWithOptions(context.TODO(), WithServer("https://api.deltastream.io/v2"), WithStaticToken("sometoken"), WithLegacyTimeColumns())
+	g.Expect(err).To(BeNil())
+	db := sql.OpenDB(connector)
+
+	rows, err := db.Query("TEST DATATYPES;")
+	g.Expect(err).To(BeNil())
+
+	ctypes, err := rows.ColumnTypes()
+	g.Expect(err).To(BeNil())
+	g.Expect(ctypes[10].Name()).To(Equal("TIME"))
+	g.Expect(ctypes[10].ScanType()).To(Equal(reflect.TypeOf(time.Time{})))
+
+	values := make([]any, len(ctypes))
+	dest := make([]any, len(ctypes))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	g.Expect(rows.Next()).To(BeTrue())
+	g.Expect(rows.Scan(dest...)).To(BeNil())
+	g.Expect(values[10]).To(Equal(time.Date(0, 1, 1, 13, 10, 2, 47438100, time.UTC)))
+}
+
+func TestRawJSONColumns(t *testing.T) {
+	g := NewWithT(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("POST", "https://api.deltastream.io/v2/statements", compareResponder(compareResultSet(
+		[]string{"id", "tags"}, `["1", "{\"a\": 1}"]`, `["2", null]`,
+	)))
+	connector, err := ConnectorWithOptions(context.TODO(), WithServer("https://api.deltastream.io/v2"), WithStaticToken("sometoken"), WithRawJSONColumns())
+	g.Expect(err).To(BeNil())
+	db := sql.OpenDB(connector)
+	defer db.Close()
+
+	rows, err := db.Query("SELECT * FROM orders;")
+	g.Expect(err).To(BeNil())
+	ctypes, err := rows.ColumnTypes()
+	g.Expect(err).To(BeNil())
+	g.Expect(ctypes[1].ScanType()).To(Equal(reflect.TypeOf(json.RawMessage{})))
+
+	var (
+		id      int64
+		tags    json.RawMessage
+		tagsPtr *json.RawMessage
+	)
+	g.Expect(rows.Next()).To(BeTrue())
+	g.Expect(rows.Scan(&id, &tags)).To(Succeed())
+	g.Expect(string(tags)).To(Equal(`{"a": 1}`))
+	var decoded map[string]int
+	g.Expect(json.Unmarshal(tags, &decoded)).To(Succeed())
+	g.Expect(decoded).To(Equal(map[string]int{"a": 1}))
+	g.Expect(rows.Next()).To(BeTrue())
+	g.Expect(rows.Scan(&id, &tagsPtr)).To(Succeed())
+	g.Expect(tagsPtr).To(BeNil())
+	g.Expect(rows.Next()).To(BeFalse())
+	g.Expect(rows.Close()).To(Succeed())
+
+	// streamed values are decoded the same way
+	server := dstest.NewStreamingServer(
+		dstest.Metadata(dstest.Column{Name: "items", Type: "ARRAY<VARCHAR>", Nullable: true}),
+		dstest.Row(`["a", "b"]`),
+		dstest.Close(websocket.CloseNormalClosure, ""),
+	)
+	defer server.Close()
+	httpmock.RegisterResponder("POST", "https://api.deltastream.io/v2/statements", httpmock.NewJsonResponderOrPanic(http.StatusOK, server.StatementResponse(nil)))
+	rows, err = db.Query("SELECT * FROM carts;")
+	g.Expect(err).To(BeNil())
+	g.Expect(rows.Next()).To(BeTrue())
+	g.Expect(rows.Scan(&tagsPtr)).To(Succeed())
+	g.Expect(string(*tagsPtr)).To(Equal(`["a", "b"]`))
+	g.Expect(rows.Next()).To(BeFalse())
+	g.Expect(rows.Err()).To(BeNil())
+	g.Expect(rows.Close()).To(Succeed())
+}
+
+func TestParseTimeOfDay(t *testing.T) {
+	g := NewWithT(t)
+
+	tod, err := ParseTimeOfDay("07:08:09")
+	g.Expect(err).To(BeNil())
+	g.Expect(tod).To(Equal(NewTimeOfDay(7, 8, 9, 0)))
+	g.Expect(tod.String()).To(Equal("07:08:09"))
+
+	tod, err = ParseTimeOfDay("23:59:59.5+05:30")
+	g.Expect(err).To(BeNil())
+	g.Expect([]int{tod.Hour(), tod.Minute(), tod.Second(), tod.Nanosecond()}).To(Equal([]int{23, 59, 59, 500000000}))
+	g.Expect(tod.String()).To(Equal("23:59:59.5+05:30"))
+	g.Expect(tod.On(time.Date(2024, 2, 3, 0, 0, 0, 0, time.UTC)).UTC()).To(Equal(time.Date(2024, 2, 3, 18, 29, 59, 500000000, time.UTC)))
+
+	tod, err = ParseTimeOfDay("00:00:01Z")
+	g.Expect(err).To(BeNil())
+	g.Expect(tod.String()).To(Equal("00:00:01Z"))
+
+	_, err = ParseTimeOfDay("25:00:00")
+	g.Expect(err).ToNot(BeNil())
+
+	var scanned TimeOfDay
+	g.Expect(scanned.Scan("12:00:00")).To(BeNil())
+	g.Expect(scanned).To(Equal(NewTimeOfDay(12, 0, 0, 0)))
+	g.Expect(scanned.Scan(42)).ToNot(BeNil())
+}
+
+func TestDecimalMode(t *testing.T) {
+	g := NewWithT(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("POST", "https://api.deltastream.io/v2/statements", compareResponder(compareResultSet(
+		[]string{"id", "amount"}, `["1", "12345678901234567.89"]`, `["2", "1.5E+1"]`, `["3", null]`,
+	)))
+
+	// query returns the scan type of the amount column and its values
+	query := func(opts ...ConnectionOption) (reflect.Type, []any) {
+		connector, err := ConnectorWithOptions(context.TODO(), append([]ConnectionOption{WithServer("https://api.deltastream.io/v2"), WithStaticToken("sometoken")}, opts...)...)
+		g.Expect(err).To(BeNil())
+		db := sql.OpenDB(connector)
+		defer db.Close()
+		rows, err := db.Query("SELECT * FROM orders;")
+		g.Expect(err).To(BeNil())
+		defer rows.Close()
+		ctypes, err := rows.ColumnTypes()
+		g.Expect(err).To(BeNil())
+		var values []any
+		for rows.Next() {
+			var (
+				id     int64
+				amount any
+			)
+			g.Expect(rows.Scan(&id, &amount)).To(Succeed())
+			values = append(values, amount)
+		}
+		g.Expect(rows.Err()).To(BeNil())
+		return ctypes[1].ScanType(), values
+	}
+
+	scanType, values := query()
+	g.Expect(scanType).To(Equal(reflect.TypeOf(float64(0))))
+	g.Expect(values).To(Equal([]any{12345678901234567.89, 15.0, nil}))
+
+	scanType, values = query(WithDecimalMode(DecimalModeString))
+	g.Expect(scanType).To(Equal(reflect.TypeOf("")))
+	g.Expect(values).To(Equal([]any{"12345678901234567.89", "1.5E+1", nil}))
+
+	scanType, values = query(WithDecimalMode(DecimalModeDecimal))
+	g.Expect(scanType).To(Equal(reflect.TypeOf(Decimal{})))
+	g.Expect(values).To(HaveLen(3))
+	g.Expect(values[0].(Decimal).String()).To(Equal("12345678901234567.89"))
+	g.Expect(values[1].(Decimal).String()).To(Equal("15"))
+	g.Expect(values[2]).To(BeNil())
+
+	// values may be scanned into Decimal in every mode
+	connector, err := ConnectorWithOptions(context.TODO(), WithServer("https://api.deltastream.io/v2"), WithStaticToken("sometoken"), WithDecimalMode(DecimalModeString))
+	g.Expect(err).To(BeNil())
+	db := sql.OpenDB(connector)
+	defer db.Close()
+	var (
+		id     int64
+		amount Decimal
+	)
+	g.Expect(db.QueryRow("SELECT * FROM orders;").Scan(&id, &amount)).To(Succeed())
+	g.Expect(amount.Cmp(NewDecimal(big.NewInt(1234567890123456789), 2))).To(Equal(0))
+}
+
+func TestParseDecimal(t *testing.T) {
+	g := NewWithT(t)
+
+	for s, expected := range map[string]string{
+		"0":          "0",
+		"-1.50":      "-1.50",
+		"0.05":       "0.05",
+		"-.5":        "-0.5",
+		"+7":         "7",
+		"1.2345E+2":  "123.45",
+		"1.5e3":      "1500",
+		"25e-4":      "0.0025",
+		"12345.6789": "12345.6789",
+	} {
+		d, err := ParseDecimal(s)
+		g.Expect(err).To(BeNil(), s)
+		g.Expect(d.String()).To(Equal(expected), s)
+	}
+	for _, s := range []string{"", ".", "-", "1.-5", ".-5", "1e", "1e99999", "abc", "1.2.3"} {
+		_, err := ParseDecimal(s)
+		g.Expect(err).ToNot(BeNil(), s)
+	}
+
+	d, err := ParseDecimal("-12.340")
+	g.Expect(err).To(BeNil())
+	g.Expect(d.Unscaled()).To(Equal(big.NewInt(-12340)))
+	g.Expect(d.Scale()).To(Equal(int32(3)))
+	g.Expect(d.Float64()).To(Equal(-12.34))
+	g.Expect(d.Rat()).To(Equal(big.NewRat(-617, 50)))
+	g.Expect(d.Cmp(NewDecimal(big.NewInt(-1234), 2))).To(Equal(0))
+	g.Expect(d.Cmp(Decimal{})).To(Equal(-1))
+	g.Expect(Decimal{}.String()).To(Equal("0"))
+	g.Expect(NewDecimal(big.NewInt(12), -2).String()).To(Equal("1200"))
+
+	var scanned Decimal
+	g.Expect(scanned.Scan("3.14")).To(Succeed())
+	g.Expect(scanned.String()).To(Equal("3.14"))
+	g.Expect(scanned.Scan(2.5)).To(Succeed())
+	g.Expect(scanned.String()).To(Equal("2.5"))
+	g.Expect(scanned.Scan(int64(42))).To(Succeed())
+	g.Expect(scanned.String()).To(Equal("42"))
+	g.Expect(scanned.Scan(true)).ToNot(Succeed())
+	v, err := scanned.Value()
+	g.Expect(err).To(BeNil())
+	g.Expect(v).To(Equal("42"))
+}
+
+func TestTimezone(t *testing.T) {
+	g := NewWithT(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	loc, err := time.LoadLocation("Europe/Paris")
+	g.Expect(err).To(BeNil())
+	server := dstest.NewStreamingServer(
+		dstest.Metadata(dstest.Column{Name: "createdAt", Type: "TIMESTAMP_LTZ"}),
+		dstest.Row("2007-04-30 13:10:02.5"),
+		dstest.Row("2007-04-30 13:10:02Z"),
+	)
+	defer server.Close()
+
+	var timezone string
+	httpmock.RegisterResponder("POST", "https://api.deltastream.io/v2/statements", func(r *http.Request) (*http.Response, error) {
+		_, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		g.Expect(err).To(BeNil())
+		p, err := multipart.NewReader(r.Body, params["boundary"]).NextPart()
+		g.Expect(err).To(BeNil())
+		req := apiv2.SubmitStatementJSONRequestBody{}
+		g.Expect(json.NewDecoder(p).Decode(&req)).To(Succeed())
+		timezone = *req.Parameters.Timezone
+		return httpmock.NewJsonResponse(http.StatusOK, server.StatementResponse(nil))
+	})
+
+	connector, err := ConnectorWithOptions(context.TODO(), WithServer("https://api.deltastream.io/v2"), WithStaticToken("sometoken"), WithTimezone(loc))
+	g.Expect(err).To(BeNil())
+	rows, err := sql.OpenDB(connector).Query("SELECT * FROM pageviews;")
+	g.Expect(err).To(BeNil())
+	defer rows.Close()
+	g.Expect(timezone).To(Equal("Europe/Paris"))
+
+	// values without an offset are in the session timezone, and all values are returned in it
+	var createdAt time.Time
+	g.Expect(rows.Next()).To(BeTrue())
+	g.Expect(rows.Scan(&createdAt)).To(Succeed())
+	g.Expect(createdAt).To(Equal(time.Date(2007, 4, 30, 13, 10, 2, 500000000, loc)))
+	g.Expect(rows.Next()).To(BeTrue())
+	g.Expect(rows.Scan(&createdAt)).To(Succeed())
+	g.Expect(createdAt.Location()).To(Equal(loc))
+	g.Expect(createdAt.Equal(time.Date(2007, 4, 30, 13, 10, 2, 0, time.UTC))).To(BeTrue())
+
+	// the result sets of the control plane use the same timezone
+	v, err := parseTime("2007-04-30 13:10:02", "TIMESTAMP_LTZ", loc)
+	g.Expect(err).To(BeNil())
+	g.Expect(v).To(Equal(time.Date(2007, 4, 30, 13, 10, 2, 0, loc)))
+
+	// the server cannot resolve the local timezone or fixed zones
+	_, err = ConnectorWithOptions(context.TODO(), WithServer("https://api.deltastream.io/v2"), WithStaticToken("sometoken"), WithTimezone(time.Local))
+	g.Expect(err).To(MatchError(&ErrClientError{message: "time.Local cannot be used as timezone, load the location by its IANA name instead"}))
+	_, err = ConnectorWithOptions(context.TODO(), WithServer("https://api.deltastream.io/v2"), WithStaticToken("sometoken"), WithTimezone(time.FixedZone("UTC+2", 2*60*60)))
+	g.Expect(err).To(MatchError(&ErrClientError{message: `timezone "UTC+2" is not an IANA time zone name`}))
+}
+
+func TestTimeParser(t *testing.T) {
+	g := NewWithT(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	// ISO 8601 timestamps, which the default parsing rejects, and the default formats otherwise
+	var parsed []string
+	parser := func(raw, colType string) (time.Time, error) {
+		parsed = append(parsed, colType+" "+raw)
+		if t, err := time.Parse(time.RFC3339Nano, raw); err == nil {
+			return t, nil
+		}
+		return ParseTime(raw, colType)
+	}
+	httpmock.RegisterResponder("POST", "https://api.deltastream.io/v2/statements", compareResponder(`{
+		"sqlState": "00000",
+		"statementID": "d789687d-4e1b-4649-846e-4f10b722f3ad",
+		"createdOn": 1703907114,
+		"metadata": {"encoding": "json", "partitionInfo": [{"rowCount": 2}], "columns": [
+			{"name": "updated", "type": "TIMESTAMP(3)", "nullable": false},
+			{"name": "seen", "type": "TIMESTAMP_LTZ(3)", "nullable": false}
+		], "context": {}},
+		"data": [["2024-01-01T10:00:00.5Z", "2024-01-01 10:00:00Z"], ["2024-01-01 11:00:00", "2024-01-01T11:00:00+01:00"]]
+	}`))
+
+	loc, err := time.LoadLocation("Europe/Athens")
+	g.Expect(err).To(BeNil())
+	connector, err := ConnectorWithOptions(context.TODO(), WithServer("https://api.deltastream.io/v2"), WithStaticToken("sometoken"), WithTimezone(loc), WithTimeParser(parser))
+	g.Expect(err).To(BeNil())
+	db := sql.OpenDB(connector)
+	defer db.Close()
+	rows, err := db.Query("SELECT * FROM pageviews;")
+	g.Expect(err).To(BeNil())
+	defer rows.Close()
+
+	var updated, seen time.Time
+	g.Expect(rows.Next()).To(BeTrue())
+	g.Expect(rows.Scan(&updated, &seen)).To(Succeed())
+	g.Expect(updated).To(Equal(time.Date(2024, 1, 1, 10, 0, 0, 500000000, time.UTC)))
+	// TIMESTAMP_LTZ values are returned in the session timezone
+	g.Expect(seen).To(Equal(time.Date(2024, 1, 1, 12, 0, 0, 0, loc)))
+	g.Expect(rows.Next()).To(BeTrue())
+	g.Expect(rows.Scan(&updated, &seen)).To(Succeed())
+	g.Expect(updated).To(Equal(time.Date(2024, 1, 1, 11, 0, 0, 0, time.UTC)))
+	g.Expect(seen).To(Equal(time.Date(2024, 1, 1, 12, 0, 0, 0, loc)))
+	g.Expect(rows.Next()).To(BeFalse())
+	g.Expect(rows.Err()).To(BeNil())
+	g.Expect(parsed).To(HaveLen(4))
+	g.Expect(parsed[0]).To(Equal("TIMESTAMP(3) 2024-01-01T10:00:00.5Z"))
+
+	// without a parser, the ISO 8601 timestamps fail to decode
+	db = compareDB(g, "https://api.deltastream.io/v2")
+	defer db.Close()
+	rows, err = db.Query("SELECT * FROM pageviews;")
+	g.Expect(err).To(BeNil())
+	defer rows.Close()
+	g.Expect(rows.Next()).To(BeFalse())
+	var decodeErr *ErrColumnDecode
+	g.Expect(errors.As(rows.Err(), &decodeErr)).To(BeTrue())
+	g.Expect(decodeErr.Column).To(Equal("updated"))
+}
+
+func TestNullableTimeColumns(t *testing.T) {
+	g := NewWithT(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	// scans into the scan type of every column, as generic code does
+	scanRow := func(rows *sql.Rows) []any {
+		ctypes, err := rows.ColumnTypes()
+		g.Expect(err).To(BeNil())
+		dest := make([]any, len(ctypes))
+		for i, ct := range ctypes {
+			dest[i] = reflect.New(ct.ScanType()).Interface()
+		}
+		g.Expect(rows.Next()).To(BeTrue())
+		g.Expect(rows.Scan(dest...)).To(Succeed())
+		values := make([]any, len(dest))
+		for i, d := range dest {
+			values[i] = reflect.ValueOf(d).Elem().Interface()
+		}
+		return values
+	}
+	updated := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	httpmock.RegisterResponder("POST", "https://api.deltastream.io/v2/statements", compareResponder(`{
+		"sqlState": "00000",
+		"statementID": "d789687d-4e1b-4649-846e-4f10b722f3ad",
+		"createdOn": 1703907114,
+		"metadata": {"encoding": "json", "partitionInfo": [{"rowCount": 2}], "columns": [
+			{"name": "created", "type": "TIMESTAMP(3)", "nullable": false},
+			{"name": "updated", "type": "TIMESTAMP(3)", "nullable": true}
+		], "context": {}},
+		"data": [["2024-01-01 10:00:00", null], ["2024-01-01 10:00:00", "2024-01-01 10:00:00"]]
+	}`))
+	db := compareDB(g, "https://api.deltastream.io/v2")
+	defer db.Close()
+	rows, err := db.Query("SELECT * FROM orders;")
+	g.Expect(err).To(BeNil())
+	ctypes, err := rows.ColumnTypes()
+	g.Expect(err).To(BeNil())
+	g.Expect(ctypes[0].ScanType()).To(Equal(reflect.TypeOf(time.Time{})))
+	g.Expect(ctypes[1].ScanType()).To(Equal(reflect.TypeOf(sql.NullTime{})))
+	g.Expect(scanRow(rows)).To(Equal([]any{updated, sql.NullTime{}}))
+	g.Expect(scanRow(rows)).To(Equal([]any{updated, sql.NullTime{Time: updated, Valid: true}}))
+	g.Expect(rows.Close()).To(Succeed())
+
+	// time.Time destinations still validate against nullable columns
+	rows, err = db.Query("SELECT * FROM orders;")
+	g.Expect(err).To(BeNil())
+	var created, last time.Time
+	g.Expect(ValidateScan(rows, &created, &last)).To(Succeed())
+	g.Expect(rows.Close()).To(Succeed())
+
+	server := dstest.NewStreamingServer(
+		dstest.Metadata(dstest.Column{Name: "created", Type: "TIMESTAMP(3)"}, dstest.Column{Name: "updated", Type: "TIMESTAMP(3)", Nullable: true}),
+		dstest.Data(ptr.To("2024-01-01 10:00:00"), nil),
+	)
+	defer server.Close()
+	rows, err = queryStreamingServer(g, context.Background(), server)
+	g.Expect(err).To(BeNil())
+	defer rows.Close()
+	g.Expect(scanRow(rows)).To(Equal([]any{updated, sql.NullTime{}}))
+}
+
+func TestParseTimeErrors(t *testing.T) {
+	g := NewWithT(t)
+
+	for _, tc := range []struct{ raw, colType string }{
+		{"2024-01-01", "TIMESTAMP(3)"},
+		{"2024-01-01 10:00:00+01:00", "TIMESTAMP"},
+		{"2024-01-01", "TIMESTAMP_LTZ(3)"},
+		{"10:00:00Z", "TIME"},
+		{"2024-13-01", "DATE"},
+		{"2024-01-01", "INTERVAL"},
+	} {
+		parsed, err := ParseTime(tc.raw, tc.colType)
+		g.Expect(err).To(HaveOccurred(), tc.colType)
+		g.Expect(parsed.IsZero()).To(BeTrue(), tc.colType)
+	}
 }
@@ -0,0 +1,103 @@
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package godeltastream
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// maxDebugBody is the number of bytes of response bodies kept by DebugCapture.
+const maxDebugBody = 1 << 20
+
+// requestIDHeader is the response header carrying the id the server assigned to a request.
+const requestIDHeader = "X-Request-Id"
+
+// DebugExchange is a request sent to the control plane or a dataplane, and its response.
+type DebugExchange struct {
+	Method string
+	URL    string
+	// StatusCode is zero if no response was received, see Err.
+	StatusCode int
+	// RequestID is the X-Request-Id header of the response, if any.
+	RequestID string
+	Header    http.Header
+	// Body is the raw response body, truncated to 1MiB.
+	Body      []byte
+	Truncated bool
+	Start     time.Time
+	Duration  time.Duration
+	Err       error
+}
+
+// DebugCapture records the requests of the statements executed with a context returned by WithDebugCapture, to attach
+// them to bug reports. Requests of streaming results are only recorded up to the websocket upgrade. A DebugCapture can
+// be shared by concurrent statements.
+type DebugCapture struct {
+	mu        sync.Mutex
+	exchanges []DebugExchange
+}
+
+// Exchanges returns the recorded requests, in the order they were sent.
+func (d *DebugCapture) Exchanges() []DebugExchange {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]DebugExchange(nil), d.exchanges...)
+}
+
+// Reset discards the recorded requests.
+func (d *DebugCapture) Reset() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.exchanges = nil
+}
+
+// do sends req with send and records the exchange. The response body is read into memory.
+func (d *DebugCapture) do(send func(*http.Request) (*http.Response, error), req *http.Request) (*http.Response, error) {
+	e := DebugExchange{Method: req.Method, URL: req.URL.String(), Start: time.Now()}
+	defer func() {
+		e.Duration = time.Since(e.Start)
+		d.mu.Lock()
+		d.exchanges = append(d.exchanges, e)
+		d.mu.Unlock()
+	}()
+
+	rsp, err := send(req)
+	if err != nil {
+		e.Err = err
+		return nil, err
+	}
+	e.StatusCode = rsp.StatusCode
+	e.RequestID = rsp.Header.Get(requestIDHeader)
+	e.Header = rsp.Header.Clone()
+
+	body, err := io.ReadAll(rsp.Body)
+	rsp.Body.Close()
+	if err != nil {
+		e.Err = err
+		return nil, err
+	}
+	rsp.Body = io.NopCloser(bytes.NewReader(body))
+	if len(body) > maxDebugBody {
+		body, e.Truncated = body[:maxDebugBody], true
+	}
+	e.Body = append([]byte(nil), body...)
+	return rsp, nil
+}
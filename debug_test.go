@@ -0,0 +1,77 @@
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package godeltastream
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+	. "github.com/onsi/gomega"
+)
+
+func TestDebugCapture(t *testing.T) {
+	g := NewWithT(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("POST", "https://api.deltastream.io/v2/statements", mockSubmitStatementsResponser(g, http.StatusAccepted, "sometoken", "LIST ORGANIZATIONS;", map[string][]byte{}, "fixtures/list-organizations-202-03000.json"))
+	httpmock.RegisterResponder("GET", "https://api.deltastream.io/v2/statements/d789687d-4e1b-4649-846e-4f10b722f3ad?partitionID=0&timezone=UTC", func(r *http.Request) (*http.Response, error) {
+		rsp, err := mockGetStatementResponser(g, http.StatusOK, "sometoken", "fixtures/list-organizations-200-00000-1.json")(r)
+		rsp.Header.Set("X-Request-Id", "req-1")
+		return rsp, err
+	})
+
+	connector, err := ConnectorWithOptions(context.TODO(), WithServer("https://api.deltastream.io/v2"), WithStaticToken("sometoken"), WithControlPlanePollInterval(time.Millisecond))
+	g.Expect(err).To(BeNil())
+	db := sql.OpenDB(connector)
+
+	capture := &DebugCapture{}
+	rows, err := db.QueryContext(WithDebugCapture(context.Background(), capture), "LIST ORGANIZATIONS;")
+	g.Expect(err).To(BeNil())
+	g.Expect(rows.Next()).To(BeTrue())
+	g.Expect(rows.Close()).To(Succeed())
+
+	submitted, err := os.ReadFile("fixtures/list-organizations-202-03000.json")
+	g.Expect(err).To(BeNil())
+	result, err := os.ReadFile("fixtures/list-organizations-200-00000-1.json")
+	g.Expect(err).To(BeNil())
+
+	exchanges := capture.Exchanges()
+	g.Expect(exchanges).To(HaveLen(2))
+	g.Expect(exchanges[0].Method).To(Equal("POST"))
+	g.Expect(exchanges[0].URL).To(Equal("https://api.deltastream.io/v2/statements"))
+	g.Expect(exchanges[0].StatusCode).To(Equal(http.StatusAccepted))
+	g.Expect(exchanges[0].Body).To(Equal(submitted))
+	g.Expect(exchanges[1].Method).To(Equal("GET"))
+	g.Expect(exchanges[1].StatusCode).To(Equal(http.StatusOK))
+	g.Expect(exchanges[1].RequestID).To(Equal("req-1"))
+	g.Expect(exchanges[1].Body).To(Equal(result))
+	g.Expect(exchanges[1].Start).ToNot(BeZero())
+	g.Expect(exchanges[1].Truncated).To(BeFalse())
+
+	// statements executed without the context are not recorded
+	_, err = db.Exec("LIST ORGANIZATIONS;")
+	g.Expect(err).To(BeNil())
+	g.Expect(capture.Exchanges()).To(HaveLen(2))
+	capture.Reset()
+	g.Expect(capture.Exchanges()).To(BeEmpty())
+}
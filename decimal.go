@@ -0,0 +1,164 @@
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package godeltastream
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Compile time validation that our types implement the expected interfaces
+var (
+	_ sql.Scanner   = &Decimal{}
+	_ driver.Valuer = Decimal{}
+	_ fmt.Stringer  = Decimal{}
+)
+
+// DecimalMode selects the type values of DECIMAL columns are decoded into, see WithDecimalMode.
+type DecimalMode int
+
+const (
+	// DecimalModeFloat64 decodes DECIMAL values into float64, which may round values with more than 15 significant
+	// digits. It is the default.
+	DecimalModeFloat64 DecimalMode = iota
+	// DecimalModeString decodes DECIMAL values into strings as sent by the server.
+	DecimalModeString
+	// DecimalModeDecimal decodes DECIMAL values into Decimal.
+	DecimalModeDecimal
+)
+
+func (m DecimalMode) String() string {
+	switch m {
+	case DecimalModeString:
+		return "string"
+	case DecimalModeDecimal:
+		return "decimal"
+	default:
+		return "float64"
+	}
+}
+
+// Decimal is an exact decimal number: an unscaled integer divided by 10 to the power of the scale. The zero value is 0.
+type Decimal struct {
+	unscaled *big.Int
+	scale    int32
+}
+
+// NewDecimal returns the decimal unscaled * 10^-scale.
+func NewDecimal(unscaled *big.Int, scale int32) Decimal {
+	d := Decimal{unscaled: new(big.Int).Set(unscaled), scale: scale}
+	if scale < 0 {
+		d.unscaled.Mul(d.unscaled, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(-scale)), nil))
+		d.scale = 0
+	}
+	return d
+}
+
+// ParseDecimal parses a decimal number in the form -123.45, optionally followed by an exponent, e.g. 1.2345E+2.
+func ParseDecimal(s string) (Decimal, error) {
+	mantissa, exp := s, int64(0)
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		var err error
+		if exp, err = strconv.ParseInt(s[i+1:], 10, 16); err != nil {
+			return Decimal{}, fmt.Errorf("invalid decimal %q", s)
+		}
+		mantissa = s[:i]
+	}
+	integer, fraction, _ := strings.Cut(mantissa, ".")
+	unscaled, ok := new(big.Int).SetString(integer+fraction, 10)
+	if !ok || strings.ContainsAny(fraction, "+-") {
+		return Decimal{}, fmt.Errorf("invalid decimal %q", s)
+	}
+	return NewDecimal(unscaled, int32(int64(len(fraction))-exp)), nil
+}
+
+// Unscaled returns the unscaled integer of the decimal.
+func (d Decimal) Unscaled() *big.Int {
+	if d.unscaled == nil {
+		return new(big.Int)
+	}
+	return new(big.Int).Set(d.unscaled)
+}
+
+// Scale returns the number of digits after the decimal point.
+func (d Decimal) Scale() int32 {
+	return d.scale
+}
+
+// Rat returns the decimal as a rational number.
+func (d Decimal) Rat() *big.Rat {
+	return new(big.Rat).SetFrac(d.Unscaled(), new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(d.scale)), nil))
+}
+
+// Float64 returns the float64 nearest to the decimal.
+func (d Decimal) Float64() float64 {
+	f, _ := d.Rat().Float64()
+	return f
+}
+
+// Cmp compares the decimal to o, returning -1, 0 or +1. Decimals of different scales may be equal, e.g. 1.5 and 1.50.
+func (d Decimal) Cmp(o Decimal) int {
+	return d.Rat().Cmp(o.Rat())
+}
+
+// String returns the decimal with its scale digits after the decimal point, e.g. 1.50.
+func (d Decimal) String() string {
+	digits := new(big.Int).Abs(d.Unscaled()).String()
+	if scale := int(d.scale); scale > 0 {
+		if pad := scale + 1 - len(digits); pad > 0 {
+			digits = strings.Repeat("0", pad) + digits
+		}
+		digits = digits[:len(digits)-scale] + "." + digits[len(digits)-scale:]
+	}
+	if d.unscaled != nil && d.unscaled.Sign() < 0 {
+		return "-" + digits
+	}
+	return digits
+}
+
+// Value implements driver.Valuer.
+func (d Decimal) Value() (driver.Value, error) {
+	return d.String(), nil
+}
+
+// Scan implements sql.Scanner.
+func (d *Decimal) Scan(src any) error {
+	var err error
+	switch v := src.(type) {
+	case Decimal:
+		*d = v
+	case string:
+		*d, err = ParseDecimal(v)
+	case []byte:
+		*d, err = ParseDecimal(string(v))
+	case int64:
+		*d = NewDecimal(big.NewInt(v), 0)
+	case float64:
+		*d, err = ParseDecimal(strconv.FormatFloat(v, 'g', -1, 64))
+	default:
+		return fmt.Errorf("unsupported Scan, storing driver.Value type %T into type *Decimal", src)
+	}
+	return err
+}
+
+func decodeDecimal(s string) (driver.Value, error) {
+	return ParseDecimal(s)
+}
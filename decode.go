@@ -0,0 +1,247 @@
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package godeltastream
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// decodeOptions control how values sent by the server are converted into driver values.
+type decodeOptions struct {
+	legacyTimeColumns bool
+	// rawJSONColumns decodes the json values of ARRAY, MAP and STRUCT columns into []byte, see WithRawJSONColumns
+	rawJSONColumns bool
+	// decimalMode selects the type DECIMAL values are decoded into, see WithDecimalMode
+	decimalMode DecimalMode
+	// location is the session timezone TIMESTAMP_LTZ values are returned in, nil to keep the offset sent by the server
+	location *time.Location
+	// timeParser replaces parseTime, see WithTimeParser
+	timeParser TimeParser
+}
+
+// TimeParser parses the value raw of a TIME, DATE or TIMESTAMP column of type colType, see WithTimeParser.
+type TimeParser func(raw, colType string) (time.Time, error)
+
+// ParseTime parses the value raw of a TIME, DATE or TIMESTAMP column of type colType as the driver does by default,
+// keeping the offset of TIMESTAMP_LTZ values, e.g. for parsers set with WithTimeParser to delegate to.
+func ParseTime(raw, colType string) (time.Time, error) {
+	return parseTime(raw, colType, nil)
+}
+
+// parseTime returns the function parsing the values of the time column colType.
+func (o decodeOptions) parseTime(colType string) func(s string) (time.Time, error) {
+	if o.timeParser == nil {
+		return func(s string) (time.Time, error) {
+			return parseTime(s, colType, o.location)
+		}
+	}
+	ltz := isLocalTimeZoneColumn(colType)
+	return func(s string) (time.Time, error) {
+		t, err := o.timeParser(s, colType)
+		if err == nil && ltz && o.location != nil {
+			t = t.In(o.location)
+		}
+		return t, err
+	}
+}
+
+// columnDecoder converts the string representation of a non null value sent by the server into a driver value.
+type columnDecoder func(s string) (driver.Value, error)
+
+// newColumnDecoders returns a decoder for every column. Decoders are resolved once per result set so that decoding a
+// row does not need to inspect column types. Conversion errors are returned as *ErrColumnDecode.
+func newColumnDecoders(colNames, colTypes []string, opts decodeOptions) []columnDecoder {
+	decoders := make([]columnDecoder, len(colTypes))
+	for i, t := range colTypes {
+		decoders[i] = withColumnContext(colNames[i], t, newColumnDecoder(t, opts))
+	}
+	return decoders
+}
+
+// withColumnContext wraps the errors of decode with the column and the raw value that failed to decode.
+func withColumnContext(colName, colType string, decode columnDecoder) columnDecoder {
+	return func(s string) (driver.Value, error) {
+		v, err := decode(s)
+		if err != nil {
+			return nil, &ErrColumnDecode{Column: colName, Type: colType, RawValue: s, Err: err}
+		}
+		return v, nil
+	}
+}
+
+func newColumnDecoder(colType string, opts decodeOptions) columnDecoder {
+	switch {
+	case opts.rawJSONColumns && isJSONColumn(colType):
+		return decodeRawJSON
+	case // as parsed by the server
+		strings.HasPrefix(colType, "VARCHAR"),
+		colType == "DATE",
+		strings.HasPrefix(colType, "ARRAY"),
+		strings.HasPrefix(colType, "MAP"),
+		strings.HasPrefix(colType, "STRUCT"):
+		return decodeString
+	case
+		colType == "TINYINT",
+		colType == "SMALLINT",
+		colType == "INTEGER":
+		return decodeInteger
+	case colType == "BIGINT":
+		return decodeBigint
+	case strings.HasPrefix(colType, "DECIMAL") && opts.decimalMode == DecimalModeString:
+		return decodeString
+	case strings.HasPrefix(colType, "DECIMAL") && opts.decimalMode == DecimalModeDecimal:
+		return decodeDecimal
+	case
+		colType == "FLOAT",
+		colType == "DOUBLE",
+		strings.HasPrefix(colType, "DECIMAL"):
+		return decodeFloat
+	case !opts.legacyTimeColumns && isTimeOfDayColumn(colType):
+		return decodeTimeOfDay
+	case strings.HasPrefix(colType, "TIME"):
+		parse := opts.parseTime(colType)
+		return func(s string) (driver.Value, error) {
+			return parse(s)
+		}
+	case
+		colType == "VARBINARY",
+		colType == "BYTES":
+		return decodeBytes
+	case colType == "BOOLEAN":
+		return decodeBoolean
+	default:
+		return decodeString
+	}
+}
+
+func decodeString(s string) (driver.Value, error) {
+	return s, nil
+}
+
+func decodeRawJSON(s string) (driver.Value, error) {
+	return []byte(s), nil
+}
+
+func decodeInteger(s string) (driver.Value, error) {
+	return strconv.ParseInt(s, 10, 64)
+}
+
+// decodeBigint decodes BIGINT values into int64, matching the scan type of BIGINT columns. Values that do not fit in an
+// int64 are decoded into *big.Int, which BIGINT values always were before.
+func decodeBigint(s string) (driver.Value, error) {
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return i, nil
+	}
+	// the server may send large values in exponent notation
+	flt, _, err := big.ParseFloat(s, 10, 0, big.ToNearestEven)
+	if err != nil {
+		return nil, err
+	}
+	i, _ := flt.Int(new(big.Int))
+	return i, nil
+}
+
+func decodeFloat(s string) (driver.Value, error) {
+	return strconv.ParseFloat(s, 64)
+}
+
+func decodeTimeOfDay(s string) (driver.Value, error) {
+	return ParseTimeOfDay(s)
+}
+
+func decodeBytes(s string) (driver.Value, error) {
+	return base64.StdEncoding.DecodeString(s)
+}
+
+func decodeBoolean(s string) (driver.Value, error) {
+	return strings.EqualFold(s, "true"), nil
+}
+
+// decodeRow decodes the values of a row into dest.
+func decodeRow(decoders []columnDecoder, row []*string, dest []driver.Value) error {
+	if len(row) != len(dest) {
+		return &ErrClientError{message: fmt.Sprintf("number of columns does not match size of result slice. expected %d, got %d", len(row), len(dest))}
+	}
+	if len(row) != len(decoders) {
+		return &ErrInterfaceError{message: fmt.Sprintf("number of values does not match number of columns. expected %d, got %d", len(decoders), len(row))}
+	}
+
+	var err error
+	for idx, v := range row {
+		if v == nil {
+			dest[idx] = nil
+			continue
+		}
+		if dest[idx], err = decoders[idx](*v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var (
+	rawJSONType  = reflect.TypeOf(json.RawMessage{})
+	decimalType  = reflect.TypeOf(Decimal{})
+	nullTimeType = reflect.TypeOf(sql.NullTime{})
+)
+
+// isJSONColumn returns whether values of the column type are sent as json.
+func isJSONColumn(colType string) bool {
+	return strings.HasPrefix(colType, "ARRAY") || strings.HasPrefix(colType, "MAP") || strings.HasPrefix(colType, "STRUCT")
+}
+
+// scanType returns the go type values of the column type are decoded into. Nullable columns of time.Time values return
+// sql.NullTime, as time.Time cannot hold NULL.
+func scanType(colType string, nullable bool, opts decodeOptions) reflect.Type {
+	switch {
+	case opts.rawJSONColumns && isJSONColumn(colType):
+		return rawJSONType
+	case strings.HasPrefix(colType, "VARCHAR"):
+		return typeMap["VARCHAR"]
+	case strings.HasPrefix(colType, "DECIMAL") && opts.decimalMode == DecimalModeString:
+		return typeMap["VARCHAR"]
+	case strings.HasPrefix(colType, "DECIMAL") && opts.decimalMode == DecimalModeDecimal:
+		return decimalType
+	case strings.HasPrefix(colType, "DECIMAL"):
+		return typeMap["DECIMAL"]
+	case strings.HasPrefix(colType, "TIMESTAMP"),
+		isTimeOfDayColumn(colType) && opts.legacyTimeColumns:
+		if nullable {
+			return nullTimeType
+		}
+		return typeMap["TIMESTAMP"]
+	case strings.HasPrefix(colType, "TIME"):
+		return typeMap["TIME"]
+	case strings.HasPrefix(colType, "ARRAY"):
+		return typeMap["ARRAY"]
+	case strings.HasPrefix(colType, "STRUCT"):
+		return typeMap["STRUCT"]
+	case strings.HasPrefix(colType, "MAP"):
+		return typeMap["MAP"]
+	default:
+		return typeMap[colType]
+	}
+}
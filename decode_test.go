@@ -0,0 +1,167 @@
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package godeltastream
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"io"
+	"math/big"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/onsi/gomega"
+
+	"github.com/deltastreaminc/go-deltastream/apiv2"
+)
+
+// loadBenchmarkResultSet returns the datatypes fixture with its rows repeated until the result set has rowCount rows.
+func loadBenchmarkResultSet(b *testing.B, rowCount int) *apiv2.ResultSet {
+	f, err := os.ReadFile("fixtures/test-datatypes-200-00000-4.json")
+	if err != nil {
+		b.Fatal(err)
+	}
+	rs := &apiv2.ResultSet{}
+	if err = json.Unmarshal(f, rs); err != nil {
+		b.Fatal(err)
+	}
+
+	data := make([][]*string, 0, rowCount)
+	for len(data) < rowCount {
+		data = append(data, (*rs.Data)[len(data)%len(*rs.Data)])
+	}
+	rs.Data = &data
+	rs.Metadata.PartitionInfo = []apiv2.ResultSetPartitionInfo{{RowCount: int32(rowCount)}}
+	return rs
+}
+
+func TestDecodeBigint(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	g.Expect(scanType("BIGINT", false, decodeOptions{})).To(gomega.Equal(reflect.TypeOf(int64(0))))
+
+	decoders := newColumnDecoders([]string{"id"}, []string{"BIGINT"}, decodeOptions{})
+	for _, tc := range []struct {
+		value    string
+		expected driver.Value
+	}{
+		{"42", int64(42)},
+		{"-9223372036854775808", int64(-9223372036854775808)},
+		{"9223372036854775807", int64(9223372036854775807)},
+		{"9223372036854775808", new(big.Int).Lsh(big.NewInt(1), 63)},
+		{"1E+20", new(big.Int).Exp(big.NewInt(10), big.NewInt(20), nil)},
+	} {
+		v, err := decoders[0](tc.value)
+		g.Expect(err).To(gomega.BeNil())
+		g.Expect(v).To(gomega.Equal(tc.expected), tc.value)
+	}
+}
+
+type benchmarkResultSetConn struct{}
+
+func (benchmarkResultSetConn) getStatement(ctx context.Context, statementID uuid.UUID, partitionID int32) (*apiv2.ResultSet, error) {
+	return nil, io.EOF
+}
+
+func BenchmarkResultSetRowsNext(b *testing.B) {
+	rs := loadBenchmarkResultSet(b, 100000)
+	dest := make([]driver.Value, len(rs.Metadata.Columns))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		rows := &resultSetRows{ctx: context.Background(), conn: benchmarkResultSetConn{}, currentRowIdx: -1, currentResultSet: rs}
+		for {
+			if err := rows.Next(dest); err == io.EOF {
+				break
+			} else if err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkDecodeRow(b *testing.B) {
+	rs := loadBenchmarkResultSet(b, 4)
+	colNames := make([]string, len(rs.Metadata.Columns))
+	colTypes := make([]string, len(rs.Metadata.Columns))
+	for i, col := range rs.Metadata.Columns {
+		colNames[i] = col.Name
+		colTypes[i] = col.Type
+	}
+	decoders := newColumnDecoders(colNames, colTypes, decodeOptions{})
+	dest := make([]driver.Value, len(colTypes))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := decodeRow(decoders, (*rs.Data)[i%4], dest); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkResultSetRowsNextBlock(b *testing.B) {
+	rs := loadBenchmarkResultSet(b, 100000)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		rows := &resultSetRows{ctx: context.Background(), conn: benchmarkResultSetConn{}, currentRowIdx: -1, currentResultSet: rs}
+		for {
+			if _, err := rows.NextBlock(); err == io.EOF {
+				break
+			} else if err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+// BenchmarkPartitionDecode decodes partitions of 10000 rows of the datatypes fixture with the JSON codec and into
+// pooled row buffers, see WithRowBufferPooling.
+func BenchmarkPartitionDecode(b *testing.B) {
+	body, err := json.Marshal(loadBenchmarkResultSet(b, 10000))
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.Run("codec", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			rs := &apiv2.ResultSet{}
+			if err := (stdlibJSONCodec{}).Unmarshal(body, rs); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("pooled", func(b *testing.B) {
+		buf := rowBufferPool.Get().(*rowBuffer)
+		defer rowBufferPool.Put(buf)
+		codec := rowBufferCodec{JSONCodec: stdlibJSONCodec{}, buf: buf}
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			rs := &apiv2.ResultSet{}
+			if err := codec.Unmarshal(body, rs); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -19,46 +19,66 @@ package godeltastream
 import (
 	"context"
 	"database/sql"
+	"fmt"
 	"net/http"
 	"net/url"
-	"time"
 
 	"github.com/deltastreaminc/go-deltastream/apiv2"
 	"github.com/deltastreaminc/go-deltastream/dpapiv2"
 	"github.com/google/uuid"
-	"github.com/pkg/errors"
 	"k8s.io/utils/ptr"
 )
 
 type DPConn struct {
 	apiv2.DataplaneRequest
-	client    *dpapiv2.ClientWithResponses
-	sessionID *string
+	client             *dpapiv2.ClientWithResponses
+	sessionID          *string
+	maintenanceMode    bool
+	authHeaderProvider AuthHeaderProvider
+	jsonCodec          JSONCodec
+	pollInterval       pollInterval
+	timezone           string
+	strictSQLStates    bool
 }
 
 func NewDPConn(dpreq apiv2.DataplaneRequest, sessionID *string, httpClient *http.Client) (*DPConn, error) {
+	return newDPConnAt(dpreq, sessionID, httpClient, "/"+defaultAPIVersion, 0)
+}
+
+// newDPConnAt returns a connection to the dataplane api served under basePath, see WithDataplaneBasePath, whose
+// responses are limited to maxResponseBytes, see WithMaxResponseBytes.
+func newDPConnAt(dpreq apiv2.DataplaneRequest, sessionID *string, httpClient *http.Client, basePath string, maxResponseBytes int64) (*DPConn, error) {
 	uri, err := url.Parse(dpreq.Uri)
 	if err != nil {
 		return nil, &ErrInterfaceError{message: "invalid dataplane uri"}
 	}
-	uri.Path = "/v2"
+	if basePath == "" {
+		basePath = "/" + defaultAPIVersion
+	}
+	uri.Path = basePath
 
-	client, err := dpapiv2.NewClientWithResponses(
+	dpconn := &DPConn{
+		DataplaneRequest: dpreq,
+		sessionID:        sessionID,
+		jsonCodec:        stdlibJSONCodec{},
+		pollInterval:     pollInterval(defaultDataplanePollInterval),
+		timezone:         "UTC",
+	}
+	dpconn.client, err = dpapiv2.NewClientWithResponses(
 		uri.String(),
 		dpapiv2.WithRequestEditorFn(func(ctx context.Context, req *http.Request) error {
 			req.Header.Add("Authorization", "Bearer "+dpreq.Token)
-			return nil
+			if maintenanceMode(ctx, dpconn.maintenanceMode) {
+				req.Header.Set(maintenanceModeHeader, "true")
+			}
+			return dpconn.authHeaderProvider.apply(ctx, req.Header)
 		}),
-		dpapiv2.WithHTTPClient(httpClient),
+		dpapiv2.WithHTTPClient(contextHTTPClient{client: httpClient, maxResponseBytes: maxResponseBytes}),
 	)
 	if err != nil {
 		return nil, err
 	}
-	return &DPConn{
-		client:           client,
-		DataplaneRequest: dpreq,
-		sessionID:        sessionID,
-	}, nil
+	return dpconn, nil
 }
 
 func (c *DPConn) getStatement(ctx context.Context, statementID uuid.UUID, partitionID int32) (rs *apiv2.ResultSet, err error) {
@@ -66,47 +86,49 @@ func (c *DPConn) getStatement(ctx context.Context, statementID uuid.UUID, partit
 		return nil, sql.ErrConnDone
 	}
 
-	t := time.NewTicker(time.Second)
-	defer t.Stop()
-
 	for {
-		resp, err := c.client.GetStatementStatusWithResponse(ctx, statementID, &dpapiv2.GetStatementStatusParams{PartitionID: &partitionID, SessionID: c.sessionID, Timezone: ptr.To("UTC")})
+		version := resultVersionFrom(ctx)
+		rsp, err := c.client.GetStatementStatus(ctx, statementID, &dpapiv2.GetStatementStatusParams{PartitionID: &partitionID, SessionID: c.sessionID, Timezone: ptr.To(c.timezone)}, version.ifMatch(partitionID))
+		if err != nil {
+			return nil, &ErrInterfaceError{wrapErr: err, message: "unable to send request to server"}
+		}
+		version.observe(partitionID, rsp)
+		resp, err := parseDPGetStatementStatusResponse(responseCodec(ctx, c.jsonCodec), rsp)
 		if err != nil {
 			return nil, &ErrInterfaceError{wrapErr: err, message: "unable to send request to server"}
 		}
 		switch {
 		case resp.JSON200 != nil:
 			if resp.JSON200.SqlState == string(SqlStateSuccessfulCompletion) {
+				recordBytesReceived(ctx, len(resp.Body))
 				return resp.JSON200, nil
 			}
 			return nil, ErrSQLError{
 				SQLCode:     SqlState(resp.JSON200.SqlState),
 				Message:     ptr.Deref(resp.JSON200.Message, ""),
 				StatementID: resp.JSON200.StatementID,
+				strict:      c.strictSQLStates,
 			}
 		case resp.JSON202 != nil:
-			continue
+			// drop out of switch to sleep and retry
 		case resp.JSON400 != nil:
 			return nil, &ErrInterfaceError{message: resp.JSON400.Message}
 		case resp.JSON403 != nil:
-			return nil, errors.Errorf(resp.JSON403.Message+": %w", ErrAuthenticationError)
+			return nil, fmt.Errorf("%s: %w", resp.JSON403.Message, ErrAuthenticationError)
 		case resp.JSON404 != nil:
 			return nil, &ErrInterfaceError{message: resp.JSON404.Message}
 		case resp.JSON408 != nil:
-			return nil, errors.Errorf(resp.JSON408.Message+": %w", ErrDeadlineExceeded)
+			return nil, fmt.Errorf("%s: %w", resp.JSON408.Message, ErrDeadlineExceeded)
 		case resp.JSON500 != nil:
-			return nil, &ErrServerError{message: resp.JSON500.Message}
+			return nil, &ErrServerError{message: resp.JSON500.Message, StatusCode: http.StatusInternalServerError}
 		case resp.JSON503 != nil:
-			return nil, errors.Errorf(resp.JSON500.Message+": %w", ErrServiceUnavailable)
+			return nil, fmt.Errorf("%s: %w", resp.JSON503.Message, ErrServiceUnavailable)
 		default:
-			return nil, &ErrServerError{message: "unexpected response"}
+			return nil, newUnexpectedResponse(resp.HTTPResponse, resp.Body)
 		}
 
-		select {
-		case <-ctx.Done():
-			return nil, ctx.Err()
-		case <-t.C:
-			continue
+		if err := c.pollInterval.wait(ctx); err != nil {
+			return nil, err
 		}
 	}
 }
@@ -0,0 +1,169 @@
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package dsmock provides mocks of the interfaces of the driver, e.g. StatementSubmitter, to unit test code using them
+// without a DeltaStream server or the fakes of the dstest package. Calls are answered by the function fields of the
+// mocks, which panic when unset, and recorded for assertions.
+package dsmock
+
+import (
+	"context"
+	"database/sql/driver"
+	"io"
+	"sync"
+
+	"github.com/google/uuid"
+
+	godeltastream "github.com/deltastreaminc/go-deltastream"
+	"github.com/deltastreaminc/go-deltastream/apiv2"
+)
+
+// Compile time validation that our types implement the expected interfaces
+var (
+	_ godeltastream.StatementSubmitter = &StatementSubmitter{}
+	_ godeltastream.ResultFetcher      = &ResultFetcher{}
+	_ godeltastream.Streamer           = &Streamer{}
+	_ driver.Rows                      = &Rows{}
+)
+
+// StatementSubmitter is a mock of godeltastream.StatementSubmitter.
+type StatementSubmitter struct {
+	SubmitRequestFunc func(ctx context.Context, req *godeltastream.StatementRequest) (driver.Rows, error)
+
+	mu    sync.Mutex
+	calls []*godeltastream.StatementRequest
+}
+
+// SubmitRequest records req and calls SubmitRequestFunc.
+func (m *StatementSubmitter) SubmitRequest(ctx context.Context, req *godeltastream.StatementRequest) (driver.Rows, error) {
+	if m.SubmitRequestFunc == nil {
+		panic("dsmock: StatementSubmitter.SubmitRequestFunc is nil but SubmitRequest was called")
+	}
+	m.mu.Lock()
+	m.calls = append(m.calls, req)
+	m.mu.Unlock()
+	return m.SubmitRequestFunc(ctx, req)
+}
+
+// SubmitRequestCalls returns the requests submitted so far, in order.
+func (m *StatementSubmitter) SubmitRequestCalls() []*godeltastream.StatementRequest {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]*godeltastream.StatementRequest(nil), m.calls...)
+}
+
+// GetStatementCall are the arguments of a call to ResultFetcher.GetStatement.
+type GetStatementCall struct {
+	StatementID uuid.UUID
+	PartitionID int32
+}
+
+// ResultFetcher is a mock of godeltastream.ResultFetcher.
+type ResultFetcher struct {
+	GetStatementFunc func(ctx context.Context, statementID uuid.UUID, partitionID int32) (*apiv2.ResultSet, error)
+
+	mu    sync.Mutex
+	calls []GetStatementCall
+}
+
+// GetStatement records the call and calls GetStatementFunc.
+func (m *ResultFetcher) GetStatement(ctx context.Context, statementID uuid.UUID, partitionID int32) (*apiv2.ResultSet, error) {
+	if m.GetStatementFunc == nil {
+		panic("dsmock: ResultFetcher.GetStatementFunc is nil but GetStatement was called")
+	}
+	m.mu.Lock()
+	m.calls = append(m.calls, GetStatementCall{StatementID: statementID, PartitionID: partitionID})
+	m.mu.Unlock()
+	return m.GetStatementFunc(ctx, statementID, partitionID)
+}
+
+// GetStatementCalls returns the calls to GetStatement so far, in order.
+func (m *ResultFetcher) GetStatementCalls() []GetStatementCall {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]GetStatementCall(nil), m.calls...)
+}
+
+// OpenStreamCall are the arguments of a call to Streamer.OpenStream.
+type OpenStreamCall struct {
+	StatementID uuid.UUID
+	Request     apiv2.DataplaneRequest
+}
+
+// Streamer is a mock of godeltastream.Streamer.
+type Streamer struct {
+	OpenStreamFunc func(ctx context.Context, statementID uuid.UUID, req apiv2.DataplaneRequest) (driver.Rows, error)
+
+	mu    sync.Mutex
+	calls []OpenStreamCall
+}
+
+// OpenStream records the call and calls OpenStreamFunc.
+func (m *Streamer) OpenStream(ctx context.Context, statementID uuid.UUID, req apiv2.DataplaneRequest) (driver.Rows, error) {
+	if m.OpenStreamFunc == nil {
+		panic("dsmock: Streamer.OpenStreamFunc is nil but OpenStream was called")
+	}
+	m.mu.Lock()
+	m.calls = append(m.calls, OpenStreamCall{StatementID: statementID, Request: req})
+	m.mu.Unlock()
+	return m.OpenStreamFunc(ctx, statementID, req)
+}
+
+// OpenStreamCalls returns the calls to OpenStream so far, in order.
+func (m *Streamer) OpenStreamCalls() []OpenStreamCall {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]OpenStreamCall(nil), m.calls...)
+}
+
+// Rows is a driver.Rows returning Values, then Err, or io.EOF if Err is nil. It is meant to be returned by the
+// functions of the mocks.
+type Rows struct {
+	ColumnNames []string
+	Values      [][]driver.Value
+	Err         error
+	Closed      bool
+	next        int
+}
+
+// NewRows returns the rows of columns holding values.
+func NewRows(columns []string, values ...[]driver.Value) *Rows {
+	return &Rows{ColumnNames: columns, Values: values}
+}
+
+// Columns implements driver.Rows.
+func (r *Rows) Columns() []string {
+	return r.ColumnNames
+}
+
+// Close implements driver.Rows.
+func (r *Rows) Close() error {
+	r.Closed = true
+	return nil
+}
+
+// Next implements driver.Rows.
+func (r *Rows) Next(dest []driver.Value) error {
+	if r.next >= len(r.Values) {
+		if r.Err != nil {
+			return r.Err
+		}
+		return io.EOF
+	}
+	copy(dest, r.Values[r.next])
+	r.next++
+	return nil
+}
@@ -0,0 +1,76 @@
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dsmock_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	godeltastream "github.com/deltastreaminc/go-deltastream"
+	"github.com/deltastreaminc/go-deltastream/apiv2"
+	"github.com/deltastreaminc/go-deltastream/dsmock"
+)
+
+// countRows is application code under test.
+func countRows(ctx context.Context, s godeltastream.StatementSubmitter, relation string) (int, error) {
+	rows, err := s.SubmitRequest(ctx, godeltastream.NewStatementRequest("SELECT * FROM "+relation+";"))
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	dest := make([]driver.Value, len(rows.Columns()))
+	n := 0
+	for {
+		if err := rows.Next(dest); err == io.EOF {
+			return n, nil
+		} else if err != nil {
+			return n, err
+		}
+		n++
+	}
+}
+
+func TestStatementSubmitter(t *testing.T) {
+	g := NewWithT(t)
+
+	rows := dsmock.NewRows([]string{"id", "name"}, []driver.Value{int64(1), "a"}, []driver.Value{int64(2), "b"})
+	submitter := &dsmock.StatementSubmitter{
+		SubmitRequestFunc: func(ctx context.Context, req *godeltastream.StatementRequest) (driver.Rows, error) {
+			return rows, nil
+		},
+	}
+	g.Expect(countRows(context.TODO(), submitter, "pageviews")).To(Equal(2))
+	g.Expect(rows.Closed).To(BeTrue())
+	calls := submitter.SubmitRequestCalls()
+	g.Expect(calls).To(HaveLen(1))
+	g.Expect(calls[0].Statement()).To(Equal("SELECT * FROM pageviews;"))
+
+	errStream := errors.New("stream failed")
+	submitter.SubmitRequestFunc = func(ctx context.Context, req *godeltastream.StatementRequest) (driver.Rows, error) {
+		return &dsmock.Rows{ColumnNames: []string{"id"}, Values: [][]driver.Value{{int64(1)}}, Err: errStream}, nil
+	}
+	n, err := countRows(context.TODO(), submitter, "pageviews")
+	g.Expect(n).To(Equal(1))
+	g.Expect(err).To(MatchError(errStream))
+
+	g.Expect(func() { _, _ = (&dsmock.Streamer{}).OpenStream(context.TODO(), [16]byte{}, apiv2.DataplaneRequest{}) }).To(Panic())
+}
@@ -0,0 +1,329 @@
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dstest
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/deltastreaminc/go-deltastream/apiv2"
+)
+
+// Generator generates random values of DeltaStream column types, formatted as the server sends them, e.g. to build
+// result set fixtures or streaming sessions richer than static files. Generators with the same seed generate the same
+// values. A Generator is not safe for concurrent use.
+type Generator struct {
+	// NullProbability is the probability of values of nullable columns to be null.
+	NullProbability float64
+
+	rand *rand.Rand
+}
+
+// NewGenerator returns a Generator seeded with seed. One value in ten of nullable columns is null.
+func NewGenerator(seed int64) *Generator {
+	return &Generator{NullProbability: 0.1, rand: rand.New(rand.NewSource(seed))}
+}
+
+// ColumnsOf returns the columns of result set metadata, e.g. to generate rows for the metadata of a recorded result.
+func ColumnsOf(columns apiv2.ResultSetColumns) []Column {
+	ret := make([]Column, len(columns))
+	for i, c := range columns {
+		ret[i] = Column{Name: c.Name, Type: c.Type, Nullable: c.Nullable}
+	}
+	return ret
+}
+
+// Value returns a random value of colType, e.g. BIGINT, DECIMAL(4, 3), TIMESTAMP(3) or MAP<VARCHAR, ARRAY<INTEGER>>.
+func (g *Generator) Value(colType string) (string, error) {
+	colType = strings.TrimSpace(colType)
+	base, params := splitType(colType)
+	switch base {
+	case "VARCHAR":
+		n := 16
+		if len(params) == 1 {
+			if l, err := strconv.Atoi(params[0]); err == nil && l < n {
+				n = l
+			}
+		}
+		return g.text(g.rand.Intn(n + 1)), nil
+	case "TINYINT":
+		return strconv.Itoa(g.rand.Intn(256) - 128), nil
+	case "SMALLINT":
+		return strconv.Itoa(g.rand.Intn(1<<16) - 1<<15), nil
+	case "INTEGER":
+		return strconv.FormatInt(int64(int32(g.rand.Uint32())), 10), nil
+	case "BIGINT":
+		return strconv.FormatInt(int64(g.rand.Uint64()), 10), nil
+	case "FLOAT":
+		return strconv.FormatFloat(float64(float32(g.rand.NormFloat64()*1e6)), 'g', -1, 32), nil
+	case "DOUBLE":
+		return strconv.FormatFloat(g.rand.NormFloat64()*1e12, 'g', -1, 64), nil
+	case "DECIMAL":
+		precision, scale := 10, 0
+		if len(params) > 0 {
+			p, err := strconv.Atoi(params[0])
+			if err != nil {
+				return "", fmt.Errorf("invalid precision of %s", colType)
+			}
+			precision = p
+		}
+		if len(params) > 1 {
+			s, err := strconv.Atoi(params[1])
+			if err != nil {
+				return "", fmt.Errorf("invalid scale of %s", colType)
+			}
+			scale = s
+		}
+		return g.decimal(precision, scale), nil
+	case "BOOLEAN":
+		return strconv.FormatBool(g.rand.Intn(2) == 1), nil
+	case "DATE":
+		return g.time().Format("2006-01-02"), nil
+	case "TIME":
+		return g.time().Format("15:04:05" + fraction(params, 0)), nil
+	case "TIMESTAMP":
+		return g.time().Format("2006-01-02 15:04:05" + fraction(params, 6)), nil
+	case "TIMESTAMP_LTZ":
+		return g.time().Format("2006-01-02 15:04:05" + fraction(params, 6) + "Z"), nil
+	case "VARBINARY", "BYTES":
+		b := make([]byte, g.rand.Intn(16))
+		_, _ = g.rand.Read(b)
+		return base64.StdEncoding.EncodeToString(b), nil
+	case "ARRAY", "MAP", "STRUCT":
+		v, err := g.jsonValue(colType)
+		if err != nil {
+			return "", err
+		}
+		b, err := json.Marshal(v)
+		return string(b), err
+	}
+	return "", fmt.Errorf("unsupported column type %s", colType)
+}
+
+// Row returns a random row of columns. Values of nullable columns are nil with probability NullProbability.
+func (g *Generator) Row(columns ...Column) ([]*string, error) {
+	row := make([]*string, len(columns))
+	for i, c := range columns {
+		if c.Nullable && g.rand.Float64() < g.NullProbability {
+			continue
+		}
+		v, err := g.Value(c.Type)
+		if err != nil {
+			return nil, fmt.Errorf("column %s: %w", c.Name, err)
+		}
+		row[i] = &v
+	}
+	return row, nil
+}
+
+// Rows returns n random rows of columns.
+func (g *Generator) Rows(n int, columns ...Column) ([][]*string, error) {
+	rows := make([][]*string, n)
+	for i := range rows {
+		row, err := g.Row(columns...)
+		if err != nil {
+			return nil, err
+		}
+		rows[i] = row
+	}
+	return rows, nil
+}
+
+// ResultSet returns a successful result set of n random rows of columns in a single partition. Encode it with
+// encoding/json to obtain a fixture of the control plane or dataplane.
+func (g *Generator) ResultSet(n int, columns ...Column) (*apiv2.ResultSet, error) {
+	rows, err := g.Rows(n, columns...)
+	if err != nil {
+		return nil, err
+	}
+	rs := &apiv2.ResultSet{
+		SqlState:    "00000",
+		StatementID: uuid.New(),
+		CreatedOn:   time.Now().Unix(),
+		Data:        &rows,
+		Metadata: apiv2.ResultSetMetadata{
+			Encoding:      apiv2.Json,
+			PartitionInfo: []apiv2.ResultSetPartitionInfo{{RowCount: int32(n)}},
+		},
+	}
+	for _, c := range columns {
+		rs.Metadata.Columns = append(rs.Metadata.Columns, struct {
+			DisplayHint *string `json:"display_hint,omitempty"`
+			Name        string  `json:"name"`
+			Nullable    bool    `json:"nullable"`
+			Type        string  `json:"type"`
+		}{Name: c.Name, Nullable: c.Nullable, Type: c.Type})
+	}
+	return rs, nil
+}
+
+// Stream returns the steps of a streaming session sending the metadata of columns followed by n random rows.
+func (g *Generator) Stream(n int, columns ...Column) ([]Step, error) {
+	rows, err := g.Rows(n, columns...)
+	if err != nil {
+		return nil, err
+	}
+	steps := []Step{Metadata(columns...)}
+	for _, row := range rows {
+		steps = append(steps, Data(row...))
+	}
+	return steps, nil
+}
+
+const alphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789 "
+
+func (g *Generator) text(n int) string {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = alphabet[g.rand.Intn(len(alphabet))]
+	}
+	return string(b)
+}
+
+func (g *Generator) decimal(precision, scale int) string {
+	digits := make([]byte, precision)
+	for i := range digits {
+		digits[i] = byte('0' + g.rand.Intn(10))
+	}
+	integer := strings.TrimLeft(string(digits[:precision-scale]), "0")
+	if integer == "" {
+		integer = "0"
+	}
+	if g.rand.Intn(2) == 1 {
+		integer = "-" + integer
+	}
+	if scale == 0 {
+		return integer
+	}
+	return integer + "." + string(digits[precision-scale:])
+}
+
+// time returns a random time between 1970 and 2100 in UTC.
+func (g *Generator) time() time.Time {
+	return time.Unix(g.rand.Int63n(4102444800), g.rand.Int63n(int64(time.Second))).UTC()
+}
+
+// fraction returns the layout of the fractional seconds of a time type with the precision in params, or def digits.
+func fraction(params []string, def int) string {
+	digits := def
+	if len(params) == 1 {
+		if p, err := strconv.Atoi(params[0]); err == nil {
+			digits = p
+		}
+	}
+	if digits == 0 {
+		return ""
+	}
+	return "." + strings.Repeat("0", digits)
+}
+
+// jsonValue returns a random value of colType as it is embedded in the json of ARRAY, MAP and STRUCT values.
+func (g *Generator) jsonValue(colType string) (any, error) {
+	base, params := splitType(colType)
+	switch base {
+	case "ARRAY":
+		if len(params) != 1 {
+			return nil, fmt.Errorf("invalid array type %s", colType)
+		}
+		values := make([]any, g.rand.Intn(4))
+		for i := range values {
+			v, err := g.jsonValue(params[0])
+			if err != nil {
+				return nil, err
+			}
+			values[i] = v
+		}
+		return values, nil
+	case "MAP":
+		if len(params) != 2 {
+			return nil, fmt.Errorf("invalid map type %s", colType)
+		}
+		values := map[string]any{}
+		for i := g.rand.Intn(4); i > 0; i-- {
+			k, err := g.Value(params[0])
+			if err != nil {
+				return nil, err
+			}
+			if values[k], err = g.jsonValue(params[1]); err != nil {
+				return nil, err
+			}
+		}
+		return values, nil
+	case "STRUCT":
+		values := map[string]any{}
+		for _, field := range params {
+			name, fieldType, ok := strings.Cut(strings.TrimSpace(field), " ")
+			if !ok {
+				return nil, fmt.Errorf("invalid struct field %q of %s", field, colType)
+			}
+			v, err := g.jsonValue(fieldType)
+			if err != nil {
+				return nil, err
+			}
+			values[strings.Trim(name, "`")] = v
+		}
+		return values, nil
+	}
+
+	v, err := g.Value(colType)
+	if err != nil {
+		return nil, err
+	}
+	switch base {
+	case "TINYINT", "SMALLINT", "INTEGER", "BIGINT", "FLOAT", "DOUBLE", "DECIMAL":
+		return json.Number(v), nil
+	case "BOOLEAN":
+		return v == "true", nil
+	}
+	return v, nil
+}
+
+// splitType splits a column type into its base type and the parameters between parentheses or angle brackets, e.g.
+// MAP<VARCHAR, ARRAY<INTEGER>> into MAP and [VARCHAR ARRAY<INTEGER>]. NOT NULL suffixes are ignored.
+func splitType(colType string) (string, []string) {
+	colType = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(colType), "NOT NULL"))
+	open := strings.IndexAny(colType, "(<")
+	if open == -1 || !strings.HasSuffix(colType, ")") && !strings.HasSuffix(colType, ">") {
+		return colType, nil
+	}
+	base := strings.TrimSpace(colType[:open])
+	inner := colType[open+1 : len(colType)-1]
+
+	var params []string
+	depth, start := 0, 0
+	for i, c := range inner {
+		switch c {
+		case '(', '<':
+			depth++
+		case ')', '>':
+			depth--
+		case ',':
+			if depth == 0 {
+				params = append(params, strings.TrimSpace(inner[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	return base, append(params, strings.TrimSpace(inner[start:]))
+}
@@ -0,0 +1,187 @@
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dstest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"sync"
+)
+
+// RecorderMode selects whether a Recorder talks to the service or replays a cassette.
+type RecorderMode int
+
+const (
+	// ModeReplay serves responses from the cassette without contacting the service.
+	ModeReplay RecorderMode = iota
+	// ModeRecord sends requests to the service and records the interactions into the cassette.
+	ModeRecord
+)
+
+// Interaction is a recorded request and its response.
+type Interaction struct {
+	Method     string      `json:"method"`
+	URL        string      `json:"url"`
+	StatusCode int         `json:"statusCode"`
+	Header     http.Header `json:"header"`
+	Body       string      `json:"body"`
+}
+
+// Cassette is the content of a recording file.
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+var tokenRegexp = regexp.MustCompile(`"(token|accessToken)"\s*:\s*"[^"]*"`)
+
+// SanitizeTokens replaces dataplane tokens in response bodies so that recordings can be committed. It is the default
+// Recorder sanitizer.
+func SanitizeTokens(i *Interaction) {
+	i.Body = tokenRegexp.ReplaceAllString(i.Body, `"$1": "redacted"`)
+	i.Header.Del("Set-Cookie")
+}
+
+// Recorder is an http.RoundTripper that records control plane and dataplane interactions to a cassette file and
+// replays them in tests. Request headers, including credentials, are never recorded. Streaming results use websockets
+// and are not recorded, use StreamingServer to script them instead.
+//
+// Use it with the driver through WithHTTPClient(rec.Client()). In ModeRecord call Save once done.
+type Recorder struct {
+	// Mode selects between recording and replaying.
+	Mode RecorderMode
+	// Path is the cassette file.
+	Path string
+	// Transport sends requests in ModeRecord. http.DefaultTransport is used when nil.
+	Transport http.RoundTripper
+	// Sanitize is applied to every interaction before it is recorded. SanitizeTokens is used when nil.
+	Sanitize func(i *Interaction)
+
+	mu       sync.Mutex
+	cassette Cassette
+	replayed []bool
+}
+
+// NewRecorder returns a Recorder for the cassette at path. In ModeReplay the cassette is loaded immediately.
+func NewRecorder(path string, mode RecorderMode) (*Recorder, error) {
+	r := &Recorder{Mode: mode, Path: path}
+	if mode == ModeRecord {
+		return r, nil
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(b, &r.cassette); err != nil {
+		return nil, fmt.Errorf("invalid cassette %s: %w", path, err)
+	}
+	r.replayed = make([]bool, len(r.cassette.Interactions))
+	return r, nil
+}
+
+// Client returns an http.Client using the recorder as transport.
+func (r *Recorder) Client() *http.Client {
+	return &http.Client{Transport: r}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	if r.Mode == ModeRecord {
+		return r.record(req)
+	}
+	return r.replay(req)
+}
+
+func (r *Recorder) record(req *http.Request) (*http.Response, error) {
+	transport := r.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	i := Interaction{
+		Method:     req.Method,
+		URL:        req.URL.String(),
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header.Clone(),
+		Body:       string(body),
+	}
+	sanitize := r.Sanitize
+	if sanitize == nil {
+		sanitize = SanitizeTokens
+	}
+	sanitize(&i)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cassette.Interactions = append(r.cassette.Interactions, i)
+	return resp, nil
+}
+
+// replay serves the first interaction not replayed yet with the method and url of req.
+func (r *Recorder) replay(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		_, _ = io.Copy(io.Discard, req.Body)
+		_ = req.Body.Close()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for idx, i := range r.cassette.Interactions {
+		if r.replayed[idx] || i.Method != req.Method || i.URL != req.URL.String() {
+			continue
+		}
+		r.replayed[idx] = true
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", i.StatusCode, http.StatusText(i.StatusCode)),
+			StatusCode:    i.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        i.Header.Clone(),
+			Body:          io.NopCloser(bytes.NewBufferString(i.Body)),
+			ContentLength: int64(len(i.Body)),
+			Request:       req,
+		}, nil
+	}
+	return nil, fmt.Errorf("no recorded interaction left for %s %s", req.Method, req.URL)
+}
+
+// Save writes the recorded interactions to the cassette file.
+func (r *Recorder) Save() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	b, err := json.MarshalIndent(&r.cassette, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(r.Path, b, 0o644)
+}
@@ -0,0 +1,282 @@
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package dstest provides in-process fakes of DeltaStream services for testing code that uses the driver.
+package dstest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+
+	"github.com/deltastreaminc/go-deltastream/apiv2"
+)
+
+// AuthMessage is the first message sent by the driver on a streaming connection.
+type AuthMessage struct {
+	Type        string `json:"type"`
+	AccessToken string `json:"accessToken"`
+	SessionID   string `json:"sessionId"`
+}
+
+// Column describes a column of a streaming result.
+type Column struct {
+	Name      string `json:"name"`
+	Type      string `json:"type"`
+	Nullable  bool   `json:"nullable,omitempty"`
+	Length    int64  `json:"length,omitempty"`
+	Precision int64  `json:"precision,omitempty"`
+	Scale     int64  `json:"scale,omitempty"`
+}
+
+// Step is a single action of a scripted streaming session.
+type Step func(conn *websocket.Conn) error
+
+// Metadata sends the metadata message describing the columns of the stream.
+func Metadata(columns ...Column) Step {
+	return sendJSON(map[string]any{"type": "metadata", "headers": map[string]string{}, "columns": columns})
+}
+
+// Data sends a data message. Values are sent as strings, nil values are sent as null.
+func Data(values ...*string) Step {
+	return sendJSON(map[string]any{"type": "data", "headers": map[string]string{}, "data": values})
+}
+
+// DataWithHeaders sends a data message with headers, e.g. the timestamp header carrying the event time of the record in
+// milliseconds since the epoch.
+func DataWithHeaders(headers map[string]string, values ...*string) Step {
+	return sendJSON(map[string]any{"type": "data", "headers": headers, "data": values})
+}
+
+// Row sends a data message without null values.
+func Row(values ...string) Step {
+	data := make([]*string, len(values))
+	for i := range values {
+		data[i] = &values[i]
+	}
+	return Data(data...)
+}
+
+// Error sends an error message. The driver ends the stream with an error when it receives one.
+func Error(sqlCode, message string) Step {
+	return sendJSON(map[string]any{"type": "error", "headers": map[string]string{}, "sqlCode": sqlCode, "message": message})
+}
+
+// Shutdown sends a notice that the server is shutting down, with the given message.
+func Shutdown(message string) Step {
+	return sendJSON(map[string]any{"type": "shutdown", "headers": map[string]string{}, "message": message})
+}
+
+// SessionAck acknowledges sessionID as the session the stream is bound to. See StreamingServer.AckSessions to
+// acknowledge the session sent by the client instead.
+func SessionAck(sessionID string) Step {
+	return sendJSON(map[string]any{"type": "session", "headers": map[string]string{}, "sessionId": sessionID})
+}
+
+// Raw sends msg as is, e.g. to test handling of malformed messages.
+func Raw(msg string) Step {
+	return func(conn *websocket.Conn) error {
+		return conn.WriteMessage(websocket.TextMessage, []byte(msg))
+	}
+}
+
+// Delay pauses the session for d.
+func Delay(d time.Duration) Step {
+	return func(conn *websocket.Conn) error {
+		time.Sleep(d)
+		return nil
+	}
+}
+
+// Close sends a websocket close frame with the given close code and reason.
+func Close(code int, reason string) Step {
+	return func(conn *websocket.Conn) error {
+		return conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(time.Second))
+	}
+}
+
+// Disconnect drops the underlying network connection without a close handshake.
+func Disconnect() Step {
+	return func(conn *websocket.Conn) error {
+		return conn.NetConn().Close()
+	}
+}
+
+func sendJSON(v any) Step {
+	return func(conn *websocket.Conn) error {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		return conn.WriteMessage(websocket.TextMessage, b)
+	}
+}
+
+// StreamingServer is an in-process dataplane serving streaming results. Every connection authenticates and then
+// replays the scripted steps. After the last step the server waits for the client to close the connection.
+type StreamingServer struct {
+	*httptest.Server
+
+	// Token is the dataplane token clients are expected to authenticate with.
+	Token string
+	// AckSessions acknowledges the session ID sent by the client on every connection before replaying the steps.
+	AckSessions bool
+
+	steps []Step
+
+	mu         sync.Mutex
+	handshakes []http.Header
+	auth       []AuthMessage
+	closeCodes []int
+	errs       []error
+}
+
+// NewStreamingServer starts a StreamingServer replaying steps. The server must be closed by the caller.
+func NewStreamingServer(steps ...Step) *StreamingServer {
+	s := &StreamingServer{Token: "dataplanetoken", steps: steps}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+func (s *StreamingServer) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.handshakes = append(s.handshakes, r.Header.Clone())
+	s.mu.Unlock()
+	upgrader := websocket.Upgrader{}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		s.recordErr(err)
+		return
+	}
+	defer conn.Close()
+
+	auth := AuthMessage{}
+	if err = conn.ReadJSON(&auth); err != nil {
+		s.recordErr(err)
+		return
+	}
+	s.mu.Lock()
+	s.auth = append(s.auth, auth)
+	s.mu.Unlock()
+	if auth.AccessToken != s.Token {
+		_ = Error("3D012", "invalid token")(conn)
+		return
+	}
+	if s.AckSessions {
+		if err = SessionAck(auth.SessionID)(conn); err != nil {
+			s.recordErr(err)
+			return
+		}
+	}
+
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				if ce, ok := err.(*websocket.CloseError); ok {
+					s.mu.Lock()
+					s.closeCodes = append(s.closeCodes, ce.Code)
+					s.mu.Unlock()
+				}
+				return
+			}
+		}
+	}()
+
+	for _, step := range s.steps {
+		select {
+		case <-closed:
+			return
+		default:
+		}
+		if err = step(conn); err != nil {
+			s.recordErr(err)
+			return
+		}
+	}
+	<-closed
+}
+
+func (s *StreamingServer) recordErr(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.errs = append(s.errs, err)
+}
+
+// Handshakes returns the headers of the websocket handshakes received so far, one per connection.
+func (s *StreamingServer) Handshakes() []http.Header {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]http.Header{}, s.handshakes...)
+}
+
+// AuthMessages returns the authentication messages received so far, one per connection.
+func (s *StreamingServer) AuthMessages() []AuthMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]AuthMessage{}, s.auth...)
+}
+
+// CloseCodes returns the codes of the close frames received from clients so far.
+func (s *StreamingServer) CloseCodes() []int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]int{}, s.closeCodes...)
+}
+
+// Errors returns the errors encountered while serving connections, e.g. failed writes to clients that went away.
+func (s *StreamingServer) Errors() []error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]error{}, s.errs...)
+}
+
+// StatementResponse returns the control plane response to a statement whose results are streamed from this server.
+func (s *StreamingServer) StatementResponse(queryID *string) *apiv2.ResultSet {
+	statementID := uuid.New()
+	return &apiv2.ResultSet{
+		SqlState:    "00000",
+		StatementID: statementID,
+		CreatedOn:   time.Now().Unix(),
+		Metadata: apiv2.ResultSetMetadata{
+			Encoding:      apiv2.Json,
+			PartitionInfo: []apiv2.ResultSetPartitionInfo{},
+			Columns:       apiv2.ResultSetColumns{},
+			DataplaneRequest: &apiv2.DataplaneRequest{
+				Token:       s.Token,
+				Uri:         s.URL,
+				StatementID: statementID.String(),
+				QueryID:     queryID,
+				RequestType: apiv2.DataplaneRequestRequestTypeStreaming,
+			},
+		},
+	}
+}
+
+// StatementHandler responds to statement submissions with StatementResponse. It can be used as the control plane
+// handler of an httptest.Server or with an http mocking library.
+func (s *StreamingServer) StatementHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(s.StatementResponse(nil))
+	}
+}
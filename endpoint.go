@@ -0,0 +1,68 @@
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package godeltastream
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/google/uuid"
+
+	"github.com/deltastreaminc/go-deltastream/apiv2"
+)
+
+// EndpointResolver returns the control plane url of the organization org, e.g. https://acme.deltastream.io/v2. See
+// WithEndpointResolver.
+type EndpointResolver func(org uuid.UUID) (string, error)
+
+// endpointClients holds the control plane clients of the organizations resolved by an EndpointResolver.
+type endpointClients struct {
+	resolver  EndpointResolver
+	newClient func(server string) (*apiv2.ClientWithResponses, error)
+
+	mu      sync.Mutex
+	clients map[uuid.UUID]*apiv2.ClientWithResponses
+}
+
+func newEndpointClients(resolver EndpointResolver, newClient func(server string) (*apiv2.ClientWithResponses, error)) *endpointClients {
+	return &endpointClients{
+		resolver:  resolver,
+		newClient: newClient,
+		clients:   map[uuid.UUID]*apiv2.ClientWithResponses{},
+	}
+}
+
+// client returns the client for the endpoint of org, resolving it on first use. Errors are not cached, the endpoint is
+// resolved again on the next statement.
+func (e *endpointClients) client(org uuid.UUID) (*apiv2.ClientWithResponses, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if client, ok := e.clients[org]; ok {
+		return client, nil
+	}
+
+	server, err := e.resolver(org)
+	if err != nil {
+		return nil, &ErrClientError{message: fmt.Sprintf("unable to resolve endpoint of organization %s", org), wrapErr: err}
+	}
+	client, err := e.newClient(server)
+	if err != nil {
+		return nil, err
+	}
+	e.clients[org] = client
+	return client, nil
+}
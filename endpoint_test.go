@@ -0,0 +1,101 @@
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package godeltastream
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jarcoal/httpmock"
+	. "github.com/onsi/gomega"
+)
+
+// useOrganizationResponder responds with a successful result set that switches the connection to org.
+func useOrganizationResponder(g *WithT, org uuid.UUID) httpmock.Responder {
+	return func(r *http.Request) (*http.Response, error) {
+		b, err := os.ReadFile("fixtures/use-database-200-00000.json")
+		g.Expect(err).To(BeNil())
+		rs := map[string]any{}
+		g.Expect(json.Unmarshal(b, &rs)).To(Succeed())
+		rs["metadata"].(map[string]any)["context"] = map[string]any{"organizationID": org.String()}
+		return httpmock.NewJsonResponse(http.StatusOK, rs)
+	}
+}
+
+func TestEndpointResolver(t *testing.T) {
+	g := NewWithT(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	org := uuid.New()
+	httpmock.RegisterResponder("POST", "https://api.deltastream.io/v2/statements", useOrganizationResponder(g, org))
+	httpmock.RegisterResponder("POST", "https://acme.deltastream.io/v2/statements", useOrganizationResponder(g, org))
+
+	var resolved []uuid.UUID
+	connector, err := ConnectorWithOptions(context.TODO(), WithServer("https://api.deltastream.io/v2"), WithStaticToken("sometoken"), WithEndpointResolver(func(o uuid.UUID) (string, error) {
+		resolved = append(resolved, o)
+		return "https://acme.deltastream.io/v2", nil
+	}))
+	g.Expect(err).To(BeNil())
+	db := sql.OpenDB(connector)
+	defer db.Close()
+	conn, err := db.Conn(context.TODO())
+	g.Expect(err).To(BeNil())
+	defer conn.Close()
+
+	// the organization is unknown until the first statement returns
+	for i := 0; i < 3; i++ {
+		_, err = conn.ExecContext(context.TODO(), "USE ORGANIZATION acme;")
+		g.Expect(err).To(BeNil())
+	}
+	info := httpmock.GetCallCountInfo()
+	g.Expect(info["POST https://api.deltastream.io/v2/statements"]).To(Equal(1))
+	g.Expect(info["POST https://acme.deltastream.io/v2/statements"]).To(Equal(2))
+	g.Expect(resolved).To(Equal([]uuid.UUID{org}))
+}
+
+func TestEndpointResolverError(t *testing.T) {
+	g := NewWithT(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("POST", "https://api.deltastream.io/v2/statements", useOrganizationResponder(g, uuid.New()))
+
+	errUnknown := errors.New("unknown organization")
+	connector, err := ConnectorWithOptions(context.TODO(), WithServer("https://api.deltastream.io/v2"), WithStaticToken("sometoken"), WithEndpointResolver(func(uuid.UUID) (string, error) {
+		return "", errUnknown
+	}))
+	g.Expect(err).To(BeNil())
+	db := sql.OpenDB(connector)
+	defer db.Close()
+	conn, err := db.Conn(context.TODO())
+	g.Expect(err).To(BeNil())
+	defer conn.Close()
+
+	_, err = conn.ExecContext(context.TODO(), "USE ORGANIZATION acme;")
+	g.Expect(err).To(BeNil())
+	_, err = conn.ExecContext(context.TODO(), "LIST DATABASES;")
+	g.Expect(err).To(MatchError(errUnknown))
+	var clientErr *ErrClientError
+	g.Expect(errors.As(err, &clientErr)).To(BeTrue())
+}
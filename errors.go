@@ -17,7 +17,12 @@ limitations under the License.
 package godeltastream
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
+	"net/http"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
 )
@@ -27,16 +32,30 @@ var ErrAuthenticationError = fmt.Errorf("error while authenticating with server"
 var ErrDeadlineExceeded = fmt.Errorf("deadline exceeded")
 var ErrServiceUnavailable = fmt.Errorf("service temporarily unavailable")
 
+// ErrStatementCancelled matches, with errors.Is, the ErrSQLError of statements cancelled by the server (SqlState
+// 57000) or cancelled after exceeding their timeout (SqlState 57014).
+var ErrStatementCancelled = fmt.Errorf("statement cancelled")
+
 type ErrStatementClosed struct{}
 
 func (*ErrStatementClosed) Error() string { return "statement is closed" }
 
+// ErrStreamClosed is returned by the methods reading rows, e.g. Next, once the rows were closed with Close.
+type ErrStreamClosed struct{}
+
+func (*ErrStreamClosed) Error() string { return "rows are closed" }
+
 // ErrInterfaceError is raised when there is a mismatch between the expected interface between client and server
 type ErrInterfaceError struct {
 	message string
 	wrapErr error
 }
 
+// NewInterfaceError returns an ErrInterfaceError with the given message, wrapping err which may be nil.
+func NewInterfaceError(message string, err error) *ErrInterfaceError {
+	return &ErrInterfaceError{message: message, wrapErr: err}
+}
+
 func (e *ErrInterfaceError) Error() string {
 	if e.message == "" {
 		return "connection is closed"
@@ -50,21 +69,58 @@ func (e *ErrInterfaceError) Unwrap() error {
 	return e.wrapErr
 }
 
+// Is reports whether target is an ErrInterfaceError with the same message. A target without a message matches any
+// ErrInterfaceError.
+func (e *ErrInterfaceError) Is(target error) bool {
+	t, ok := target.(*ErrInterfaceError)
+	if !ok || t == nil {
+		return false
+	}
+	return t.message == "" || t.message == e.message
+}
+
 // ErrServerError is raised when server has an internal error while processing a message
 type ErrServerError struct {
 	message string
+	wrapErr error
+	// StatusCode is the HTTP status code of the response, if any.
+	StatusCode int
+}
+
+// NewServerError returns an ErrServerError with the given HTTP status code and message, wrapping err which may be nil.
+func NewServerError(statusCode int, message string, err error) *ErrServerError {
+	return &ErrServerError{message: message, wrapErr: err, StatusCode: statusCode}
 }
 
 func (e *ErrServerError) Error() string {
 	return e.message
 }
 
+func (e *ErrServerError) Unwrap() error {
+	return e.wrapErr
+}
+
+// Is reports whether target is an ErrServerError with the same message. A target without a message matches any
+// ErrServerError.
+func (e *ErrServerError) Is(target error) bool {
+	t, ok := target.(*ErrServerError)
+	if !ok || t == nil {
+		return false
+	}
+	return t.message == "" || t.message == e.message
+}
+
 // ErrClientError is raised when client has an internal error while processing a message
 type ErrClientError struct {
 	message string
 	wrapErr error
 }
 
+// NewClientError returns an ErrClientError with the given message, wrapping err which may be nil.
+func NewClientError(message string, err error) *ErrClientError {
+	return &ErrClientError{message: message, wrapErr: err}
+}
+
 func (e *ErrClientError) Error() string {
 	if e.message == "" {
 		return "connection is closed"
@@ -78,12 +134,259 @@ func (e *ErrClientError) Unwrap() error {
 	return e.wrapErr
 }
 
+// Is reports whether target is an ErrClientError with the same message, e.g. ErrNotSupported. A target without a
+// message matches any ErrClientError.
+func (e *ErrClientError) Is(target error) bool {
+	t, ok := target.(*ErrClientError)
+	if !ok || t == nil {
+		return false
+	}
+	return t.message == "" || t.message == e.message
+}
+
+// maxBodySnippet is the number of bytes of response bodies kept in ErrUnexpectedResponse.
+const maxBodySnippet = 512
+
+// ErrUnexpectedResponse is returned when the server responds with a status code or content type the API does not
+// define, e.g. an HTML error page from a proxy or load balancer.
+type ErrUnexpectedResponse struct {
+	StatusCode  int
+	ContentType string
+	// BodySnippet is the beginning of the response body.
+	BodySnippet string
+}
+
+func newUnexpectedResponse(rsp *http.Response, body []byte) *ErrUnexpectedResponse {
+	e := &ErrUnexpectedResponse{}
+	if rsp != nil {
+		e.StatusCode = rsp.StatusCode
+		e.ContentType = rsp.Header.Get("Content-Type")
+	}
+	e.BodySnippet = bodySnippet(body)
+	return e
+}
+
+// bodySnippet returns the beginning of a response body, up to maxBodySnippet bytes.
+func bodySnippet(body []byte) string {
+	if len(body) > maxBodySnippet {
+		body = body[:maxBodySnippet]
+		// do not cut a multi-byte character in half
+		for len(body) > 0 && !utf8.Valid(body) {
+			body = body[:len(body)-1]
+		}
+	}
+	return strings.TrimSpace(string(body))
+}
+
+func (e *ErrUnexpectedResponse) Error() string {
+	msg := fmt.Sprintf("unexpected response from server. status code: %d", e.StatusCode)
+	if e.ContentType != "" {
+		msg += ", content type: " + e.ContentType
+	}
+	if e.BodySnippet != "" {
+		msg += ", body: " + e.BodySnippet
+	}
+	return msg
+}
+
+// ErrResponseTooLarge is returned when a response of the server is larger than the limit set with WithMaxResponseBytes.
+type ErrResponseTooLarge struct {
+	Limit int64
+}
+
+func (e *ErrResponseTooLarge) Error() string {
+	return fmt.Sprintf("response exceeds the maximum size of %d bytes", e.Limit)
+}
+
+// maxRawValueInError bounds the length of the raw value quoted in the message of ErrColumnDecode.
+const maxRawValueInError = 64
+
+// ErrColumnDecode is returned by Next when a value sent by the server cannot be converted to the type of its column.
+type ErrColumnDecode struct {
+	// Column and Type are the name and type of the column.
+	Column string
+	Type   string
+	// RawValue is the value as sent by the server.
+	RawValue string
+	Err      error
+}
+
+func (e *ErrColumnDecode) Error() string {
+	raw := e.RawValue
+	if len(raw) > maxRawValueInError {
+		raw = raw[:maxRawValueInError]
+		// do not cut a multi-byte character in half
+		for len(raw) > 0 && !utf8.ValidString(raw) {
+			raw = raw[:len(raw)-1]
+		}
+		raw += "..."
+	}
+	return fmt.Sprintf("cannot decode value %q of column %s (%s): %v", raw, e.Column, e.Type, e.Err)
+}
+
+func (e *ErrColumnDecode) Unwrap() error {
+	return e.Err
+}
+
+// ErrStreaming wraps the errors of streaming results with the ids of the statement and of the continuous query producing
+// the stream, so that client streams can be correlated with server side queries.
+type ErrStreaming struct {
+	StatementID string
+	// QueryID is the id of the query producing the stream, empty if the stream is not produced by a query.
+	QueryID string
+	Err     error
+}
+
+func (e *ErrStreaming) Error() string {
+	if e.QueryID == "" {
+		return fmt.Sprintf("%v (statement %s)", e.Err, e.StatementID)
+	}
+	return fmt.Sprintf("%v (statement %s, query %s)", e.Err, e.StatementID, e.QueryID)
+}
+
+func (e *ErrStreaming) Unwrap() error {
+	return e.Err
+}
+
+// ErrStreamHandshake is returned when the websocket connection to the dataplane serving a streaming result cannot be
+// established, e.g. when a proxy rejects the connection or the dataplane token is not accepted.
+type ErrStreamHandshake struct {
+	// URL is the dataplane URL dialed, with the values of its query parameters redacted.
+	URL string
+	// StatusCode is the status code of the response to the handshake, zero if no response was received.
+	StatusCode int
+	// Headers are the headers of the response without cookies, nil if no response was received.
+	Headers http.Header
+	// Body is the beginning of the body of the response.
+	Body string
+	// Err is the error dialing the dataplane, e.g. a network error or websocket.ErrBadHandshake.
+	Err error
+}
+
+func (e *ErrStreamHandshake) Error() string {
+	if e.StatusCode == 0 {
+		return fmt.Sprintf("unable to connect to dataplane %s: %v", e.URL, e.Err)
+	}
+	msg := fmt.Sprintf("websocket handshake with dataplane %s failed. status code: %d", e.URL, e.StatusCode)
+	if e.Body != "" {
+		msg += ", body: " + e.Body
+	}
+	return msg
+}
+
+func (e *ErrStreamHandshake) Unwrap() error {
+	return e.Err
+}
+
+// ErrStreamEndedByServer is returned by the rows of streaming results the server ended other than normally, e.g. while
+// shutting down, unless the stream was reconnected, see WithStreamDialRetry. Streams the server ends normally end with
+// io.EOF.
+type ErrStreamEndedByServer struct {
+	// Code is the websocket close code sent by the server, e.g. 1001 when going away.
+	Code int
+	// Reason is the reason sent by the server.
+	Reason string
+}
+
+func (e *ErrStreamEndedByServer) Error() string {
+	if e.Reason == "" {
+		return fmt.Sprintf("stream ended by server (close code %d)", e.Code)
+	}
+	return fmt.Sprintf("stream ended by server (close code %d): %s", e.Code, e.Reason)
+}
+
+// ErrSessionMismatch is returned by the rows of streaming results when the dataplane acknowledges a session other than
+// the one the statement was submitted with, see WithSessionID, unless the connection recovers its sessions, see
+// WithSessionRecoveryHandler. Servers that do not acknowledge sessions are not verified.
+type ErrSessionMismatch struct {
+	// Requested is the session ID sent by the driver.
+	Requested string
+	// Bound is the session ID acknowledged by the server, empty if the server did not bind a session.
+	Bound string
+}
+
+func (e *ErrSessionMismatch) Error() string {
+	if e.Bound == "" {
+		return fmt.Sprintf("stream is not bound to session %s", e.Requested)
+	}
+	return fmt.Sprintf("stream is bound to session %s instead of %s", e.Bound, e.Requested)
+}
+
+// ErrResultExpired is returned by the rows of a result set when a partition cannot be read from the result the rows
+// started with, e.g. when the result expired or was computed again while a long export was reading it. Reading the
+// result again requires submitting the statement again.
+type ErrResultExpired struct {
+	StatementID uuid.UUID
+	// PartitionID is the partition that could not be read.
+	PartitionID int32
+	// Reason describes how the result changed.
+	Reason string
+	// Err is the error fetching the partition, if any.
+	Err error
+}
+
+func (e *ErrResultExpired) Error() string {
+	return fmt.Sprintf("unable to read partition %d of the result of statement %s: %s", e.PartitionID, e.StatementID, e.Reason)
+}
+
+func (e *ErrResultExpired) Unwrap() error {
+	return e.Err
+}
+
 type ErrSQLError struct {
 	SQLCode     SqlState
 	Message     string
 	StatementID uuid.UUID
+	strict      bool // see WithStrictSQLStates
 }
 
 func (e ErrSQLError) Error() string {
 	return fmt.Sprintf("sql error: %s (SQLState: %s)", e.Message, e.SQLCode)
 }
+
+// Is reports whether target is an ErrSQLError with the same SQLCode, Message and StatementID. Fields the target leaves
+// empty match any value, so a target without a SQLCode matches any ErrSQLError. Cancelled statements also match
+// ErrStatementCancelled, and errors of connections with WithStrictSQLStates the errors their SqlState maps to.
+func (e ErrSQLError) Is(target error) bool {
+	if target == ErrStatementCancelled {
+		return e.SQLCode == SqlStateCancelled || e.SQLCode == SqlStateTimeout
+	}
+	if e.strict {
+		switch target {
+		case sql.ErrNoRows:
+			return e.SQLCode == SqlStateNoData
+		case context.DeadlineExceeded:
+			return e.SQLCode == SqlStateTimeout
+		case ErrAuthenticationError:
+			return e.SQLCode == SqlStateInvalidApiToken || e.SQLCode == SqlStateInsufficientPrivilege
+		}
+	}
+	var t ErrSQLError
+	switch v := target.(type) {
+	case ErrSQLError:
+		t = v
+	case *ErrSQLError:
+		if v == nil {
+			return false
+		}
+		t = *v
+	default:
+		return false
+	}
+	return (t.SQLCode == "" || t.SQLCode == e.SQLCode) &&
+		(t.Message == "" || t.Message == e.Message) &&
+		(t.StatementID == uuid.Nil || t.StatementID == e.StatementID)
+}
+
+// As allows errors.As to extract an ErrSQLError into both ErrSQLError and *ErrSQLError targets.
+func (e ErrSQLError) As(target any) bool {
+	switch t := target.(type) {
+	case *ErrSQLError:
+		*t = e
+		return true
+	case **ErrSQLError:
+		*t = &e
+		return true
+	}
+	return false
+}
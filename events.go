@@ -0,0 +1,84 @@
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package godeltastream
+
+import (
+	"time"
+)
+
+// ConnectionEventType is the kind of a ConnectionEvent.
+type ConnectionEventType string
+
+const (
+	// ConnectionEventConnected is emitted when a connection is opened.
+	ConnectionEventConnected ConnectionEventType = "connected"
+	// ConnectionEventAuthRefreshed is emitted when the connection refreshed a token the server rejected.
+	ConnectionEventAuthRefreshed ConnectionEventType = "auth-refreshed"
+	// ConnectionEventStatementSubmitted is emitted when the server accepted a statement, whether it succeeded or not.
+	ConnectionEventStatementSubmitted ConnectionEventType = "statement-submitted"
+	// ConnectionEventDataplaneAttached is emitted when results are fetched from a dataplane.
+	ConnectionEventDataplaneAttached ConnectionEventType = "dataplane-attached"
+	// ConnectionEventStreamOpened is emitted when a streaming result starts.
+	ConnectionEventStreamOpened ConnectionEventType = "stream-opened"
+	// ConnectionEventStreamClosed is emitted when a streaming result is closed, or stopped by closing its connection.
+	ConnectionEventStreamClosed ConnectionEventType = "stream-closed"
+	// ConnectionEventSessionRecovered is emitted when a connection moved to a new session, see
+	// WithSessionRecoveryHandler.
+	ConnectionEventSessionRecovered ConnectionEventType = "session-recovered"
+	// ConnectionEventConnClosed is emitted when a connection is closed.
+	ConnectionEventConnClosed ConnectionEventType = "conn-closed"
+)
+
+// ConnectionEvent is a change of the state of a connection, see WithConnectionEventHandler.
+type ConnectionEvent struct {
+	Type ConnectionEventType
+	Time time.Time
+	// ConnectionID identifies the connection for the lifetime of the process.
+	ConnectionID string
+	// StatementID is the id of the statement of statement, dataplane and stream events.
+	StatementID string
+	// QueryID is the id of the query producing the stream of stream events, if any.
+	QueryID string
+	// Dataplane is the uri of the dataplane of dataplane and stream events.
+	Dataplane string
+	// SessionID is the new session of session events.
+	SessionID string
+	// ComputePool is the compute pool of statement events, if the request named one, see WithComputePoolSelector.
+	ComputePool string
+}
+
+// ConnectionEventHandler receives the events of connections. It is called synchronously, from the goroutines of the
+// connection and of its streams, so it must be safe for concurrent use and return quickly.
+type ConnectionEventHandler func(ConnectionEvent)
+
+// WithConnectionEventHandler reports the lifecycle events of connections to handler, e.g. for diagnostics or to show
+// the status of the connection.
+func WithConnectionEventHandler(handler ConnectionEventHandler) func(*connectionOptions) {
+	return func(o *connectionOptions) {
+		o.eventHandler = handler
+	}
+}
+
+// emit reports an event of the connection, if it has an event handler.
+func (c *Conn) emit(e ConnectionEvent) {
+	if c.eventHandler == nil {
+		return
+	}
+	e.Time = time.Now()
+	e.ConnectionID = c.id
+	c.eventHandler(e)
+}
@@ -0,0 +1,111 @@
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package godeltastream
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+	. "github.com/onsi/gomega"
+
+	"github.com/deltastreaminc/go-deltastream/dstest"
+)
+
+func TestConnectionEvents(t *testing.T) {
+	g := NewWithT(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	server := dstest.NewStreamingServer(dstest.Metadata(streamingColumns...), dstest.Row("1", "a"))
+	defer server.Close()
+	streaming := server.StatementResponse(nil)
+
+	responses := []httpmock.Responder{
+		mockGetStatementResponser(g, http.StatusOK, "token2", "fixtures/list-organizations-200-00000-1.json"),
+		mockGetStatementResponser(g, http.StatusOK, "token2", "fixtures/dataplane-query-200-00000-0.json"),
+		httpmock.NewJsonResponderOrPanic(http.StatusOK, streaming),
+	}
+	httpmock.RegisterResponder("POST", "https://api.deltastream.io/v2/statements", func(r *http.Request) (*http.Response, error) {
+		if r.Header.Get("Authorization") == "Bearer token1" {
+			return httpmock.NewStringResponse(http.StatusUnauthorized, "unauthorized"), nil
+		}
+		next := responses[0]
+		responses = responses[1:]
+		return next(r)
+	})
+	httpmock.RegisterResponder("GET", "https://dpapi.deltastream.io/v2/statements/d789687d-4e1b-4649-846e-4f10b722f3ad?partitionID=0&timezone=UTC",
+		mockGetStatementResponser(g, http.StatusOK, "dataplanetoken", "fixtures/list-organizations-200-00000-1.json"),
+	)
+
+	var (
+		mu     sync.Mutex
+		events []ConnectionEvent
+	)
+	start := time.Now()
+	connector, err := ConnectorWithOptions(context.TODO(), WithServer("https://api.deltastream.io/v2"), WithAuthClient(&rotatingAuthClient{}),
+		WithConnectionEventHandler(func(e ConnectionEvent) {
+			mu.Lock()
+			defer mu.Unlock()
+			events = append(events, e)
+		}))
+	g.Expect(err).To(BeNil())
+	db := sql.OpenDB(connector)
+	defer db.Close()
+	conn, err := db.Conn(context.TODO())
+	g.Expect(err).To(BeNil())
+
+	_, err = conn.ExecContext(context.TODO(), "LIST ORGANIZATIONS;")
+	g.Expect(err).To(BeNil())
+	rows, err := conn.QueryContext(context.TODO(), "SELECT * FROM mview_table;")
+	g.Expect(err).To(BeNil())
+	g.Expect(rows.Close()).To(Succeed())
+	rows, err = conn.QueryContext(context.TODO(), "SELECT * FROM pageviews;")
+	g.Expect(err).To(BeNil())
+	g.Expect(rows.Close()).To(Succeed())
+	g.Expect(conn.Raw(func(driverConn any) error { return driverConn.(*Conn).Close() })).To(Succeed())
+
+	mu.Lock()
+	defer mu.Unlock()
+	type summary struct {
+		Type                   ConnectionEventType
+		StatementID, Dataplane string
+	}
+	var got []summary
+	for _, e := range events {
+		g.Expect(e.ConnectionID).To(Equal(events[0].ConnectionID))
+		g.Expect(e.ConnectionID).NotTo(BeEmpty())
+		g.Expect(e.Time).To(BeTemporally(">=", start))
+		got = append(got, summary{e.Type, e.StatementID, e.Dataplane})
+	}
+	streamID := streaming.Metadata.DataplaneRequest.StatementID
+	g.Expect(got).To(Equal([]summary{
+		{Type: ConnectionEventConnected},
+		{Type: ConnectionEventAuthRefreshed},
+		{Type: ConnectionEventStatementSubmitted, StatementID: "d789687d-4e1b-4649-846e-4f10b722f3ad"},
+		{Type: ConnectionEventStatementSubmitted, StatementID: "d789687d-4e1b-4649-846e-4f10b722f3ad"},
+		{Type: ConnectionEventDataplaneAttached, StatementID: "d789687d-4e1b-4649-846e-4f10b722f3ad", Dataplane: "https://dpapi.deltastream.io/v2/statements"},
+		{Type: ConnectionEventStatementSubmitted, StatementID: streaming.StatementID.String()},
+		{Type: ConnectionEventStreamOpened, StatementID: streamID, Dataplane: streaming.Metadata.DataplaneRequest.Uri},
+		{Type: ConnectionEventStreamClosed, StatementID: streamID, Dataplane: streaming.Metadata.DataplaneRequest.Uri},
+		{Type: ConnectionEventConnClosed},
+	}))
+}
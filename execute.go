@@ -0,0 +1,143 @@
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package godeltastream
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	defaultExecuteTimeout      = 30 * time.Second
+	executeDialTimeout         = 5 * time.Second
+	executeTLSHandshakeTimeout = 5 * time.Second
+	// maxExecuteConnectors bounds the number of configurations whose transports are kept warm by Execute.
+	maxExecuteConnectors = 16
+)
+
+// ConnectionConfig configures the connection used by Execute.
+type ConnectionConfig struct {
+	// Server is the url of the api, https://api.deltastream.com if empty. Urls without a path use the api version as
+	// path, see WithAPIVersion.
+	Server string
+	// Token is the api token statements are authenticated with.
+	Token string
+	// SessionID is the optional ID of the session statements run in.
+	SessionID string
+	// Timeout bounds the whole execution of a statement, 30 seconds if zero.
+	Timeout time.Duration
+}
+
+// key returns the hash identifying the connectors built from c. Timeout is not part of the key, as it does not
+// affect the connector.
+func (c ConnectionConfig) key() string {
+	h := sha256.New()
+	for _, s := range []string{c.Server, c.Token, c.SessionID} {
+		h.Write([]byte(s))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// executeConnectors caches the connectors of Execute by configuration, so that invocations of a serverless function
+// reuse the warm connections of previous invocations.
+var executeConnectors = struct {
+	sync.Mutex
+	byKey map[string]*connector
+	keys  []string // in insertion order, to evict the oldest connector
+}{byKey: map[string]*connector{}}
+
+// Execute runs a single statement without database/sql, for short lived processes such as serverless functions. The
+// transport of each configuration is kept in a package-level cache and reused across calls.
+func Execute(ctx context.Context, config ConnectionConfig, query string) (*ExecResult, error) {
+	connector, err := executeConnector(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := config.Timeout
+	if timeout == 0 {
+		timeout = defaultExecuteTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	conn, err := connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	res, err := conn.(*Conn).ExecContext(ctx, query, nil)
+	if err != nil {
+		return nil, err
+	}
+	return res.(*ExecResult), nil
+}
+
+func executeConnector(ctx context.Context, config ConnectionConfig) (*connector, error) {
+	if config.Token == "" {
+		return nil, &ErrClientError{message: "no api token provided"}
+	}
+	key := config.key()
+	executeConnectors.Lock()
+	defer executeConnectors.Unlock()
+	if c, ok := executeConnectors.byKey[key]; ok {
+		return c, nil
+	}
+
+	options := []ConnectionOption{WithStaticToken(config.Token), WithHTTPClient(newExecuteHTTPClient())}
+	if config.Server != "" {
+		options = append(options, WithServer(config.Server))
+	}
+	if config.SessionID != "" {
+		options = append(options, WithSessionID(config.SessionID))
+	}
+	c, err := ConnectorWithOptions(ctx, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(executeConnectors.keys) == maxExecuteConnectors {
+		evicted := executeConnectors.keys[0]
+		executeConnectors.byKey[evicted].opts.httpClient.CloseIdleConnections()
+		delete(executeConnectors.byKey, evicted)
+		executeConnectors.keys = executeConnectors.keys[1:]
+	}
+	executeConnectors.byKey[key] = c
+	executeConnectors.keys = append(executeConnectors.keys, key)
+	return c, nil
+}
+
+// newExecuteHTTPClient returns a client failing fast when the server cannot be reached, so that a function does not
+// spend its time budget on connection attempts.
+func newExecuteHTTPClient() *http.Client {
+	return &http.Client{Transport: &http.Transport{
+		Proxy:               http.ProxyFromEnvironment,
+		DialContext:         (&net.Dialer{Timeout: executeDialTimeout, KeepAlive: 30 * time.Second}).DialContext,
+		TLSHandshakeTimeout: executeTLSHandshakeTimeout,
+		TLSClientConfig:     &tls.Config{ClientSessionCache: tls.NewLRUClientSessionCache(0)},
+		ForceAttemptHTTP2:   true,
+		MaxIdleConnsPerHost: 2,
+		IdleConnTimeout:     90 * time.Second,
+	}}
+}
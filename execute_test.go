@@ -0,0 +1,89 @@
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package godeltastream
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestExecute(t *testing.T) {
+	g := NewWithT(t)
+
+	s := &connCountingServer{}
+	s.Server = httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		g.Expect(r.Header.Get("Authorization")).To(Equal("Bearer sometoken"))
+		w.Header().Set("Content-Type", "application/json")
+		b, _ := os.ReadFile("fixtures/list-organizations-200-00000-1.json")
+		_, _ = w.Write(b)
+	}))
+	s.Config.ConnState = func(c net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			s.mu.Lock()
+			s.conns++
+			s.mu.Unlock()
+		}
+	}
+	s.Start()
+	defer s.Close()
+
+	config := ConnectionConfig{Server: s.URL + "/v2", Token: "sometoken"}
+	for i := 0; i < 3; i++ {
+		res, err := Execute(context.Background(), config, "LIST ORGANIZATIONS;")
+		g.Expect(err).To(BeNil())
+		g.Expect(res.Columns).To(ContainElement("name"))
+	}
+	// invocations reuse the cached transport and its connection
+	g.Expect(s.connections()).To(Equal(1))
+
+	c1, err := executeConnector(context.Background(), config)
+	g.Expect(err).To(BeNil())
+	c2, err := executeConnector(context.Background(), ConnectionConfig{Server: s.URL + "/v2", Token: "sometoken", Timeout: time.Second})
+	g.Expect(err).To(BeNil())
+	g.Expect(c2).To(BeIdenticalTo(c1))
+	c3, err := executeConnector(context.Background(), ConnectionConfig{Server: s.URL + "/v2", Token: "othertoken"})
+	g.Expect(err).To(BeNil())
+	g.Expect(c3).NotTo(BeIdenticalTo(c1))
+}
+
+func TestExecuteTimeout(t *testing.T) {
+	g := NewWithT(t)
+
+	release := make(chan struct{})
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer s.Close()
+	defer close(release)
+
+	_, err := Execute(context.Background(), ConnectionConfig{Server: s.URL + "/v2", Token: "sometoken", Timeout: 50 * time.Millisecond}, "LIST ORGANIZATIONS;")
+	g.Expect(err).To(MatchError(context.DeadlineExceeded))
+}
+
+func TestExecuteNoToken(t *testing.T) {
+	g := NewWithT(t)
+
+	_, err := Execute(context.Background(), ConnectionConfig{}, "LIST ORGANIZATIONS;")
+	g.Expect(err).To(MatchError("no api token provided"))
+}
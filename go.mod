@@ -10,6 +10,7 @@ require (
 	github.com/onsi/gomega v1.30.0
 	github.com/pkg/errors v0.9.1
 	golang.org/x/oauth2 v0.17.0
+	google.golang.org/protobuf v1.31.0
 	k8s.io/utils v0.0.0-20240102154912-e7106e64919e
 )
 
@@ -26,7 +27,6 @@ require (
 	golang.org/x/net v0.33.0 // indirect
 	golang.org/x/text v0.21.0 // indirect
 	google.golang.org/appengine v1.6.7 // indirect
-	google.golang.org/protobuf v1.31.0 // indirect
 	gopkg.in/check.v1 v1.0.0-20201130134442-10cb98267c6c // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
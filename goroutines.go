@@ -0,0 +1,86 @@
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package godeltastream
+
+import (
+	"sort"
+	"sync"
+)
+
+// goroutineGroup tracks the background goroutines of a connection, e.g. the readers of streaming results, so that
+// they are all stopped and awaited when the connection is closed.
+type goroutineGroup struct {
+	mu      sync.Mutex
+	wg      sync.WaitGroup
+	nextID  uint64
+	running map[uint64]trackedGoroutine
+}
+
+type trackedGoroutine struct {
+	name string
+	stop func()
+}
+
+// Go runs f in a new goroutine named name. stop must make f return, it is called when the group is closed.
+func (g *goroutineGroup) Go(name string, stop func(), f func()) {
+	g.mu.Lock()
+	if g.running == nil {
+		g.running = map[uint64]trackedGoroutine{}
+	}
+	id := g.nextID
+	g.nextID++
+	g.running[id] = trackedGoroutine{name: name, stop: stop}
+	g.wg.Add(1)
+	g.mu.Unlock()
+
+	go func() {
+		defer g.wg.Done()
+		defer func() {
+			g.mu.Lock()
+			delete(g.running, id)
+			g.mu.Unlock()
+		}()
+		f()
+	}()
+}
+
+// Active returns the sorted names of the goroutines that are still running.
+func (g *goroutineGroup) Active() []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	names := make([]string, 0, len(g.running))
+	for _, r := range g.running {
+		names = append(names, r.name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Close stops all running goroutines and waits for them to return.
+func (g *goroutineGroup) Close() {
+	g.mu.Lock()
+	stops := make([]func(), 0, len(g.running))
+	for _, r := range g.running {
+		stops = append(stops, r.stop)
+	}
+	g.mu.Unlock()
+
+	for _, stop := range stops {
+		stop()
+	}
+	g.wg.Wait()
+}
@@ -0,0 +1,110 @@
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package godeltastream
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/jarcoal/httpmock"
+	. "github.com/onsi/gomega"
+
+	"github.com/deltastreaminc/go-deltastream/dstest"
+)
+
+func TestActiveGoroutines(t *testing.T) {
+	g := NewWithT(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	endless := func(conn *websocket.Conn) error {
+		for i := 0; ; i++ {
+			if err := dstest.Row(fmt.Sprint(i), "n")(conn); err != nil {
+				return err
+			}
+		}
+	}
+	server := dstest.NewStreamingServer(dstest.Metadata(streamingColumns...), endless)
+	defer server.Close()
+
+	statement := server.StatementResponse(nil)
+	httpmock.RegisterResponder("POST", "https://api.deltastream.io/v2/statements", httpmock.NewJsonResponderOrPanic(200, statement))
+
+	connector, err := ConnectorWithOptions(context.TODO(), WithServer("https://api.deltastream.io/v2"), WithStaticToken("sometoken"))
+	g.Expect(err).To(BeNil())
+	dconn, err := connector.Connect(context.Background())
+	g.Expect(err).To(BeNil())
+	conn := dconn.(*Conn)
+
+	// closing rows stops their reader
+	rows, err := conn.QueryContext(context.Background(), "SELECT * FROM pageviews;", nil)
+	g.Expect(err).To(BeNil())
+	g.Expect(conn.ActiveGoroutines()).To(Equal([]string{"streaming rows " + statement.StatementID.String()}))
+	g.Expect(rows.Close()).To(Succeed())
+	g.Expect(conn.ActiveGoroutines()).To(BeEmpty())
+
+	// closing the connection stops the readers of rows still open
+	rows1, err := conn.QueryContext(context.Background(), "SELECT * FROM pageviews;", nil)
+	g.Expect(err).To(BeNil())
+	rows2, err := conn.QueryContext(context.Background(), "SELECT * FROM pageviews;", nil)
+	g.Expect(err).To(BeNil())
+	g.Expect(conn.ActiveGoroutines()).To(HaveLen(2))
+	g.Expect(conn.Close()).To(Succeed())
+	g.Expect(conn.ActiveGoroutines()).To(BeEmpty())
+
+	dest := make([]driver.Value, 2)
+	for _, r := range []driver.Rows{rows1, rows2} {
+		g.Expect(r.Next(dest)).To(Equal(io.EOF))
+		g.Expect(r.Close()).To(Succeed())
+	}
+	g.Eventually(server.CloseCodes).Should(HaveLen(3))
+}
+
+func TestActiveGoroutinesDecodeWorkers(t *testing.T) {
+	g := NewWithT(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	server := dstest.NewStreamingServer(dstest.Metadata(streamingColumns...), dstest.Row("1", "a"))
+	defer server.Close()
+
+	statement := server.StatementResponse(nil)
+	httpmock.RegisterResponder("POST", "https://api.deltastream.io/v2/statements", httpmock.NewJsonResponderOrPanic(200, statement))
+
+	connector, err := ConnectorWithOptions(context.TODO(), WithServer("https://api.deltastream.io/v2"), WithStaticToken("sometoken"), WithStreamDecodeWorkers(2))
+	g.Expect(err).To(BeNil())
+	dconn, err := connector.Connect(context.Background())
+	g.Expect(err).To(BeNil())
+	conn := dconn.(*Conn)
+
+	_, err = conn.QueryContext(context.Background(), "SELECT * FROM pageviews;", nil)
+	g.Expect(err).To(BeNil())
+	id := statement.StatementID.String()
+	// the goroutines of the decode pool are tracked as well
+	g.Expect(conn.ActiveGoroutines()).To(ConsistOf(
+		"streaming decode reader "+id,
+		"streaming decode worker "+id,
+		"streaming decode worker "+id,
+		"streaming rows "+id,
+	))
+	g.Expect(conn.Close()).To(Succeed())
+	g.Expect(conn.ActiveGoroutines()).To(BeEmpty())
+}
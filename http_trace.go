@@ -0,0 +1,142 @@
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package godeltastream
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// HTTPTiming is the timing of a request sent to the control plane or a dataplane. Durations are measured from Start and
+// are zero for phases that did not occur, e.g. DNSLookup and Connect when an idle connection was reused.
+type HTTPTiming struct {
+	Method string
+	URL    string
+	Start  time.Time
+	// DNSLookup is the time spent resolving the host name.
+	DNSLookup time.Duration
+	// Connect is the time spent establishing the TCP connection.
+	Connect time.Duration
+	// TLSHandshake is the time spent on the TLS handshake.
+	TLSHandshake time.Duration
+	// TimeToFirstByte is the time from Start until the first byte of the response was received, which includes the time
+	// the server took to process the request.
+	TimeToFirstByte time.Duration
+	// Duration is the time from Start until the response headers were received or the request failed.
+	Duration time.Duration
+	// ReusedConn reports whether the request was sent on a previously used connection.
+	ReusedConn bool
+	Err        error
+}
+
+// HTTPTrace records the timings of the requests of the statements executed with a context returned by WithHTTPTrace,
+// to tell network latency from server latency. Websocket handshakes of streaming results are not recorded. An
+// HTTPTrace can be shared by concurrent statements.
+type HTTPTrace struct {
+	mu      sync.Mutex
+	timings []HTTPTiming
+}
+
+// Timings returns the recorded timings, in the order the requests were sent.
+func (t *HTTPTrace) Timings() []HTTPTiming {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]HTTPTiming(nil), t.timings...)
+}
+
+// Reset discards the recorded timings.
+func (t *HTTPTrace) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.timings = nil
+}
+
+// do sends req with send, tracing its phases with an httptrace.ClientTrace, and records the timing.
+func (t *HTTPTrace) do(send func(*http.Request) (*http.Response, error), req *http.Request) (*http.Response, error) {
+	var (
+		mu                               sync.Mutex // hooks may be called concurrently, e.g. while dialing several addresses
+		timing                           = HTTPTiming{Method: req.Method, URL: req.URL.String(), Start: time.Now()}
+		dnsStart, connectStart, tlsStart time.Time
+	)
+	since := func(start time.Time) time.Duration {
+		if start.IsZero() {
+			return 0
+		}
+		return time.Since(start)
+	}
+	trace := &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			mu.Lock()
+			defer mu.Unlock()
+			dnsStart = time.Now()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			mu.Lock()
+			defer mu.Unlock()
+			timing.DNSLookup = since(dnsStart)
+		},
+		ConnectStart: func(string, string) {
+			mu.Lock()
+			defer mu.Unlock()
+			if connectStart.IsZero() {
+				connectStart = time.Now()
+			}
+		},
+		ConnectDone: func(_, _ string, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			if err == nil && timing.Connect == 0 {
+				timing.Connect = since(connectStart)
+			}
+		},
+		TLSHandshakeStart: func() {
+			mu.Lock()
+			defer mu.Unlock()
+			tlsStart = time.Now()
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			timing.TLSHandshake = since(tlsStart)
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			mu.Lock()
+			defer mu.Unlock()
+			timing.ReusedConn = info.Reused
+		},
+		GotFirstResponseByte: func() {
+			mu.Lock()
+			defer mu.Unlock()
+			timing.TimeToFirstByte = time.Since(timing.Start)
+		},
+	}
+
+	rsp, err := send(req.WithContext(httptrace.WithClientTrace(req.Context(), trace)))
+	mu.Lock()
+	timing.Duration = time.Since(timing.Start)
+	timing.Err = err
+	recorded := timing
+	mu.Unlock()
+
+	t.mu.Lock()
+	t.timings = append(t.timings, recorded)
+	t.mu.Unlock()
+	return rsp, err
+}
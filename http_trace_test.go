@@ -0,0 +1,86 @@
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package godeltastream
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestHTTPTrace(t *testing.T) {
+	g := NewWithT(t)
+
+	result, err := os.ReadFile("fixtures/list-organizations-200-00000-1.json")
+	g.Expect(err).To(BeNil())
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(result)
+	}))
+	defer server.Close()
+
+	connector, err := ConnectorWithOptions(context.TODO(), WithServer(server.URL+"/v2"), WithStaticToken("sometoken"), WithHTTPClient(server.Client()))
+	g.Expect(err).To(BeNil())
+	db := sql.OpenDB(connector)
+	defer db.Close()
+
+	trace := &HTTPTrace{}
+	ctx := WithHTTPTrace(context.Background(), trace)
+	_, err = db.ExecContext(ctx, "LIST ORGANIZATIONS;")
+	g.Expect(err).To(BeNil())
+	_, err = db.ExecContext(ctx, "LIST ORGANIZATIONS;")
+	g.Expect(err).To(BeNil())
+
+	timings := trace.Timings()
+	g.Expect(timings).To(HaveLen(2))
+	first := timings[0]
+	g.Expect(first.Method).To(Equal("POST"))
+	g.Expect(first.URL).To(Equal(server.URL + "/v2/statements"))
+	g.Expect(first.Start).ToNot(BeZero())
+	g.Expect(first.ReusedConn).To(BeFalse())
+	g.Expect(first.Connect).To(BeNumerically(">", 0))
+	g.Expect(first.TLSHandshake).To(BeNumerically(">", 0))
+	g.Expect(first.TimeToFirstByte).To(BeNumerically(">=", first.Connect))
+	g.Expect(first.Duration).To(BeNumerically(">=", first.TimeToFirstByte))
+	g.Expect(first.Err).To(BeNil())
+
+	// the connection is reused by the second statement
+	g.Expect(timings[1].ReusedConn).To(BeTrue())
+	g.Expect(timings[1].Connect).To(BeZero())
+	g.Expect(timings[1].TLSHandshake).To(BeZero())
+	g.Expect(timings[1].TimeToFirstByte).To(BeNumerically(">", 0))
+
+	// statements executed without the context are not recorded
+	_, err = db.Exec("LIST ORGANIZATIONS;")
+	g.Expect(err).To(BeNil())
+	g.Expect(trace.Timings()).To(HaveLen(2))
+	trace.Reset()
+	g.Expect(trace.Timings()).To(BeEmpty())
+
+	// failed requests are recorded with their error
+	server.Close()
+	_, err = db.ExecContext(ctx, "LIST ORGANIZATIONS;")
+	g.Expect(err).ToNot(BeNil())
+	timings = trace.Timings()
+	g.Expect(timings).ToNot(BeEmpty())
+	g.Expect(timings[len(timings)-1].Err).ToNot(BeNil())
+}
@@ -0,0 +1,96 @@
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package godeltastream
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+)
+
+// The iterators below have the shape of iter.Seq2 without depending on the iter package, which requires go 1.23, so
+// that modules targeting go 1.23 or later can range over them while this module supports older versions:
+//
+//	for row, err := range godeltastream.RowsIter(rows) {
+//		...
+//	}
+
+// RowsIter returns an iterator over the rows, yielding the values of each row or the error ending the rows, after which
+// the iteration stops. The rows are closed when the iteration ends, including when the loop exits early, e.g. on break
+// or return. Each row is a new slice that may be retained.
+func RowsIter(rows *sql.Rows) func(yield func([]driver.Value, error) bool) {
+	return ScanIter(rows, func(rows *sql.Rows) ([]driver.Value, error) {
+		columns, err := rows.Columns()
+		if err != nil {
+			return nil, err
+		}
+		values := make([]any, len(columns))
+		dest := make([]any, len(columns))
+		for i := range dest {
+			dest[i] = &values[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		row := make([]driver.Value, len(values))
+		for i, v := range values {
+			row[i] = v
+		}
+		return row, nil
+	})
+}
+
+// ScanIter returns an iterator over the rows, yielding the values scan returns for each row, e.g. structs. The iteration
+// stops after the first error, of scan or of the rows. The rows are closed when the iteration ends, including when the
+// loop exits early.
+func ScanIter[T any](rows *sql.Rows, scan func(*sql.Rows) (T, error)) func(yield func(T, error) bool) {
+	return func(yield func(T, error) bool) {
+		defer rows.Close()
+		var zero T
+		for rows.Next() {
+			v, err := scan(rows)
+			if err != nil {
+				yield(zero, err)
+				return
+			}
+			if !yield(v, nil) {
+				return
+			}
+		}
+		if err := rows.Err(); err != nil {
+			yield(zero, err)
+			return
+		}
+		if err := rows.Close(); err != nil {
+			yield(zero, err)
+		}
+	}
+}
+
+// QueryIter returns an iterator running query on db when the iteration starts and yielding the values scan returns for
+// each row, see ScanIter. An error running the query is yielded once.
+func QueryIter[T any](ctx context.Context, db Querier, scan func(*sql.Rows) (T, error), query string, args ...any) func(yield func(T, error) bool) {
+	return func(yield func(T, error) bool) {
+		rows, err := db.QueryContext(ctx, query, args...)
+		if err != nil {
+			var zero T
+			yield(zero, err)
+			return
+		}
+		ScanIter(rows, scan)(yield)
+	}
+}
@@ -0,0 +1,114 @@
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package godeltastream
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	. "github.com/onsi/gomega"
+)
+
+func TestRowsIter(t *testing.T) {
+	g := NewWithT(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("POST", "https://api.deltastream.io/v2/statements", compareResponder(compareResultSet(
+		[]string{"id", "note"}, `["1", "a"]`, `["2", null]`, `["3", "c"]`,
+	)))
+	db := compareDB(g, "https://api.deltastream.io/v2")
+	defer db.Close()
+
+	rows, err := db.QueryContext(context.TODO(), "SELECT * FROM notes;")
+	g.Expect(err).To(BeNil())
+	var read [][]driver.Value
+	RowsIter(rows)(func(row []driver.Value, err error) bool {
+		g.Expect(err).To(BeNil())
+		read = append(read, row)
+		return true
+	})
+	g.Expect(read).To(Equal([][]driver.Value{{int64(1), "a"}, {int64(2), nil}, {int64(3), "c"}}))
+	g.Expect(db.Stats().InUse).To(Equal(0))
+
+	// leaving the loop early closes the rows
+	rows, err = db.QueryContext(context.TODO(), "SELECT * FROM notes;")
+	g.Expect(err).To(BeNil())
+	read = nil
+	RowsIter(rows)(func(row []driver.Value, err error) bool {
+		read = append(read, row)
+		return false
+	})
+	g.Expect(read).To(HaveLen(1))
+	g.Expect(db.Stats().InUse).To(Equal(0))
+}
+
+func TestQueryIter(t *testing.T) {
+	g := NewWithT(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("POST", "https://api.deltastream.io/v2/statements", compareResponder(compareResultSet(
+		[]string{"id", "note"}, `["1", "a"]`, `["2", null]`,
+	)))
+	db := compareDB(g, "https://api.deltastream.io/v2")
+	defer db.Close()
+
+	type note struct {
+		id   int64
+		note sql.NullString
+	}
+	scanNote := func(rows *sql.Rows) (note, error) {
+		var n note
+		err := rows.Scan(&n.id, &n.note)
+		return n, err
+	}
+
+	var notes []note
+	QueryIter(context.TODO(), db, scanNote, "SELECT * FROM notes;")(func(n note, err error) bool {
+		g.Expect(err).To(BeNil())
+		notes = append(notes, n)
+		return true
+	})
+	g.Expect(notes).To(Equal([]note{{id: 1, note: sql.NullString{String: "a", Valid: true}}, {id: 2}}))
+	g.Expect(db.Stats().InUse).To(Equal(0))
+
+	// scan errors end the iteration
+	errScan := errors.New("scan failed")
+	var errs []error
+	QueryIter(context.TODO(), db, func(*sql.Rows) (note, error) { return note{}, errScan }, "SELECT * FROM notes;")(func(n note, err error) bool {
+		errs = append(errs, err)
+		return true
+	})
+	g.Expect(errs).To(Equal([]error{errScan}))
+	g.Expect(db.Stats().InUse).To(Equal(0))
+
+	// query errors are yielded once
+	httpmock.RegisterResponder("POST", "https://api.deltastream.io/v2/statements", httpmock.NewStringResponder(http.StatusUnauthorized, `{"message": "no token"}`))
+	errs = nil
+	QueryIter(context.TODO(), db, scanNote, "SELECT * FROM notes;")(func(n note, err error) bool {
+		errs = append(errs, err)
+		return true
+	})
+	g.Expect(errs).To(HaveLen(1))
+	g.Expect(errs[0]).To(HaveOccurred())
+}
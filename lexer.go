@@ -0,0 +1,81 @@
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package godeltastream
+
+import "strings"
+
+type sqlSegmentKind int
+
+const (
+	// sqlSegmentCode is statement text outside of quotes and comments.
+	sqlSegmentCode sqlSegmentKind = iota
+	// sqlSegmentQuoted is a string literal or quoted identifier, including its quotes.
+	sqlSegmentQuoted
+	// sqlSegmentComment is a -- or /* */ comment. The newline ending a -- comment is not part of it.
+	sqlSegmentComment
+)
+
+// sqlSegment is the text of a query at query[start:end].
+type sqlSegment struct {
+	kind       sqlSegmentKind
+	start, end int
+}
+
+// lexSQL splits query into code, quoted and comment segments, so that semicolons, placeholders and the like are only
+// looked for in code. Quotes are escaped by doubling them, which reads as two consecutive quoted segments. Unterminated
+// quotes and comments run to the end of query.
+func lexSQL(query string) []sqlSegment {
+	var segments []sqlSegment
+	code := 0 // start of the current code segment
+	for i := 0; i < len(query); {
+		var (
+			kind sqlSegmentKind
+			end  int
+		)
+		switch c := query[i]; {
+		case c == '\'' || c == '"' || c == '`':
+			kind, end = sqlSegmentQuoted, indexAfter(query, i+1, string(c))
+		case strings.HasPrefix(query[i:], "--"):
+			kind, end = sqlSegmentComment, len(query)
+			if n := strings.IndexByte(query[i:], '\n'); n >= 0 {
+				end = i + n
+			}
+		case strings.HasPrefix(query[i:], "/*"):
+			kind, end = sqlSegmentComment, indexAfter(query, i+2, "*/")
+		default:
+			i++
+			continue
+		}
+		if i > code {
+			segments = append(segments, sqlSegment{kind: sqlSegmentCode, start: code, end: i})
+		}
+		segments = append(segments, sqlSegment{kind: kind, start: i, end: end})
+		i, code = end, end
+	}
+	if len(query) > code {
+		segments = append(segments, sqlSegment{kind: sqlSegmentCode, start: code, end: len(query)})
+	}
+	return segments
+}
+
+// indexAfter returns the index following the first occurrence of sep in query[from:], or len(query) if there is none.
+func indexAfter(query string, from int, sep string) int {
+	if i := strings.Index(query[from:], sep); i >= 0 {
+		return from + i + len(sep)
+	}
+	return len(query)
+}
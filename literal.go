@@ -0,0 +1,234 @@
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package godeltastream
+
+import (
+	"database/sql/driver"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Compile time validation that our types implement the expected interfaces
+var (
+	_ driver.Valuer = Literal("")
+)
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	timeOfDayType = reflect.TypeOf(TimeOfDay{})
+	valuerType    = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+)
+
+// Literal is a value encoded in the SQL literal syntax of DeltaStream, e.g. ARRAY[1, 2] or MAP['k', 'v'], ready to be
+// used in statements such as INSERT INTO ... VALUES.
+type Literal string
+
+// String returns the literal as it appears in a statement.
+func (l Literal) String() string {
+	return string(l)
+}
+
+// Value implements driver.Valuer.
+func (l Literal) Value() (driver.Value, error) {
+	return string(l), nil
+}
+
+// FormatLiteral encodes v in the SQL literal syntax of DeltaStream:
+//   - nil and nil pointers, slices and maps are NULL
+//   - strings are single quoted, with quotes doubled
+//   - []byte are hexadecimal X'...' literals
+//   - time.Time are TIMESTAMP literals in UTC and TimeOfDay are TIME literals
+//   - slices and arrays are ARRAY[...] literals
+//   - maps are MAP[key, value, ...] literals, sorted by key
+//   - structs are ROW(...) literals of their exported fields, in declaration order; fields tagged `deltastream:"-"`
+//     are skipped
+//
+// Values nest, e.g. a map of slices is a MAP of ARRAY literals. These are the types decoded from ARRAY, MAP and STRUCT
+// columns as strings.
+func FormatLiteral(v any) (Literal, error) {
+	var b strings.Builder
+	if err := writeLiteral(&b, reflect.ValueOf(v)); err != nil {
+		return "", err
+	}
+	return Literal(b.String()), nil
+}
+
+// QuoteString returns s as a single quoted SQL string literal.
+func QuoteString(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+func writeLiteral(b *strings.Builder, v reflect.Value) error {
+	if !v.IsValid() {
+		b.WriteString("NULL")
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			b.WriteString("NULL")
+			return nil
+		}
+	}
+	switch t := v.Interface().(type) {
+	case Literal:
+		b.WriteString(string(t))
+		return nil
+	case time.Time:
+		b.WriteString("TIMESTAMP " + QuoteString(t.UTC().Format("2006-01-02 15:04:05.999999999")))
+		return nil
+	case TimeOfDay:
+		b.WriteString("TIME " + QuoteString(t.String()))
+		return nil
+	case Decimal:
+		b.WriteString(t.String())
+		return nil
+	case []byte:
+		if t == nil {
+			b.WriteString("NULL")
+			return nil
+		}
+		b.WriteString("X'" + hex.EncodeToString(t) + "'")
+		return nil
+	case driver.Valuer:
+		dv, err := t.Value()
+		if err != nil {
+			return err
+		}
+		return writeLiteral(b, reflect.ValueOf(dv))
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		return writeLiteral(b, v.Elem())
+	case reflect.String:
+		b.WriteString(QuoteString(v.String()))
+	case reflect.Bool:
+		if v.Bool() {
+			b.WriteString("TRUE")
+		} else {
+			b.WriteString("FALSE")
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		b.WriteString(strconv.FormatInt(v.Int(), 10))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		b.WriteString(strconv.FormatUint(v.Uint(), 10))
+	case reflect.Float32, reflect.Float64:
+		f := v.Float()
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return &ErrClientError{message: fmt.Sprintf("cannot encode %v as a literal", f)}
+		}
+		b.WriteString(strconv.FormatFloat(f, 'g', -1, v.Type().Bits()))
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			b.WriteString("NULL")
+			return nil
+		}
+		b.WriteString("ARRAY[")
+		for i := 0; i < v.Len(); i++ {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			if err := writeLiteral(b, v.Index(i)); err != nil {
+				return err
+			}
+		}
+		b.WriteString("]")
+	case reflect.Map:
+		if v.IsNil() {
+			b.WriteString("NULL")
+			return nil
+		}
+		return writeMapLiteral(b, v)
+	case reflect.Struct:
+		b.WriteString("ROW(")
+		first := true
+		for i := 0; i < v.NumField(); i++ {
+			f := v.Type().Field(i)
+			if !f.IsExported() || f.Tag.Get("deltastream") == "-" {
+				continue
+			}
+			if !first {
+				b.WriteString(", ")
+			}
+			first = false
+			if err := writeLiteral(b, v.Field(i)); err != nil {
+				return err
+			}
+		}
+		b.WriteString(")")
+	default:
+		return &ErrClientError{message: fmt.Sprintf("cannot encode value of type %s as a literal", v.Type())}
+	}
+	return nil
+}
+
+// writeMapLiteral writes the entries of a map sorted by their encoded key, so statements are deterministic.
+func writeMapLiteral(b *strings.Builder, v reflect.Value) error {
+	type entry struct{ key, value string }
+	entries := make([]entry, 0, v.Len())
+	iter := v.MapRange()
+	for iter.Next() {
+		var kb, vb strings.Builder
+		if err := writeLiteral(&kb, iter.Key()); err != nil {
+			return err
+		}
+		if err := writeLiteral(&vb, iter.Value()); err != nil {
+			return err
+		}
+		entries = append(entries, entry{key: kb.String(), value: vb.String()})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].key < entries[j].key })
+
+	b.WriteString("MAP[")
+	for i, e := range entries {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(e.key + ", " + e.value)
+	}
+	b.WriteString("]")
+	return nil
+}
+
+// isCompositeValue reports whether v is a slice, array, map or struct that the default conversion of database/sql
+// rejects, and that can be encoded as a literal instead.
+func isCompositeValue(v any) bool {
+	t := reflect.TypeOf(v)
+	if t == nil || t.Implements(valuerType) {
+		return false
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		return t.Elem().Kind() != reflect.Uint8
+	case reflect.Map:
+		return true
+	case reflect.Struct:
+		return t != timeType && t != timeOfDayType
+	}
+	return false
+}
@@ -0,0 +1,91 @@
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package godeltastream
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// WithOrganization runs the statements of connections in the organization org, its name or id, instead of the default
+// organization of the token. Connect looks up the organizations the token has access to and returns an
+// *ErrOrganizationAccess if org is not one of them, rather than failing later statements with opaque errors.
+func WithOrganization(org string) func(*connectionOptions) {
+	return func(o *connectionOptions) {
+		o.organization = &org
+	}
+}
+
+// ErrOrganizationAccess is returned by Connect when the token has no access to the organization set with
+// WithOrganization.
+type ErrOrganizationAccess struct {
+	// Organization is the name or id of the organization set with WithOrganization.
+	Organization string
+	// AccessibleIDs are the ids of the organizations the token has access to.
+	AccessibleIDs []string
+}
+
+func (e *ErrOrganizationAccess) Error() string {
+	if len(e.AccessibleIDs) == 0 {
+		return fmt.Sprintf("no access to organization %s, the token has access to no organization", e.Organization)
+	}
+	return fmt.Sprintf("no access to organization %s, the token has access to organizations %s", e.Organization, strings.Join(e.AccessibleIDs, ", "))
+}
+
+// useOrganization sets the organization of the context of the connection to org, once verified that the token has
+// access to it.
+func (c *Conn) useOrganization(ctx context.Context, org string) error {
+	rs, err := c.submitStatement(ctx, nil, "LIST ORGANIZATIONS;")
+	if err != nil {
+		return err
+	}
+	rows, err := c.queryRows(ctx, rs)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	accessErr := &ErrOrganizationAccess{Organization: org}
+	view := NewRowView(rows)
+	for {
+		if err := view.Next(); err == io.EOF {
+			return accessErr
+		} else if err != nil {
+			return err
+		}
+		id, ok := view.String("id")
+		if !ok {
+			return &ErrInterfaceError{message: "organizations have no id column"}
+		}
+		name, _ := view.String("name")
+		if strings.EqualFold(id, org) || name == org {
+			orgID, err := uuid.Parse(id)
+			if err != nil {
+				return &ErrInterfaceError{message: fmt.Sprintf("invalid organization id %s", id)}
+			}
+			rsctx := c.GetContext()
+			rsctx.OrganizationID = &orgID
+			c.SetContext(rsctx)
+			return nil
+		}
+		accessErr.AccessibleIDs = append(accessErr.AccessibleIDs, id)
+	}
+}
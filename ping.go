@@ -0,0 +1,60 @@
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package godeltastream
+
+import (
+	"sync"
+	"time"
+
+	"github.com/deltastreaminc/go-deltastream/apiv2"
+)
+
+// WithPingCacheTTL answers Ping from the last successful version request to the control plane for ttl, instead of
+// sending a request on every Ping, e.g. for frameworks checking the health of pooled connections frequently. The cache
+// is shared by the connections of a connector. Failed pings are not cached.
+func WithPingCacheTTL(ttl time.Duration) func(*connectionOptions) {
+	return func(o *connectionOptions) {
+		o.pingCache = &pingCache{ttl: ttl, succeeded: map[*apiv2.ClientWithResponses]time.Time{}}
+	}
+}
+
+// pingCache holds when pings last succeeded, per control plane client.
+type pingCache struct {
+	ttl       time.Duration
+	mu        sync.Mutex
+	succeeded map[*apiv2.ClientWithResponses]time.Time
+}
+
+// fresh returns whether a ping using client succeeded less than ttl ago.
+func (p *pingCache) fresh(client *apiv2.ClientWithResponses) bool {
+	if p == nil {
+		return false
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	t, ok := p.succeeded[client]
+	return ok && time.Since(t) < p.ttl
+}
+
+func (p *pingCache) success(client *apiv2.ClientWithResponses) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.succeeded[client] = time.Now()
+}
@@ -0,0 +1,83 @@
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package godeltastream
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// WithPinnedCertificates restricts the dataplanes the driver sends dataplane tokens to, over HTTPS and WSS, to those
+// presenting a certificate whose SHA-256 fingerprint is one of fingerprints. Fingerprints are the hex encoded digests
+// of DER certificates, with or without colons, e.g. as printed by openssl x509 -fingerprint -sha256. Any certificate of
+// the chain presented by the dataplane may be pinned, e.g. that of an intermediate authority. Connections to other
+// dataplanes fail with an *ErrCertificatePinning, in addition to the usual certificate verification.
+func WithPinnedCertificates(fingerprints ...string) func(*connectionOptions) {
+	return func(o *connectionOptions) {
+		o.pinnedCertificates = append(o.pinnedCertificates, fingerprints...)
+	}
+}
+
+// ErrCertificatePinning is returned when a dataplane presents no certificate pinned with WithPinnedCertificates.
+type ErrCertificatePinning struct {
+	// ServerName is the host name the connection was made to.
+	ServerName string
+	// Fingerprints are the SHA-256 fingerprints of the certificates presented by the server.
+	Fingerprints []string
+}
+
+func (e *ErrCertificatePinning) Error() string {
+	return fmt.Sprintf("no pinned certificate presented by %s, fingerprints: %s", e.ServerName, strings.Join(e.Fingerprints, ", "))
+}
+
+// parsePinnedCertificates returns the set of the normalized fingerprints.
+func parsePinnedCertificates(fingerprints []string) (map[string]bool, error) {
+	pins := make(map[string]bool, len(fingerprints))
+	for _, f := range fingerprints {
+		normalized := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(f), ":", ""))
+		if b, err := hex.DecodeString(normalized); err != nil || len(b) != sha256.Size {
+			return nil, &ErrClientError{message: fmt.Sprintf("invalid sha256 certificate fingerprint %q", f)}
+		}
+		pins[normalized] = true
+	}
+	return pins, nil
+}
+
+// pinCertificates makes config reject connections presenting no pinned certificate, after the verification config
+// already performs.
+func pinCertificates(config *tls.Config, pins map[string]bool) {
+	verify := config.VerifyConnection
+	config.VerifyConnection = func(cs tls.ConnectionState) error {
+		if verify != nil {
+			if err := verify(cs); err != nil {
+				return err
+			}
+		}
+		presented := make([]string, len(cs.PeerCertificates))
+		for i, cert := range cs.PeerCertificates {
+			sum := sha256.Sum256(cert.Raw)
+			presented[i] = hex.EncodeToString(sum[:])
+			if pins[presented[i]] {
+				return nil
+			}
+		}
+		return &ErrCertificatePinning{ServerName: cs.ServerName, Fingerprints: presented}
+	}
+}
@@ -0,0 +1,120 @@
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package godeltastream
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	. "github.com/onsi/gomega"
+
+	"github.com/deltastreaminc/go-deltastream/apiv2"
+	"github.com/deltastreaminc/go-deltastream/dstest"
+)
+
+func TestPinnedCertificates(t *testing.T) {
+	g := NewWithT(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	// the dataplane streams over WSS and serves result sets over HTTPS
+	stream := dstest.NewStreamingServer(dstest.Metadata(streamingColumns...), dstest.Row("1", "a"))
+	defer stream.Close()
+	mux := http.NewServeMux()
+	mux.Handle("/", stream.Config.Handler)
+	mux.HandleFunc("/v2/statements/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		http.ServeFile(w, r, "fixtures/list-organizations-200-00000-1.json")
+	})
+	dataplane := httptest.NewUnstartedServer(mux)
+	dataplane.Config.ErrorLog = log.New(io.Discard, "", 0)
+	dataplane.StartTLS()
+	defer dataplane.Close()
+
+	streaming := stream.StatementResponse(nil)
+	streaming.Metadata.DataplaneRequest.Uri = dataplane.URL
+	b, err := os.ReadFile("fixtures/dataplane-query-200-00000-0.json")
+	g.Expect(err).To(BeNil())
+	resultSet := &apiv2.ResultSet{}
+	g.Expect(json.Unmarshal(b, resultSet)).To(Succeed())
+	resultSet.Metadata.DataplaneRequest.Uri = dataplane.URL + "/v2/statements"
+	httpmock.RegisterResponder("POST", "https://api.deltastream.io/v2/statements", func(r *http.Request) (*http.Response, error) {
+		body, _ := io.ReadAll(r.Body)
+		if strings.Contains(string(body), "pageviews") {
+			return httpmock.NewJsonResponse(http.StatusOK, streaming)
+		}
+		return httpmock.NewJsonResponse(http.StatusOK, resultSet)
+	})
+
+	roots := x509.NewCertPool()
+	roots.AddCert(dataplane.Certificate())
+	sum := sha256.Sum256(dataplane.Certificate().Raw)
+	pinned := strings.ToUpper(fmt.Sprintf("% x", sum[:]))
+	pinned = strings.ReplaceAll(pinned, " ", ":")
+	query := func(fingerprint string, queries ...string) error {
+		connector, err := ConnectorWithOptions(context.TODO(), WithServer("https://api.deltastream.io/v2"), WithStaticToken("sometoken"),
+			WithDataplaneTLSConfig(&tls.Config{RootCAs: roots, ServerName: "example.com"}), WithPinnedCertificates(fingerprint))
+		if err != nil {
+			return err
+		}
+		db := sql.OpenDB(connector)
+		defer db.Close()
+		for _, q := range queries {
+			rows, err := db.Query(q)
+			if err != nil {
+				return err
+			}
+			if !rows.Next() {
+				return fmt.Errorf("no rows: %v", rows.Err())
+			}
+			rows.Close()
+		}
+		return nil
+	}
+
+	g.Expect(query(pinned, "SELECT * FROM pageviews;", "SELECT * FROM mview_table;")).To(Succeed())
+
+	// the certificate is verified before any token is sent, over WSS and HTTPS
+	other := strings.Repeat("ab", sha256.Size)
+	for _, q := range []string{"SELECT * FROM pageviews;", "SELECT * FROM mview_table;"} {
+		err = query(other, q)
+		var pinErr *ErrCertificatePinning
+		g.Expect(errors.As(err, &pinErr)).To(BeTrue(), q)
+		g.Expect(pinErr.ServerName).To(Equal("example.com"))
+		g.Expect(pinErr.Fingerprints).To(Equal([]string{fmt.Sprintf("%x", sum[:])}))
+	}
+	g.Expect(stream.AuthMessages()).To(HaveLen(1))
+
+	err = query("not a fingerprint", "SELECT * FROM pageviews;")
+	var clientErr *ErrClientError
+	g.Expect(errors.As(err, &clientErr)).To(BeTrue())
+	g.Expect(err.Error()).To(ContainSubstring(`invalid sha256 certificate fingerprint "not a fingerprint"`))
+}
@@ -0,0 +1,98 @@
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package godeltastream
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+type dialContextFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// proxyConnectDialer returns a dialer that tunnels connections through the http proxy at proxyURL using CONNECT
+// requests carrying header. Credentials in proxyURL are sent as basic Proxy-Authorization unless header already sets
+// it. It is used for streaming connections, as the websocket dialer does not support custom CONNECT headers.
+func proxyConnectDialer(proxyURL *url.URL, header http.Header, forward dialContextFunc) dialContextFunc {
+	if forward == nil {
+		forward = (&net.Dialer{}).DialContext
+	}
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		proxyAddr := proxyURL.Host
+		if proxyURL.Port() == "" {
+			port := "80"
+			if proxyURL.Scheme == "https" {
+				port = "443"
+			}
+			proxyAddr = net.JoinHostPort(proxyURL.Hostname(), port)
+		}
+
+		conn, err := forward(ctx, network, proxyAddr)
+		if err != nil {
+			return nil, err
+		}
+		if proxyURL.Scheme == "https" {
+			tlsConn := tls.Client(conn, &tls.Config{ServerName: proxyURL.Hostname()})
+			if err = tlsConn.HandshakeContext(ctx); err != nil {
+				_ = conn.Close()
+				return nil, err
+			}
+			conn = tlsConn
+		}
+
+		req := &http.Request{
+			Method: http.MethodConnect,
+			URL:    &url.URL{Opaque: addr},
+			Host:   addr,
+			Header: http.Header{},
+		}
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		if u := proxyURL.User; u != nil && req.Header.Get("Proxy-Authorization") == "" {
+			password, _ := u.Password()
+			req.Header.Set("Proxy-Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(u.Username()+":"+password)))
+		}
+
+		if deadline, ok := ctx.Deadline(); ok {
+			_ = conn.SetDeadline(deadline)
+			defer func() { _ = conn.SetDeadline(time.Time{}) }()
+		}
+		if err = req.Write(conn); err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+		// the proxy does not send data before the client speaks, so the buffered reader does not consume any of it
+		resp, err := http.ReadResponse(bufio.NewReader(conn), req)
+		if err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			_ = conn.Close()
+			return nil, &ErrClientError{message: fmt.Sprintf("proxy refused connection: %s", resp.Status)}
+		}
+		return conn, nil
+	}
+}
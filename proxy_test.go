@@ -0,0 +1,118 @@
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package godeltastream
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/deltastreaminc/go-deltastream/dstest"
+)
+
+// newTestProxy starts an http proxy recording the headers of the requests it forwards and tunnels.
+func newTestProxy(g *WithT) (*httptest.Server, func() []http.Header) {
+	var (
+		mu      sync.Mutex
+		headers []http.Header
+	)
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		headers = append(headers, r.Header.Clone())
+		mu.Unlock()
+
+		if r.Method != http.MethodConnect {
+			r.RequestURI = ""
+			r.Header.Del("Proxy-Authorization")
+			resp, err := http.DefaultTransport.RoundTrip(r)
+			g.Expect(err).To(BeNil())
+			defer resp.Body.Close()
+			for k, v := range resp.Header {
+				w.Header()[k] = v
+			}
+			w.WriteHeader(resp.StatusCode)
+			_, _ = io.Copy(w, resp.Body)
+			return
+		}
+
+		upstream, err := net.Dial("tcp", r.Host)
+		g.Expect(err).To(BeNil())
+		client, _, err := w.(http.Hijacker).Hijack()
+		g.Expect(err).To(BeNil())
+		_, err = client.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n"))
+		g.Expect(err).To(BeNil())
+		go func() {
+			_, _ = io.Copy(upstream, client)
+			_ = upstream.Close()
+		}()
+		_, _ = io.Copy(client, upstream)
+		_ = client.Close()
+	}))
+	return proxy, func() []http.Header {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]http.Header{}, headers...)
+	}
+}
+
+func TestProxy(t *testing.T) {
+	g := NewWithT(t)
+
+	streaming := dstest.NewStreamingServer(dstest.Metadata(dstest.Column{Name: "id", Type: "BIGINT"}), dstest.Row("1"))
+	defer streaming.Close()
+	api := httptest.NewServer(streaming.StatementHandler())
+	defer api.Close()
+	proxy, proxyHeaders := newTestProxy(g)
+	defer proxy.Close()
+
+	proxyURL, err := url.Parse(proxy.URL)
+	g.Expect(err).To(BeNil())
+	proxyURL.User = url.UserPassword("user", "secret")
+
+	connector, err := ConnectorWithOptions(context.TODO(),
+		WithServer(api.URL+"/v2"),
+		WithStaticToken("sometoken"),
+		WithProxy(proxyURL),
+		WithProxyConnectHeader(http.Header{"X-Proxy-Tenant": []string{"deltastream"}}),
+	)
+	g.Expect(err).To(BeNil())
+
+	db := sql.OpenDB(connector)
+	rows, err := db.QueryContext(context.Background(), "SELECT * FROM pageviews;")
+	g.Expect(err).To(BeNil())
+	var id int64
+	g.Expect(rows.Next()).To(BeTrue())
+	g.Expect(rows.Scan(&id)).To(BeNil())
+	g.Expect(id).To(Equal(int64(1)))
+	g.Expect(rows.Close()).To(BeNil())
+
+	headers := proxyHeaders()
+	g.Expect(headers).To(HaveLen(2))
+	// control plane request forwarded by the proxy
+	g.Expect(headers[0].Get("Proxy-Authorization")).To(Equal("Basic dXNlcjpzZWNyZXQ="))
+	// streaming connection tunneled through the proxy
+	g.Expect(headers[1].Get("Proxy-Authorization")).To(Equal("Basic dXNlcjpzZWNyZXQ="))
+	g.Expect(headers[1].Get("X-Proxy-Tenant")).To(Equal("deltastream"))
+}
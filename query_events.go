@@ -0,0 +1,152 @@
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package godeltastream
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// QueryEvent is an entry of the history of a query, delivered by TailQueryEvents.
+type QueryEvent struct {
+	QueryID string
+	// State is the state of the query recorded by the entry, e.g. QueryStateRunning.
+	State string
+	// PreviousState is the state of the previous entry delivered, empty for the first one.
+	PreviousState string
+	// Message is the message recorded by the entry, e.g. the error of an errored query.
+	Message string
+	// Time is when the entry was recorded, zero if the history has no time column.
+	Time time.Time
+	// Values are all the columns of the entry by name, as scanned into any.
+	Values map[string]any
+}
+
+// Errored returns whether the entry records the query failing.
+func (e QueryEvent) Errored() bool {
+	return strings.EqualFold(e.State, QueryStateErrored)
+}
+
+// TailOptions control how TailQueryEvents polls the history of a query.
+type TailOptions struct {
+	// Interval is the delay between polls. Defaults to 5s.
+	Interval time.Duration
+	// Follow keeps polling once the query is errored or terminated, e.g. for queries that are restarted.
+	Follow bool
+}
+
+// TailQueryEvents polls DESCRIBE QUERY HISTORY and calls handler with the entries not delivered before, in the order
+// of the history, so that state transitions and errors are read from structured events rather than parsed from
+// messages. It returns nil once the query is errored or terminated, unless opts.Follow is set, the error of handler if
+// any, or the error of ctx once done.
+func TailQueryEvents(ctx context.Context, db Queryer, queryID string, opts TailOptions, handler func(QueryEvent) error) error {
+	id, err := uuid.Parse(queryID)
+	if err != nil {
+		return &ErrClientError{message: fmt.Sprintf("invalid query id %q", queryID)}
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = 5 * time.Second
+	}
+
+	delivered := map[string]bool{}
+	var state string
+	for {
+		events, err := describeQueryHistory(ctx, db, id)
+		if err != nil {
+			return err
+		}
+		for _, e := range events {
+			key := fmt.Sprint(e.Values)
+			if delivered[key] {
+				continue
+			}
+			delivered[key] = true
+			e.QueryID, e.PreviousState = queryID, state
+			state = e.State
+			if err := handler(e); err != nil {
+				return err
+			}
+		}
+		if !opts.Follow && (strings.EqualFold(state, QueryStateErrored) || strings.EqualFold(state, QueryStateTerminated)) {
+			return nil
+		}
+
+		t := time.NewTimer(opts.Interval)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// describeQueryHistory returns the entries of the history of the query id.
+func describeQueryHistory(ctx context.Context, db Queryer, id uuid.UUID) ([]QueryEvent, error) {
+	rows, err := db.QueryContext(ctx, fmt.Sprintf("DESCRIBE QUERY HISTORY %s;", id))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	hasState := false
+	for _, c := range columns {
+		hasState = hasState || strings.EqualFold(c, "state")
+	}
+	if !hasState {
+		return nil, &ErrInterfaceError{message: "query history has no state column"}
+	}
+
+	var events []QueryEvent
+	for rows.Next() {
+		values := make([]any, len(columns))
+		dest := make([]any, len(columns))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		e := QueryEvent{Values: make(map[string]any, len(columns))}
+		for i, c := range columns {
+			e.Values[c] = values[i]
+			switch v := values[i].(type) {
+			case string:
+				switch strings.ToLower(c) {
+				case "state":
+					e.State = v
+				case "message", "messages", "error":
+					e.Message = v
+				}
+			case time.Time:
+				switch strings.ToLower(c) {
+				case "time", "timestamp", "created_at", "createdat", "updated_at", "updatedat":
+					e.Time = v
+				}
+			}
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
@@ -0,0 +1,152 @@
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package godeltastream
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Queryer is implemented by *sql.DB, *sql.Conn and *sql.Tx.
+type Queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// Query states reported by DESCRIBE QUERY.
+const (
+	QueryStateRunning    = "running"
+	QueryStateErrored    = "errored"
+	QueryStateTerminated = "terminated"
+)
+
+// WaitOptions control how WaitForQueryState polls the state of a query.
+type WaitOptions struct {
+	// Backoff is the delay before polling again, doubled after every poll. Defaults to 1s.
+	Backoff time.Duration
+	// MaxBackoff caps the delay between polls. Defaults to 30s.
+	MaxBackoff time.Duration
+	// Timeout bounds the total wait, in addition to the deadline of the context. No timeout when zero.
+	Timeout time.Duration
+	// Progress is called with the state of the query after every poll.
+	Progress func(state string)
+}
+
+// ErrUnexpectedQueryState is returned by WaitForQueryState when a query reaches a final state it was not waited for,
+// e.g. errored.
+type ErrUnexpectedQueryState struct {
+	QueryID string
+	State   string
+}
+
+func (e *ErrUnexpectedQueryState) Error() string {
+	return fmt.Sprintf("query %s is %s", e.QueryID, e.State)
+}
+
+// WaitForQueryState polls DESCRIBE QUERY until the query reaches one of targetStates (e.g. QueryStateRunning after a
+// CREATE ... AS SELECT) and returns that state. States are compared case insensitively. It fails with
+// *ErrUnexpectedQueryState if the query is errored or terminated instead.
+func WaitForQueryState(ctx context.Context, db Queryer, queryID string, opts WaitOptions, targetStates ...string) (string, error) {
+	if opts.Backoff <= 0 {
+		opts.Backoff = time.Second
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = 30 * time.Second
+	}
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+
+	backoff := opts.Backoff
+	for {
+		state, err := describeQueryState(ctx, db, queryID)
+		if err != nil {
+			return "", err
+		}
+		if opts.Progress != nil {
+			opts.Progress(state)
+		}
+		for _, s := range targetStates {
+			if strings.EqualFold(s, state) {
+				return state, nil
+			}
+		}
+		if strings.EqualFold(state, QueryStateErrored) || strings.EqualFold(state, QueryStateTerminated) {
+			return state, &ErrUnexpectedQueryState{QueryID: queryID, State: state}
+		}
+
+		t := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return state, ctx.Err()
+		case <-t.C:
+		}
+		if backoff *= 2; backoff > opts.MaxBackoff {
+			backoff = opts.MaxBackoff
+		}
+	}
+}
+
+func describeQueryState(ctx context.Context, db Queryer, queryID string) (string, error) {
+	id, err := uuid.Parse(queryID)
+	if err != nil {
+		return "", &ErrClientError{message: fmt.Sprintf("invalid query id %q", queryID)}
+	}
+	rows, err := db.QueryContext(ctx, fmt.Sprintf("DESCRIBE QUERY %s;", id))
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return "", err
+	}
+	stateIdx := -1
+	for i, c := range columns {
+		if strings.EqualFold(c, "state") {
+			stateIdx = i
+		}
+	}
+	if stateIdx < 0 {
+		return "", &ErrInterfaceError{message: "query description has no state column"}
+	}
+
+	if !rows.Next() {
+		if err = rows.Err(); err != nil {
+			return "", err
+		}
+		return "", &ErrClientError{message: fmt.Sprintf("query %s not found", queryID)}
+	}
+	values := make([]any, len(columns))
+	dest := make([]any, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	if err = rows.Scan(dest...); err != nil {
+		return "", err
+	}
+	state, _ := values[stateIdx].(string)
+	return state, nil
+}
@@ -21,6 +21,7 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"mime"
@@ -243,6 +244,71 @@ func TestEmptyResultset(t *testing.T) {
 	g.Expect(rows.Err()).To(BeNil())
 }
 
+func TestMetadataOnlyResultsets(t *testing.T) {
+	g := gomega.NewWithT(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	organizationColumns := []string{"id", "name", "description", "profileImageURI", "createdAt"}
+	for _, tc := range []struct {
+		fixture string
+		columns []string
+		rows    int
+	}{
+		{fixture: "fixtures/list-organizations-200-00000-0.json", columns: organizationColumns},
+		{fixture: "fixtures/list-organizations-200-00000-nodata.json", columns: organizationColumns},
+		{fixture: "fixtures/list-organizations-200-00000-nopartitions.json", columns: organizationColumns},
+		{fixture: "fixtures/list-organizations-200-00000-unpartitioned.json", columns: organizationColumns, rows: 1},
+		{fixture: "fixtures/insert-into-200-00000.json", columns: []string{}},
+	} {
+		// results read the same from the control plane and from the dataplane
+		for _, dataplane := range []bool{false, true} {
+			if dataplane {
+				httpmock.RegisterResponder("POST", "https://api.deltastream.io/v2/statements",
+					mockSubmitStatementsResponser(g, http.StatusOK, "sometoken", "LIST ORGANIZATIONS;", map[string][]byte{}, "fixtures/dataplane-query-200-00000-0.json"),
+				)
+				httpmock.RegisterResponder("GET", "https://dpapi.deltastream.io/v2/statements/d789687d-4e1b-4649-846e-4f10b722f3ad?partitionID=0&timezone=UTC",
+					mockGetStatementResponser(g, http.StatusOK, "dataplanetoken", tc.fixture),
+				)
+			} else {
+				httpmock.RegisterResponder("POST", "https://api.deltastream.io/v2/statements",
+					mockSubmitStatementsResponser(g, http.StatusOK, "sometoken", "LIST ORGANIZATIONS;", map[string][]byte{}, tc.fixture),
+				)
+			}
+			db := compareDB(g, "https://api.deltastream.io/v2")
+
+			rows, err := db.QueryContext(context.TODO(), "LIST ORGANIZATIONS;")
+			g.Expect(err).To(BeNil(), tc.fixture)
+			g.Expect(rows.Columns()).To(Equal(tc.columns), tc.fixture)
+			columnTypes, err := rows.ColumnTypes()
+			g.Expect(err).To(BeNil(), tc.fixture)
+			g.Expect(columnTypes).To(HaveLen(len(tc.columns)), tc.fixture)
+			read := 0
+			for rows.Next() {
+				read++
+			}
+			g.Expect(rows.Err()).To(BeNil(), tc.fixture)
+			g.Expect(read).To(Equal(tc.rows), tc.fixture)
+			g.Expect(rows.Close()).To(Succeed())
+			g.Expect(db.Close()).To(Succeed())
+		}
+	}
+
+	// announced rows missing from the data fail the query rather than Next
+	httpmock.RegisterResponder("POST", "https://api.deltastream.io/v2/statements", compareResponder(`{
+		"sqlState": "00000",
+		"statementID": "d789687d-4e1b-4649-846e-4f10b722f3ad",
+		"createdOn": 1703907114,
+		"metadata": {"encoding": "json", "partitionInfo": [{"rowCount": 2}], "columns": [{"name": "id", "type": "BIGINT", "nullable": false}], "context": {}}
+	}`))
+	db := compareDB(g, "https://api.deltastream.io/v2")
+	defer db.Close()
+	_, err := db.QueryContext(context.TODO(), "LIST ORGANIZATIONS;")
+	var interfaceErr *ErrInterfaceError
+	g.Expect(errors.As(err, &interfaceErr)).To(BeTrue())
+	g.Expect(err.Error()).To(ContainSubstring("partition has 0 rows instead of 2"))
+}
+
 func TestDelayedResultset(t *testing.T) {
 	g := gomega.NewWithT(t)
 	httpmock.Activate()
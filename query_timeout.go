@@ -0,0 +1,63 @@
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package godeltastream
+
+import (
+	"context"
+	"database/sql/driver"
+	"time"
+)
+
+// WithDefaultQueryTimeout applies a timeout of d to the statements executed and queried with a context without a
+// deadline, as if the context had one, so that applications forgetting to set deadlines do not wait forever. As with
+// deadlines set by callers, the timeout covers reading the rows of queries, including streaming queries, which should
+// use WithoutDefaultQueryTimeout when they are meant to run longer.
+func WithDefaultQueryTimeout(d time.Duration) func(*connectionOptions) {
+	return func(o *connectionOptions) {
+		o.defaultQueryTimeout = d
+	}
+}
+
+// withDefaultQueryTimeout returns ctx with the default query timeout of the connection, unless ctx has a deadline or
+// opted out with WithoutDefaultQueryTimeout. The returned cancel func must be called once the statement completes, or
+// its rows are closed, see releaseOnClose.
+func (c *Conn) withDefaultQueryTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.defaultQueryTimeout <= 0 || defaultQueryTimeoutDisabled(ctx) {
+		return ctx, func() {}
+	}
+	if _, ok := ctx.Deadline(); ok {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, c.defaultQueryTimeout)
+}
+
+// releaseOnClose makes the rows of a query call cancel once closed, or calls it right away if the query failed.
+func releaseOnClose(rows driver.Rows, err error, cancel context.CancelFunc) (driver.Rows, error) {
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	switch rows := rows.(type) {
+	case *resultSetRows:
+		rows.release = cancel
+	case *streamingRows:
+		rows.release = cancel
+	default:
+		cancel()
+	}
+	return rows, nil
+}
@@ -0,0 +1,116 @@
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package godeltastream
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+	. "github.com/onsi/gomega"
+
+	"github.com/deltastreaminc/go-deltastream/dstest"
+)
+
+func TestDefaultQueryTimeout(t *testing.T) {
+	g := NewWithT(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	// statements never complete
+	httpmock.RegisterResponder("POST", "https://api.deltastream.io/v2/statements", mockSubmitStatementsResponser(g, http.StatusAccepted, "sometoken", "LIST ORGANIZATIONS;", map[string][]byte{}, "fixtures/list-organizations-202-03000.json"))
+	httpmock.RegisterResponder("GET", "https://api.deltastream.io/v2/statements/d789687d-4e1b-4649-846e-4f10b722f3ad?partitionID=0&timezone=UTC", mockGetStatementResponser(g, http.StatusAccepted, "sometoken", "fixtures/list-organizations-202-03000.json"))
+
+	connector, err := ConnectorWithOptions(context.TODO(), WithServer("https://api.deltastream.io/v2"), WithStaticToken("sometoken"),
+		WithDefaultQueryTimeout(50*time.Millisecond), WithControlPlanePollInterval(10*time.Millisecond))
+	g.Expect(err).To(BeNil())
+	db := sql.OpenDB(connector)
+	defer db.Close()
+
+	// elapsed returns how long f took to fail with a deadline error
+	elapsed := func(f func() error) time.Duration {
+		start := time.Now()
+		err := f()
+		g.Expect(errors.Is(err, context.DeadlineExceeded)).To(BeTrue(), "%v", err)
+		return time.Since(start)
+	}
+	exec := func(ctx context.Context) func() error {
+		return func() error {
+			_, err := db.ExecContext(ctx, "LIST ORGANIZATIONS;")
+			return err
+		}
+	}
+	g.Expect(elapsed(exec(context.Background()))).To(BeNumerically("<", time.Second))
+	g.Expect(elapsed(func() error {
+		_, err := db.QueryContext(context.Background(), "LIST ORGANIZATIONS;")
+		return err
+	})).To(BeNumerically("<", time.Second))
+
+	// deadlines of callers apply instead
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	g.Expect(elapsed(exec(ctx))).To(BeNumerically(">=", 250*time.Millisecond))
+
+	// as do cancellations of callers that opted out
+	ctx, cancel = context.WithCancel(WithoutDefaultQueryTimeout(context.Background()))
+	time.AfterFunc(300*time.Millisecond, cancel)
+	start := time.Now()
+	_, err = db.ExecContext(ctx, "LIST ORGANIZATIONS;")
+	g.Expect(errors.Is(err, context.Canceled)).To(BeTrue(), "%v", err)
+	g.Expect(time.Since(start)).To(BeNumerically(">=", 250*time.Millisecond))
+}
+
+func TestDefaultQueryTimeoutStreaming(t *testing.T) {
+	g := NewWithT(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	server := dstest.NewStreamingServer(dstest.Metadata(streamingColumns...), dstest.Row("1", "a"), dstest.Delay(200*time.Millisecond), dstest.Row("2", "b"))
+	defer server.Close()
+	httpmock.RegisterResponder("POST", "https://api.deltastream.io/v2/statements", httpmock.NewJsonResponderOrPanic(200, server.StatementResponse(nil)))
+
+	connector, err := ConnectorWithOptions(context.TODO(), WithServer("https://api.deltastream.io/v2"), WithStaticToken("sometoken"), WithDefaultQueryTimeout(100*time.Millisecond))
+	g.Expect(err).To(BeNil())
+	db := sql.OpenDB(connector)
+	defer db.Close()
+
+	// the timeout covers reading the rows
+	rows, err := db.QueryContext(context.Background(), "SELECT * FROM pageviews;")
+	g.Expect(err).To(BeNil())
+	g.Expect(rows.Next()).To(BeTrue())
+	g.Expect(rows.Next()).To(BeFalse())
+	g.Expect(errors.Is(rows.Err(), context.DeadlineExceeded)).To(BeTrue(), "%v", rows.Err())
+	g.Expect(rows.Close()).To(Succeed())
+
+	// unless the query opted out
+	rows, err = db.QueryContext(WithoutDefaultQueryTimeout(context.Background()), "SELECT * FROM pageviews;")
+	g.Expect(err).To(BeNil())
+	defer rows.Close()
+	var ids []int64
+	for i := 0; i < 2 && rows.Next(); i++ {
+		var id int64
+		var name string
+		g.Expect(rows.Scan(&id, &name)).To(Succeed())
+		ids = append(ids, id)
+	}
+	g.Expect(rows.Err()).To(BeNil())
+	g.Expect(ids).To(Equal([]int64{1, 2}))
+}
@@ -0,0 +1,82 @@
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package godeltastream
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/deltastreaminc/go-deltastream/dstest"
+)
+
+func TestRecorder(t *testing.T) {
+	g := NewWithT(t)
+
+	fixture, err := os.ReadFile("fixtures/list-organizations-200-00000-1.json")
+	g.Expect(err).To(BeNil())
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(fixture)
+	}))
+
+	queryOrgs := func(client *http.Client) []string {
+		connector, err := ConnectorWithOptions(context.TODO(), WithServer(server.URL+"/v2"), WithStaticToken("sometoken"), WithHTTPClient(client))
+		g.Expect(err).To(BeNil())
+		rows, err := sql.OpenDB(connector).QueryContext(context.Background(), "LIST ORGANIZATIONS;")
+		g.Expect(err).To(BeNil())
+		defer rows.Close()
+
+		var names []string
+		for rows.Next() {
+			var id, name string
+			var description, profileImageURI *string
+			var createdAt any
+			g.Expect(rows.Scan(&id, &name, &description, &profileImageURI, &createdAt)).To(BeNil())
+			names = append(names, name)
+		}
+		g.Expect(rows.Err()).To(BeNil())
+		return names
+	}
+
+	cassette := filepath.Join(t.TempDir(), "cassette.json")
+	rec, err := dstest.NewRecorder(cassette, dstest.ModeRecord)
+	g.Expect(err).To(BeNil())
+	recorded := queryOrgs(rec.Client())
+	g.Expect(recorded).ToNot(BeEmpty())
+	g.Expect(rec.Save()).To(BeNil())
+
+	b, err := os.ReadFile(cassette)
+	g.Expect(err).To(BeNil())
+	g.Expect(string(b)).ToNot(ContainSubstring("sometoken"))
+
+	server.Close()
+	rec, err = dstest.NewRecorder(cassette, dstest.ModeReplay)
+	g.Expect(err).To(BeNil())
+	g.Expect(queryOrgs(rec.Client())).To(Equal(recorded))
+
+	connector, err := ConnectorWithOptions(context.TODO(), WithServer(server.URL+"/v2"), WithStaticToken("sometoken"), WithHTTPClient(rec.Client()))
+	g.Expect(err).To(BeNil())
+	_, err = sql.OpenDB(connector).QueryContext(context.Background(), "LIST ORGANIZATIONS;")
+	g.Expect(err).To(MatchError(ContainSubstring("no recorded interaction left")))
+}
@@ -0,0 +1,66 @@
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package godeltastream
+
+import (
+	"io"
+	"net/http"
+)
+
+// WithMaxResponseBytes bounds the size of the responses of the control plane and of dataplanes, e.g. result set
+// partitions and downloaded files, so that a misbehaving statement cannot exhaust the memory of the client. Requests
+// whose response is larger fail with an *ErrResponseTooLarge. Responses are not limited by default.
+func WithMaxResponseBytes(n int64) func(*connectionOptions) {
+	return func(o *connectionOptions) {
+		o.maxResponseBytes = n
+	}
+}
+
+// limitResponse fails responses declaring a length over limit and limits the body of the others to limit bytes.
+func limitResponse(rsp *http.Response, limit int64) (*http.Response, error) {
+	if limit <= 0 {
+		return rsp, nil
+	}
+	if rsp.ContentLength > limit {
+		rsp.Body.Close()
+		return nil, &ErrResponseTooLarge{Limit: limit}
+	}
+	rsp.Body = &limitedBody{ReadCloser: rsp.Body, limit: limit, remaining: limit}
+	return rsp, nil
+}
+
+// limitedBody returns an *ErrResponseTooLarge once more than limit bytes are read.
+type limitedBody struct {
+	io.ReadCloser
+	limit     int64
+	remaining int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	// read one byte past the limit to tell a body of exactly limit bytes from a larger one
+	if int64(len(p)) > b.remaining+1 {
+		p = p[:b.remaining+1]
+	}
+	n, err := b.ReadCloser.Read(p)
+	if int64(n) > b.remaining {
+		n = int(b.remaining)
+		b.remaining = 0
+		return n, &ErrResponseTooLarge{Limit: b.limit}
+	}
+	b.remaining -= int64(n)
+	return n, err
+}
@@ -0,0 +1,87 @@
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package godeltastream
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	. "github.com/onsi/gomega"
+)
+
+func TestMaxResponseBytes(t *testing.T) {
+	g := NewWithT(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	// the control plane response fits, the dataplane partition does not
+	httpmock.RegisterResponder("POST", "https://api.deltastream.io/v2/statements",
+		mockSubmitStatementsResponser(g, http.StatusOK, "sometoken", "SELECT * FROM mview_table;", map[string][]byte{}, "fixtures/dataplane-query-200-00000-0.json"),
+	)
+	httpmock.RegisterResponder("GET", "https://dpapi.deltastream.io/v2/statements/d789687d-4e1b-4649-846e-4f10b722f3ad?partitionID=0&timezone=UTC",
+		mockGetStatementResponser(g, http.StatusOK, "dataplanetoken", "fixtures/list-organizations-200-00000-1.json"),
+	)
+
+	connector, err := ConnectorWithOptions(context.TODO(), WithServer("https://api.deltastream.io/v2"), WithStaticToken("sometoken"), WithMaxResponseBytes(600))
+	g.Expect(err).To(BeNil())
+	db := sql.OpenDB(connector)
+	defer db.Close()
+
+	_, err = db.QueryContext(context.TODO(), "SELECT * FROM mview_table;")
+	var tooLarge *ErrResponseTooLarge
+	g.Expect(errors.As(err, &tooLarge)).To(BeTrue())
+	g.Expect(tooLarge.Limit).To(Equal(int64(600)))
+
+	// responses of exactly the limit are accepted
+	partition, err := os.ReadFile("fixtures/list-organizations-200-00000-1.json")
+	g.Expect(err).To(BeNil())
+	connector, err = ConnectorWithOptions(context.TODO(), WithServer("https://api.deltastream.io/v2"), WithStaticToken("sometoken"), WithMaxResponseBytes(int64(len(partition))))
+	g.Expect(err).To(BeNil())
+	db = sql.OpenDB(connector)
+	defer db.Close()
+	rows, err := db.QueryContext(context.TODO(), "SELECT * FROM mview_table;")
+	g.Expect(err).To(BeNil())
+	g.Expect(rows.Close()).To(Succeed())
+}
+
+func TestMaxResponseBytesContentLength(t *testing.T) {
+	g := NewWithT(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("POST", "https://api.deltastream.io/v2/statements", func(r *http.Request) (*http.Response, error) {
+		rsp := httpmock.NewStringResponse(http.StatusOK, `{"sqlState": "00000"}`)
+		rsp.ContentLength = 1 << 30
+		return rsp, nil
+	})
+
+	connector, err := ConnectorWithOptions(context.TODO(), WithServer("https://api.deltastream.io/v2"), WithStaticToken("sometoken"), WithMaxResponseBytes(1024))
+	g.Expect(err).To(BeNil())
+	db := sql.OpenDB(connector)
+	defer db.Close()
+
+	// responses declaring a larger size fail before their body is read
+	_, err = db.ExecContext(context.TODO(), "USE DATABASE db;")
+	var tooLarge *ErrResponseTooLarge
+	g.Expect(errors.As(err, &tooLarge)).To(BeTrue())
+	g.Expect(err.Error()).To(ContainSubstring("response exceeds the maximum size of 1024 bytes"))
+}
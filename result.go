@@ -0,0 +1,138 @@
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package godeltastream
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/deltastreaminc/go-deltastream/apiv2"
+)
+
+// Compile time validation that our types implement the expected interfaces
+var (
+	_ driver.Result = &ExecResult{}
+)
+
+// ExecResult is the result of a statement executed with ExecContext. Statements such as CREATE ... AS SELECT return
+// a result set describing what was created, e.g. the query ID and the name of the created relation. ExecResult keeps
+// the first row of that result set so callers do not need to switch to QueryContext to read it.
+//
+// database/sql does not expose the driver.Result it wraps, use WithExecResult to receive the ExecResult of a statement.
+type ExecResult struct {
+	// StatementID is the ID of the executed statement.
+	StatementID uuid.UUID
+	// StatementIDs are the IDs of every statement when a multi-statement SQL was executed.
+	StatementIDs []uuid.UUID
+	// Columns are the names of the columns of the result set.
+	Columns []string
+	// FirstRow is the first row of the result set, nil if the result set is empty or could not be decoded.
+	FirstRow []driver.Value
+	// FirstRowErr is the error decoding the first row. The statement itself succeeded.
+	FirstRowErr error
+}
+
+// newExecResult builds the ExecResult of rs. The statement was already applied by the server, so a first row that
+// cannot be decoded is reported in FirstRowErr rather than failing the statement.
+func newExecResult(rs *apiv2.ResultSet, opts decodeOptions) *ExecResult {
+	r := &ExecResult{StatementID: rs.StatementID}
+	if rs.StatementIDs != nil {
+		r.StatementIDs = *rs.StatementIDs
+	}
+
+	colTypes := make([]string, len(rs.Metadata.Columns))
+	r.Columns = make([]string, len(rs.Metadata.Columns))
+	for i, c := range rs.Metadata.Columns {
+		r.Columns[i] = c.Name
+		colTypes[i] = c.Type
+	}
+
+	if rs.Data != nil && len(*rs.Data) > 0 {
+		firstRow := make([]driver.Value, len(r.Columns))
+		if err := decodeRow(newColumnDecoders(r.Columns, colTypes, opts), (*rs.Data)[0], firstRow); err != nil {
+			r.FirstRowErr = err
+		} else {
+			r.FirstRow = firstRow
+		}
+	}
+	return r
+}
+
+// Value returns the value of the named column in the first row. ok is false if the result set has no such column or
+// no rows.
+func (r *ExecResult) Value(column string) (v driver.Value, ok bool) {
+	if r.FirstRow == nil {
+		return nil, false
+	}
+	for i, c := range r.Columns {
+		if c == column {
+			return r.FirstRow[i], true
+		}
+	}
+	return nil, false
+}
+
+// LastInsertId returns the database's auto-generated ID
+// after, for example, an INSERT into a table with primary
+// key.
+func (r *ExecResult) LastInsertId() (int64, error) {
+	return -1, nil
+}
+
+// RowsAffected returns the number of rows affected by the
+// query.
+func (r *ExecResult) RowsAffected() (int64, error) {
+	return -1, nil
+}
+
+// Execer is implemented by *sql.DB, *sql.Conn and *sql.Tx.
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// ExecReturning executes a statement that returns a single row describing what it did, e.g. CREATE STORE, and returns
+// the values of that row by column name. NULL values are omitted.
+func ExecReturning(ctx context.Context, db Execer, query string, args ...any) (map[string]string, error) {
+	res := ExecResult{}
+	if _, err := db.ExecContext(WithExecResult(ctx, &res), query, args...); err != nil {
+		return nil, err
+	}
+	if res.FirstRow == nil {
+		return nil, &ErrClientError{message: "statement did not return a row"}
+	}
+
+	values := make(map[string]string, len(res.Columns))
+	for i, c := range res.Columns {
+		switch v := res.FirstRow[i].(type) {
+		case nil:
+		case string:
+			values[c] = v
+		case []byte:
+			values[c] = string(v)
+		case time.Time:
+			values[c] = v.Format(time.RFC3339Nano)
+		default:
+			values[c] = fmt.Sprint(v)
+		}
+	}
+	return values, nil
+}
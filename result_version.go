@@ -0,0 +1,98 @@
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package godeltastream
+
+import (
+	"context"
+	"net/http"
+	"sync"
+)
+
+// resultVersion tracks the versions of the partitions of a result set, which servers may identify with the ETag
+// header, so that a partition fetched again, e.g. after a retry, is known to belong to the same result. Partitions
+// fetched again are requested with If-Match and the version received before, and servers reply with 412 once the
+// result was computed again. Partitions of servers not sending ETags are not verified.
+type resultVersion struct {
+	mu     sync.Mutex
+	etags  map[int32]string // ETag of every partition received
+	status int              // status code of the last response
+	last   string           // ETag of the last partition received
+	etag   string           // ETag the last partition was requested with
+}
+
+// withResultVersion returns a context tracking the version of the result set fetched using it.
+func withResultVersion(ctx context.Context) context.Context {
+	return context.WithValue(ctx, resultVersionKey, &resultVersion{etags: map[int32]string{}})
+}
+
+// resultVersionFrom returns the result version tracked by ctx, nil if none.
+func resultVersionFrom(ctx context.Context) *resultVersion {
+	v, _ := ctx.Value(resultVersionKey).(*resultVersion)
+	return v
+}
+
+// ifMatch returns a request editor adding the version of partitionID, once received, to requests of the partition.
+func (v *resultVersion) ifMatch(partitionID int32) func(context.Context, *http.Request) error {
+	return func(_ context.Context, req *http.Request) error {
+		if v == nil {
+			return nil
+		}
+		v.mu.Lock()
+		defer v.mu.Unlock()
+		v.etag = v.etags[partitionID]
+		if v.etag != "" {
+			req.Header.Set("If-Match", v.etag)
+		}
+		return nil
+	}
+}
+
+// observe records the response to a request of the partition partitionID.
+func (v *resultVersion) observe(partitionID int32, rsp *http.Response) {
+	if v == nil || rsp == nil {
+		return
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.status = rsp.StatusCode
+	if rsp.StatusCode != http.StatusOK {
+		return
+	}
+	v.last = rsp.Header.Get("ETag")
+	if _, ok := v.etags[partitionID]; !ok {
+		v.etags[partitionID] = v.last
+	}
+}
+
+// expired returns the reason the last response shows the result is no longer available, if any.
+func (v *resultVersion) expired() string {
+	if v == nil {
+		return ""
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	switch {
+	case v.status == http.StatusNotFound, v.status == http.StatusGone:
+		return "result is no longer available"
+	case v.status == http.StatusPreconditionFailed:
+		return "result was computed again"
+	case v.status == http.StatusOK && v.etag != "" && v.last != v.etag:
+		// servers ignoring If-Match
+		return "result was computed again"
+	}
+	return ""
+}
@@ -19,12 +19,9 @@ package godeltastream
 import (
 	"context"
 	"database/sql/driver"
-	"encoding/base64"
 	"fmt"
 	"io"
-	"math/big"
 	"reflect"
-	"strconv"
 	"strings"
 	"time"
 
@@ -56,8 +53,8 @@ func init() {
 		"DOUBLE":        reflect.TypeOf(float64(0)),
 		"DECIMAL":       reflect.TypeOf(float64(0)),
 		"TIMESTAMP":     reflect.TypeOf(time.Now()),
-		"DATE":          reflect.TypeOf(time.Now()),
-		"TIME":          reflect.TypeOf(time.Now()),
+		"DATE":          reflect.TypeOf(""),
+		"TIME":          reflect.TypeOf(TimeOfDay{}),
 		"TIMESTAMP_LTZ": reflect.TypeOf(time.Now()),
 		"VARBINARY":     reflect.TypeOf([]byte{}),
 		"BYTES":         reflect.TypeOf([]byte{}),
@@ -78,9 +75,18 @@ type resultSetRows struct {
 
 	currentRowIdx       int32
 	currentPartitionIdx int32
+	rowsRead            int64
+	partitionsFetched   int
+	closed              bool
 
 	currentResultSet         *apiv2.ResultSet
 	enableColumnDisplayHints bool
+	decodeOptions            decodeOptions
+	decoders                 []columnDecoder
+	partitionFetchRetry      *partitionFetchRetryPolicy
+	rowBufferPooling         bool
+	rowBuffer                *rowBuffer         // rows of the partitions fetched, see WithRowBufferPooling
+	release                  context.CancelFunc // called once closed, see releaseOnClose
 }
 
 func (r *resultSetRows) ColumnTypeNullable(index int) (nullable bool, ok bool) {
@@ -110,32 +116,42 @@ func (r *resultSetRows) ColumnTypeScanType(index int) reflect.Type {
 		return nil
 	}
 	md := r.currentResultSet.Metadata.Columns[index]
-	switch {
-	case strings.HasPrefix(md.Type, "VARCHAR"):
-		return typeMap["VARCHAR"]
-	case strings.HasPrefix(md.Type, "DECIMAL"):
-		return typeMap["DECIMAL"]
-	case strings.HasPrefix(md.Type, "TIMESTAMP"):
-		return typeMap["TIMESTAMP"]
-	case strings.HasPrefix(md.Type, "TIME"):
-		return typeMap["TIME"]
-	case strings.HasPrefix(md.Type, "ARRAY"):
-		return typeMap["ARRAY"]
-	case strings.HasPrefix(md.Type, "STRUCT"):
-		return typeMap["STRUCT"]
-	case strings.HasPrefix(md.Type, "MAP"):
-		return typeMap["MAP"]
-	default:
-		return typeMap[md.Type]
-	}
+	return scanType(md.Type, md.Nullable, r.decodeOptions)
 }
 
-// Close implements driver.Rows.
+// Close implements driver.Rows. Close may be called more than once, Next returns an *ErrStreamClosed once it was.
 func (r *resultSetRows) Close() error {
+	if r.conn != nil {
+		reportRowsStats(r.ctx, r.RowsStats())
+	}
 	r.conn = nil
+	r.closed = true
+	if r.rowBuffer != nil {
+		r.currentResultSet.Data = nil
+		r.rowBuffer.reset()
+		rowBufferPool.Put(r.rowBuffer)
+		r.rowBuffer = nil
+	}
+	if r.release != nil {
+		r.release()
+	}
 	return nil
 }
 
+// RowsStats implements RowsStatsProvider.
+func (r *resultSetRows) RowsStats() RowsStats {
+	var total int64
+	for _, p := range r.currentResultSet.Metadata.PartitionInfo {
+		total += int64(p.RowCount)
+	}
+	return RowsStats{
+		RowsRead:          r.rowsRead,
+		RowsUnconsumed:    max(total-r.rowsRead, 0),
+		PartitionsFetched: r.partitionsFetched,
+		BytesReceived:     bytesReceived(r.ctx),
+	}
+}
+
 // Columns returns the names of the columns. The number of
 // columns of the result is inferred from the length of the
 // slice. If a particular column name isn't known, an empty
@@ -159,75 +175,66 @@ func (r *resultSetRows) Columns() []string {
 // should be taken when closing Rows not to modify
 // a buffer held in dest.
 func (r *resultSetRows) Next(dest []driver.Value) error {
+	if r.closed {
+		return &ErrStreamClosed{}
+	}
 	rowIdx, partIdx := r.calcPartitionIdx(r.currentRowIdx + 1)
 	if partIdx == -1 {
 		return io.EOF
 	}
 	if partIdx != r.currentPartitionIdx {
-		resp, err := r.conn.getStatement(r.ctx, r.currentResultSet.StatementID, int32(partIdx))
-		if err != nil {
+		if err := r.fetchPartition(partIdx); err != nil {
 			return err
 		}
-		r.currentPartitionIdx = partIdx
-		r.currentResultSet = resp
 	}
 	r.currentRowIdx += 1
-	rowData := (*r.currentResultSet.Data)[rowIdx]
-	if len(rowData) != len(dest) {
-		return &ErrClientError{message: fmt.Sprintf("number of columns does not match size of result slice. expected %d, got %d", len(rowData), len(dest))}
+	r.rowsRead++
+	if r.decoders == nil {
+		colNames := make([]string, len(r.currentResultSet.Metadata.Columns))
+		colTypes := make([]string, len(r.currentResultSet.Metadata.Columns))
+		for i, col := range r.currentResultSet.Metadata.Columns {
+			colNames[i] = col.Name
+			colTypes[i] = col.Type
+		}
+		r.decoders = newColumnDecoders(colNames, colTypes, r.decodeOptions)
 	}
+	return decodeRow(r.decoders, (*r.currentResultSet.Data)[rowIdx], dest)
+}
 
-	var err error
-	for idx, col := range r.currentResultSet.Metadata.Columns {
-		switch {
-		case rowData[idx] == nil:
-			dest[idx] = nil
-		default:
-			fallthrough
-		case // as parsed by the server
-			strings.HasPrefix(col.Type, "VARCHAR"),
-			col.Type == "DATE",
-			strings.HasPrefix(col.Type, "ARRAY"),
-			strings.HasPrefix(col.Type, "MAP"),
-			strings.HasPrefix(col.Type, "STRUCT"):
-			dest[idx] = *rowData[idx]
-		case
-			col.Type == "TINYINT",
-			col.Type == "SMALLINT",
-			col.Type == "INTEGER":
-			dest[idx], err = strconv.ParseInt(*rowData[idx], 10, 64)
-			if err != nil {
-				return err
-			}
-		case col.Type == "BIGINT":
-			flt, _, err := big.ParseFloat(*rowData[idx], 10, 0, big.ToNearestEven)
-			if err != nil {
-				return err
-			}
-			dest[idx], _ = flt.Int(new(big.Int))
-		case
-			col.Type == "FLOAT",
-			col.Type == "DOUBLE",
-			strings.HasPrefix(col.Type, "DECIMAL"):
-			dest[idx], err = strconv.ParseFloat(*rowData[idx], 64)
-			if err != nil {
-				return err
-			}
-		case strings.HasPrefix(col.Type, "TIME"):
-			dest[idx], err = parseTime(*rowData[idx], col.Type)
-			if err != nil {
-				return err
-			}
-		case
-			col.Type == "VARBINARY",
-			col.Type == "BYTES":
-			dest[idx], err = base64.StdEncoding.DecodeString(*rowData[idx])
-			if err != nil {
-				return err
-			}
-		case col.Type == "BOOLEAN":
-			dest[idx] = strings.ToLower(*rowData[idx]) == "true"
+// fetchPartition makes the partition partIdx the current result set.
+func (r *resultSetRows) fetchPartition(partIdx int32) error {
+	resp, err := r.getPartition(partIdx)
+	if err != nil {
+		return err
+	}
+	if len(resp.Metadata.Columns) == 0 {
+		// partitions without columns share those of the first partition
+		resp.Metadata.Columns = r.currentResultSet.Metadata.Columns
+	} else if err := resultSetSchema(r.currentResultSet.Metadata.Columns).verify(resultSetSchema(resp.Metadata.Columns)); err != nil {
+		return err
+	}
+	r.currentPartitionIdx = partIdx
+	r.currentResultSet = resp
+	r.partitionsFetched++
+	return nil
+}
+
+// normalizeResultSet makes the first partition of rs consistent with its data, so that result sets without rows read
+// the same whether the server sent no data, no partitions or partitions without rows: their columns are known and Next
+// returns io.EOF. Rows sent without partitions make up a single partition.
+func normalizeResultSet(rs *apiv2.ResultSet) error {
+	if rs.Data == nil {
+		rs.Data = &[][]*string{}
+	}
+	rows := int32(len(*rs.Data))
+	if len(rs.Metadata.PartitionInfo) == 0 {
+		if rows > 0 {
+			rs.Metadata.PartitionInfo = []apiv2.ResultSetPartitionInfo{{RowCount: rows}}
 		}
+		return nil
+	}
+	if expected := rs.Metadata.PartitionInfo[0].RowCount; expected > rows {
+		return &ErrInterfaceError{message: fmt.Sprintf("partition has %d rows instead of %d", rows, expected)}
 	}
 	return nil
 }
@@ -242,19 +249,25 @@ func (r *resultSetRows) calcPartitionIdx(rowIdx int32) (row, part int32) {
 	return -1, -1
 }
 
-func parseTime(s, colType string) (time.Time, error) {
+// isLocalTimeZoneColumn returns whether colType is a TIMESTAMP_LTZ type.
+func isLocalTimeZoneColumn(colType string) bool {
+	return strings.HasSuffix(colType, `WITH LOCAL TIME ZONE`) || strings.HasPrefix(colType, `TIMESTAMP_LTZ`)
+}
+
+// parseTime parses the TIME, DATE and TIMESTAMP values of colType. TIMESTAMP_LTZ values without an offset are in the
+// session timezone location, and all of them are returned in location if it is not nil. Values that cannot be parsed
+// return the zero time with the error.
+func parseTime(s, colType string, location *time.Location) (time.Time, error) {
 	if colType == `DATE` {
 		return time.Parse(`2006-01-02`, s)
 	}
 
 	switch {
-	case
-		strings.HasSuffix(colType, `WITH LOCAL TIME ZONE`),
-		strings.HasPrefix(colType, `TIMESTAMP_LTZ`):
+	case isLocalTimeZoneColumn(colType):
 
 		sspl := strings.Split(s, " ")
 		if len(sspl) != 2 {
-			return time.Now(), fmt.Errorf("invalid timestamp_ltz %s", s)
+			return time.Time{}, fmt.Errorf("invalid timestamp_ltz %s", s)
 		}
 		timePart := sspl[1]
 		containsNano := strings.Contains(timePart, ".")
@@ -267,19 +280,26 @@ func parseTime(s, colType string) (time.Time, error) {
 		if containsTZ {
 			layout += "Z0700"
 		}
-		return time.Parse(layout, s)
+		if location == nil {
+			return time.Parse(layout, s)
+		}
+		t, err := time.ParseInLocation(layout, s, location)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return t.In(location), nil
 	case
 		colType == `TIMESTAMP`,
 		strings.HasPrefix(colType, `TIMESTAMP(`):
 
 		sspl := strings.Split(s, " ")
 		if len(sspl) != 2 {
-			return time.Now(), fmt.Errorf("invalid timestamp %s", s)
+			return time.Time{}, fmt.Errorf("invalid timestamp %s", s)
 		}
 		timePart := sspl[1]
 		containsNano := strings.Contains(timePart, ".")
 		if strings.Contains(timePart, "Z") || strings.Contains(timePart, "+") || strings.Contains(timePart, "-") {
-			return time.Now(), fmt.Errorf("timestamp cannot be parsed with timezone. timestamp_ltz must be used instead")
+			return time.Time{}, fmt.Errorf("timestamp cannot be parsed with timezone. timestamp_ltz must be used instead")
 		}
 		layout := "2006-01-02 15:04:05"
 		if containsNano {
@@ -292,7 +312,7 @@ func parseTime(s, colType string) (time.Time, error) {
 
 		containsNano := strings.Contains(s, ".")
 		if strings.Contains(s, "Z") || strings.Contains(s, "+") || strings.Contains(s, "-") {
-			return time.Now(), fmt.Errorf("time cannot be parsed with timezone")
+			return time.Time{}, fmt.Errorf("time cannot be parsed with timezone")
 		}
 		layout := "15:04:05"
 		if containsNano {
@@ -300,6 +320,6 @@ func parseTime(s, colType string) (time.Time, error) {
 		}
 		return time.Parse(layout, s)
 	default:
-		return time.Now(), fmt.Errorf("unsupported column type %s", colType)
+		return time.Time{}, fmt.Errorf("unsupported column type %s", colType)
 	}
 }
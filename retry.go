@@ -0,0 +1,260 @@
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package godeltastream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"k8s.io/utils/ptr"
+
+	"github.com/deltastreaminc/go-deltastream/apiv2"
+)
+
+// Default delays between status requests of pending statements. Dataplanes usually complete statements sooner than
+// the control plane.
+const (
+	defaultControlPlanePollInterval = time.Second
+	defaultDataplanePollInterval    = 250 * time.Millisecond
+)
+
+// defaultStatementNotFoundGrace is the time statements accepted by the control plane may not be found for, see
+// WithStatementNotFoundGrace.
+const defaultStatementNotFoundGrace = 5 * time.Second
+
+// pollInterval is the delay between status requests of a pending statement. Every delay is randomized by up to 10% so
+// that connections started together do not poll in lockstep.
+type pollInterval time.Duration
+
+func (p pollInterval) next() time.Duration {
+	d := time.Duration(p)
+	if jitter := int64(d / 10); jitter > 0 {
+		d += time.Duration(rand.Int63n(2*jitter+1) - jitter)
+	}
+	return d
+}
+
+// wait blocks until the next poll is due or ctx is done.
+func (p pollInterval) wait(ctx context.Context) error {
+	t := time.NewTimer(p.next())
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// sqlStateClassResourceNotReady is the SqlState class of errors raised while a resource is still being provisioned.
+const sqlStateClassResourceNotReady = "3E"
+
+type notReadyRetryPolicy struct {
+	backoff time.Duration
+	maxWait time.Duration
+}
+
+// do calls f until it succeeds, fails with an error other than a resource not ready error, or maxWait has elapsed. f
+// is not called again once canRetry returns false.
+func (p *notReadyRetryPolicy) do(ctx context.Context, canRetry func() bool, f func() (*apiv2.ResultSet, error)) (*apiv2.ResultSet, error) {
+	deadline := time.Now().Add(p.maxWait)
+	backoff := p.backoff
+	if backoff <= 0 {
+		backoff = time.Second
+	}
+	for {
+		rs, err := f()
+		var sqlErr ErrSQLError
+		if err == nil || !errors.As(err, &sqlErr) || sqlErr.SQLCode.Class() != sqlStateClassResourceNotReady || !canRetry() {
+			return rs, err
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return rs, err
+		}
+		wait := backoff
+		if wait > remaining {
+			wait = remaining
+		}
+
+		t := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return nil, ctx.Err()
+		case <-t.C:
+		}
+		backoff *= 2
+	}
+}
+
+// sendStatementWithReauth sends a statement, and sends it once more with a new token if the server rejected the token,
+// the token manager of the connection can refresh it and the body can be sent again.
+func (c *Conn) sendStatementWithReauth(ctx context.Context, body *statementBody) (*apiv2.ResultSet, error) {
+	rs, err := c.sendStatement(ctx, body)
+	refresher, ok := c.tokenManager.(TokenRefresher)
+	if err == nil || !ok || !isInvalidTokenError(err) || !body.replayable() {
+		return rs, err
+	}
+	if rerr := refresher.ForceRefresh(ctx); rerr != nil {
+		return rs, err
+	}
+	c.emit(ConnectionEvent{Type: ConnectionEventAuthRefreshed})
+	return c.sendStatement(ctx, body)
+}
+
+func isInvalidTokenError(err error) bool {
+	var unexpected *ErrUnexpectedResponse
+	if errors.As(err, &unexpected) {
+		return unexpected.StatusCode == http.StatusUnauthorized
+	}
+	return errors.Is(err, ErrSQLError{SQLCode: SqlStateInvalidApiToken})
+}
+
+type streamDialRetryPolicy struct {
+	retries int
+	backoff time.Duration
+}
+
+// retryable returns whether the stream may be opened by dialing again after err, e.g. after network errors or
+// responses of load balancers while dataplanes restart. Errors received once connected are never retried.
+func (p *streamDialRetryPolicy) retryable(err error) bool {
+	var dialErr *streamDialError
+	if !errors.As(err, &dialErr) {
+		return false
+	}
+	switch {
+	case dialErr.statusCode == 0,
+		dialErr.statusCode >= http.StatusInternalServerError,
+		dialErr.statusCode == http.StatusTooManyRequests,
+		dialErr.statusCode == http.StatusUnauthorized,
+		dialErr.statusCode == http.StatusForbidden:
+		return true
+	}
+	return false
+}
+
+type partitionFetchRetryPolicy struct {
+	retries int
+	backoff time.Duration
+}
+
+// retryable returns whether fetching a partition may succeed when sent again after err, e.g. after network errors or
+// while the server is unavailable.
+func (p *partitionFetchRetryPolicy) retryable(err error) bool {
+	var (
+		interfaceErr *ErrInterfaceError
+		serverErr    *ErrServerError
+		unexpected   *ErrUnexpectedResponse
+	)
+	switch {
+	case errors.As(err, &unexpected):
+		return unexpected.StatusCode >= http.StatusInternalServerError || unexpected.StatusCode == http.StatusTooManyRequests
+	case errors.As(err, &interfaceErr):
+		// requests that could not be sent or whose response could not be read
+		return interfaceErr.wrapErr != nil
+	}
+	return errors.As(err, &serverErr) || errors.Is(err, ErrServiceUnavailable) || errors.Is(err, ErrDeadlineExceeded)
+}
+
+// getPartition fetches the partition partIdx of the result set of the rows, sending the request again as configured
+// with WithPartitionFetchRetry. It returns an *ErrResultExpired if the partition no longer belongs to the result the
+// rows started with.
+func (r *resultSetRows) getPartition(partIdx int32) (*apiv2.ResultSet, error) {
+	statementID := r.currentResultSet.StatementID
+	version := resultVersionFrom(r.ctx)
+	ctx := r.ctx
+	if r.rowBufferPooling {
+		// the rows of the previous partition are read, its buffer is reused
+		if r.rowBuffer == nil {
+			r.rowBuffer = rowBufferPool.Get().(*rowBuffer)
+		}
+		ctx = withRowBuffer(ctx, r.rowBuffer)
+	}
+	rs, err := r.conn.getStatement(ctx, statementID, partIdx)
+	if p := r.partitionFetchRetry; p != nil {
+		backoff := p.backoff
+		if backoff <= 0 {
+			backoff = time.Second
+		}
+		for retry := 0; err != nil && retry < p.retries && p.retryable(err) && version.expired() == ""; retry++ {
+			t := time.NewTimer(backoff)
+			select {
+			case <-r.ctx.Done():
+				t.Stop()
+				return nil, r.ctx.Err()
+			case <-t.C:
+			}
+			backoff *= 2
+			rs, err = r.conn.getStatement(ctx, statementID, partIdx)
+		}
+	}
+	if reason := version.expired(); reason != "" {
+		return nil, &ErrResultExpired{StatementID: statementID, PartitionID: partIdx, Reason: reason, Err: err}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// the partitions of the result must match the partitions announced by the first one
+	expected := r.currentResultSet.Metadata.PartitionInfo
+	if partitions := rs.Metadata.PartitionInfo; len(partitions) > 0 && len(partitions) != len(expected) {
+		return nil, &ErrResultExpired{StatementID: statementID, PartitionID: partIdx, Reason: fmt.Sprintf("result has %d partitions instead of %d", len(partitions), len(expected))}
+	}
+	if rows := len(ptr.Deref(rs.Data, nil)); int(partIdx) < len(expected) && rows != int(expected[partIdx].RowCount) {
+		return nil, &ErrResultExpired{StatementID: statementID, PartitionID: partIdx, Reason: fmt.Sprintf("partition has %d rows instead of %d", rows, expected[partIdx].RowCount)}
+	}
+	return rs, nil
+}
+
+// openStream opens the streaming result of the statement statementID served by the dataplane of req, dialing again
+// as configured with WithStreamDialRetry. The dataplane request is requested again from the control plane before every
+// retry, since its token may have expired in the meantime.
+func (c *Conn) openStream(ctx context.Context, statementID uuid.UUID, req apiv2.DataplaneRequest) (*streamingRows, error) {
+	httpClient := httpClientOverride(ctx, c.dataplaneHTTPClient())
+	rows, err := newStreamingRows(ctx, c, req, httpClient, c.session(), c.enableColumnDisplayHints)
+	if c.streamDialRetry == nil {
+		return rows, err
+	}
+
+	backoff := c.streamDialRetry.backoff
+	if backoff <= 0 {
+		backoff = time.Second
+	}
+	for retry := 0; err != nil && retry < c.streamDialRetry.retries && c.streamDialRetry.retryable(err); retry++ {
+		t := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return nil, ctx.Err()
+		case <-t.C:
+		}
+		backoff *= 2
+
+		if rs, rerr := c.getStatement(ctx, statementID, 0); rerr == nil && rs.Metadata.DataplaneRequest != nil {
+			req = *rs.Metadata.DataplaneRequest
+		}
+		rows, err = newStreamingRows(ctx, c, req, httpClient, c.session(), c.enableColumnDisplayHints)
+	}
+	return rows, err
+}
@@ -0,0 +1,240 @@
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package godeltastream
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+
+	"github.com/deltastreaminc/go-deltastream/apiv2"
+)
+
+// Limits of the values interned by a rowBuffer. Longer values are rarely repeated and interning them would mostly grow
+// the table.
+const (
+	maxInternedLength = 64
+	maxInternedValues = 4096
+)
+
+var errRowData = errors.New("unexpected row data")
+
+// rowBufferPool holds the rowBuffers of closed rows, see WithRowBufferPooling.
+var rowBufferPool = sync.Pool{
+	New: func() any {
+		return &rowBuffer{rows: [][]*string{}, cells: []*string{}, intern: map[string]*string{}}
+	},
+}
+
+// rowBuffer holds the rows of the partitions of a result set, reused from one partition to the next and, once the rows
+// are closed, by other rows. Repeated values of a partition, e.g. enums or booleans, share a single string.
+type rowBuffer struct {
+	rows   [][]*string
+	cells  []*string
+	values []string
+	intern map[string]*string
+}
+
+// reset drops the rows of b, keeping its memory.
+func (b *rowBuffer) reset() {
+	clear(b.cells)
+	clear(b.values)
+	clear(b.intern)
+	b.rows, b.cells, b.values = b.rows[:0], b.cells[:0], b.values[:0]
+}
+
+// decode decodes the data of a result set, an array of rows of strings or nulls, into b, replacing the rows it held.
+func (b *rowBuffer) decode(data []byte) ([][]*string, error) {
+	b.reset()
+	s := rowScanner{data: data}
+	if !s.consume('[') {
+		return nil, errRowData
+	}
+	if s.consume(']') {
+		return b.rows, s.end()
+	}
+	for {
+		if !s.consume('[') {
+			return nil, errRowData
+		}
+		start := len(b.cells)
+		for !s.consume(']') {
+			if len(b.cells) > start && !s.consume(',') {
+				return nil, errRowData
+			}
+			cell, err := b.cell(&s)
+			if err != nil {
+				return nil, err
+			}
+			b.cells = append(b.cells, cell)
+		}
+		b.rows = append(b.rows, b.cells[start:len(b.cells):len(b.cells)])
+		if s.consume(']') {
+			return b.rows, s.end()
+		}
+		if !s.consume(',') {
+			return nil, errRowData
+		}
+	}
+}
+
+// cell decodes the next value of s, interning short values.
+func (b *rowBuffer) cell(s *rowScanner) (*string, error) {
+	if s.literal("null") {
+		return nil, nil
+	}
+	raw, escaped, err := s.str()
+	if err != nil {
+		return nil, err
+	}
+	if p, ok := b.intern[string(raw)]; ok {
+		return p, nil
+	}
+	var v string
+	if escaped {
+		if err := json.Unmarshal(s.data[s.pos-len(raw)-2:s.pos], &v); err != nil {
+			return nil, err
+		}
+	} else {
+		v = string(raw)
+	}
+	b.values = append(b.values, v)
+	p := &b.values[len(b.values)-1]
+	if len(raw) <= maxInternedLength && len(b.intern) < maxInternedValues {
+		b.intern[string(raw)] = p
+	}
+	return p, nil
+}
+
+// rowScanner reads the tokens of the data of a result set.
+type rowScanner struct {
+	data []byte
+	pos  int
+}
+
+func (s *rowScanner) skipSpace() {
+	for s.pos < len(s.data) {
+		switch s.data[s.pos] {
+		case ' ', '\t', '\n', '\r':
+			s.pos++
+		default:
+			return
+		}
+	}
+}
+
+// consume skips c and the spaces before it, if c is next.
+func (s *rowScanner) consume(c byte) bool {
+	s.skipSpace()
+	if s.pos < len(s.data) && s.data[s.pos] == c {
+		s.pos++
+		return true
+	}
+	return false
+}
+
+// literal skips lit and the spaces before it, if lit is next.
+func (s *rowScanner) literal(lit string) bool {
+	s.skipSpace()
+	if bytes.HasPrefix(s.data[s.pos:], []byte(lit)) {
+		s.pos += len(lit)
+		return true
+	}
+	return false
+}
+
+// str returns the contents of the next string, without quotes, and whether they contain escape sequences.
+func (s *rowScanner) str() (raw []byte, escaped bool, err error) {
+	if !s.consume('"') {
+		return nil, false, errRowData
+	}
+	start := s.pos
+	for s.pos < len(s.data) {
+		switch s.data[s.pos] {
+		case '\\':
+			escaped = true
+			s.pos += 2
+			continue
+		case '"':
+			s.pos++
+			return s.data[start : s.pos-1], escaped, nil
+		}
+		s.pos++
+	}
+	return nil, false, errRowData
+}
+
+// end returns an error if anything but spaces follows.
+func (s *rowScanner) end() error {
+	s.skipSpace()
+	if s.pos != len(s.data) {
+		return errRowData
+	}
+	return nil
+}
+
+// rowBufferCodec decodes the rows of result sets into a rowBuffer, see withRowBuffer, and everything else with its
+// JSONCodec.
+type rowBufferCodec struct {
+	JSONCodec
+	buf *rowBuffer
+}
+
+func (c rowBufferCodec) Unmarshal(data []byte, v any) error {
+	rs, ok := v.(*apiv2.ResultSet)
+	if !ok {
+		return c.JSONCodec.Unmarshal(data, v)
+	}
+	// the data of the result set shadows that of the embedded result set
+	shadow := struct {
+		*apiv2.ResultSet
+		Data json.RawMessage `json:"data,omitempty"`
+	}{ResultSet: rs}
+	if err := c.JSONCodec.Unmarshal(data, &shadow); err != nil {
+		return err
+	}
+	if len(shadow.Data) == 0 || string(shadow.Data) == "null" {
+		return nil
+	}
+	rows, err := c.buf.decode(shadow.Data)
+	if err != nil {
+		// the codec reports unexpected data as it would without the buffer
+		c.buf.reset()
+		rows = nil
+		if err := c.JSONCodec.Unmarshal(shadow.Data, &rows); err != nil {
+			return err
+		}
+	}
+	rs.Data = &rows
+	return nil
+}
+
+// withRowBuffer returns a context decoding the rows of the result sets fetched using it into buf, replacing the rows it
+// held.
+func withRowBuffer(ctx context.Context, buf *rowBuffer) context.Context {
+	return context.WithValue(ctx, rowBufferKey, buf)
+}
+
+// responseCodec returns the codec decoding the responses of requests sent using ctx.
+func responseCodec(ctx context.Context, codec JSONCodec) JSONCodec {
+	if buf, ok := ctx.Value(rowBufferKey).(*rowBuffer); ok && buf != nil {
+		return rowBufferCodec{JSONCodec: codec, buf: buf}
+	}
+	return codec
+}
@@ -0,0 +1,107 @@
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package godeltastream
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	. "github.com/onsi/gomega"
+	"k8s.io/utils/ptr"
+)
+
+func TestRowBufferDecode(t *testing.T) {
+	g := NewWithT(t)
+
+	buf := rowBufferPool.New().(*rowBuffer)
+	for _, data := range []string{
+		`[]`,
+		` [ [ ] , [ null ] ] `,
+		`[["1", "a", null], ["2", "a", "b"]]`,
+		`[["quote \" and backslash \\", "été", "tab\tnewline\n"]]`,
+		`[["` + fmt.Sprintf("%070d", 1) + `"], ["` + fmt.Sprintf("%070d", 1) + `"]]`,
+	} {
+		expected := [][]*string{}
+		g.Expect(json.Unmarshal([]byte(data), &expected)).To(Succeed())
+		rows, err := buf.decode([]byte(data))
+		g.Expect(err).To(BeNil(), data)
+		g.Expect(rows).To(Equal(expected), data)
+	}
+
+	// repeated short values share a string
+	rows, err := buf.decode([]byte(`[["true", "PENDING"], ["true", "DONE"], ["false", "PENDING"]]`))
+	g.Expect(err).To(BeNil())
+	g.Expect(rows[0][0]).To(BeIdenticalTo(rows[1][0]))
+	g.Expect(rows[0][1]).To(BeIdenticalTo(rows[2][1]))
+	g.Expect(rows[0][0]).NotTo(BeIdenticalTo(rows[2][0]))
+
+	for _, data := range []string{``, `{}`, `[[1]]`, `[["a"] ["b"]]`, `[["a" "b"]]`, `[["a"]] x`, `[["a`} {
+		_, err := buf.decode([]byte(data))
+		g.Expect(err).To(HaveOccurred(), data)
+	}
+}
+
+func TestRowBufferPooling(t *testing.T) {
+	g := NewWithT(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	partition := func(data string) string {
+		return `{
+			"sqlState": "00000",
+			"statementID": "d789687d-4e1b-4649-846e-4f10b722f3ad",
+			"createdOn": 1703907114,
+			"metadata": {"encoding": "json", "partitionInfo": [{"rowCount": 2}, {"rowCount": 2}, {"rowCount": 1}], "columns": [
+				{"name": "id", "type": "BIGINT", "nullable": false},
+				{"name": "status", "type": "VARCHAR", "nullable": true}
+			]},
+			"data": ` + data + `
+		}`
+	}
+	httpmock.RegisterResponder("POST", "https://api.deltastream.io/v2/statements", compareResponder(partition(`[["1", "PENDING"], ["2", null]]`)))
+	httpmock.RegisterRegexpResponder("GET", regexp.MustCompile(`partitionID=1`), compareResponder(partition(`[["3", "DONE"], ["4", "DONE"]]`)))
+	httpmock.RegisterRegexpResponder("GET", regexp.MustCompile(`partitionID=2`), compareResponder(partition(`[["5", "PENDING"]]`)))
+
+	connector, err := ConnectorWithOptions(context.TODO(), WithServer("https://api.deltastream.io/v2"), WithStaticToken("sometoken"), WithRowBufferPooling())
+	g.Expect(err).To(BeNil())
+	db := sql.OpenDB(connector)
+	defer db.Close()
+
+	for i := 0; i < 2; i++ {
+		rows, err := db.QueryContext(context.TODO(), "SELECT * FROM orders;")
+		g.Expect(err).To(BeNil())
+		var statuses []*string
+		for rows.Next() {
+			var (
+				id     int64
+				status *string
+			)
+			g.Expect(rows.Scan(&id, &status)).To(Succeed())
+			statuses = append(statuses, status)
+		}
+		g.Expect(rows.Err()).To(BeNil())
+		g.Expect(rows.Close()).To(Succeed())
+		// scanned values remain valid once the buffers are reused
+		g.Expect(statuses).To(Equal([]*string{ptr.To("PENDING"), nil, ptr.To("DONE"), ptr.To("DONE"), ptr.To("PENDING")}))
+	}
+	g.Expect(httpmock.GetTotalCallCount()).To(Equal(6))
+}
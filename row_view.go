@@ -0,0 +1,152 @@
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package godeltastream
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// RowView reads the rows of driver.Rows by column name rather than by index, e.g. the rows returned by
+// Conn.SubmitRequest or Conn.OpenStream:
+//
+//	view := godeltastream.NewRowView(rows)
+//	for view.Next() == nil {
+//		state := view.MustString("state")
+//		...
+//	}
+//
+// Column names are matched case insensitively, the first column wins if several match.
+type RowView struct {
+	rows    driver.Rows
+	columns []string
+	index   map[string]int
+	values  []driver.Value
+}
+
+// NewRowView returns a view of rows, positioned before the first row.
+func NewRowView(rows driver.Rows) *RowView {
+	columns := rows.Columns()
+	index := make(map[string]int, len(columns))
+	for i, c := range columns {
+		if _, ok := index[strings.ToLower(c)]; !ok {
+			index[strings.ToLower(c)] = i
+		}
+	}
+	return &RowView{rows: rows, columns: columns, index: index}
+}
+
+// Next reads the next row, returning io.EOF after the last one, see driver.Rows. The values of the previous row are
+// overwritten, and []byte values may be reused by the rows, so values retained across rows should be copied.
+func (v *RowView) Next() error {
+	if v.values == nil {
+		v.values = make([]driver.Value, len(v.columns))
+	}
+	return v.rows.Next(v.values)
+}
+
+// Columns returns the names of the columns.
+func (v *RowView) Columns() []string {
+	return v.columns
+}
+
+// Has returns whether the rows have the column name.
+func (v *RowView) Has(name string) bool {
+	_, ok := v.index[strings.ToLower(name)]
+	return ok
+}
+
+// Get returns the value of the column name in the current row, nil if NULL. It returns false if there is no such
+// column or no current row.
+func (v *RowView) Get(name string) (driver.Value, bool) {
+	i, ok := v.index[strings.ToLower(name)]
+	if !ok || v.values == nil {
+		return nil, false
+	}
+	return v.values[i], true
+}
+
+// String returns the value of the column name if it is a string. It returns false if there is no such column, if the
+// value is NULL or of another type.
+func (v *RowView) String(name string) (string, bool) {
+	return viewValue[string](v, name)
+}
+
+// Int64 returns the value of the column name if it is an integer, see String.
+func (v *RowView) Int64(name string) (int64, bool) {
+	return viewValue[int64](v, name)
+}
+
+// Float64 returns the value of the column name if it is a floating point number, see String.
+func (v *RowView) Float64(name string) (float64, bool) {
+	return viewValue[float64](v, name)
+}
+
+// Bool returns the value of the column name if it is a boolean, see String.
+func (v *RowView) Bool(name string) (bool, bool) {
+	return viewValue[bool](v, name)
+}
+
+// Time returns the value of the column name if it is a time, see String.
+func (v *RowView) Time(name string) (time.Time, bool) {
+	return viewValue[time.Time](v, name)
+}
+
+// MustString returns the value of the column name, panicking if String would return false.
+func (v *RowView) MustString(name string) string {
+	return mustViewValue[string](v, name)
+}
+
+// MustInt64 returns the value of the column name, panicking if Int64 would return false.
+func (v *RowView) MustInt64(name string) int64 {
+	return mustViewValue[int64](v, name)
+}
+
+// MustFloat64 returns the value of the column name, panicking if Float64 would return false.
+func (v *RowView) MustFloat64(name string) float64 {
+	return mustViewValue[float64](v, name)
+}
+
+// MustBool returns the value of the column name, panicking if Bool would return false.
+func (v *RowView) MustBool(name string) bool {
+	return mustViewValue[bool](v, name)
+}
+
+// MustTime returns the value of the column name, panicking if Time would return false.
+func (v *RowView) MustTime(name string) time.Time {
+	return mustViewValue[time.Time](v, name)
+}
+
+func viewValue[T any](v *RowView, name string) (T, bool) {
+	value, _ := v.Get(name)
+	t, ok := value.(T)
+	return t, ok
+}
+
+func mustViewValue[T any](v *RowView, name string) T {
+	t, ok := viewValue[T](v, name)
+	if !ok {
+		value, found := v.Get(name)
+		if !found {
+			panic(fmt.Sprintf("godeltastream: no column %s", name))
+		}
+		panic(fmt.Sprintf("godeltastream: column %s is %T, not %T", name, value, t))
+	}
+	return t
+}
@@ -0,0 +1,77 @@
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package godeltastream
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+	. "github.com/onsi/gomega"
+)
+
+func TestRowView(t *testing.T) {
+	g := NewWithT(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("POST", "https://api.deltastream.io/v2/statements", compareResponder(compareResultSet(
+		[]string{"id", "amount", "updated", "note"},
+		`["1", "10.25", "2024-01-01 10:00:00.000", "a"]`,
+		`["2", "20.00", "2024-01-01 11:00:00.000", null]`,
+	)))
+	conn, err := compareDB(g, "https://api.deltastream.io/v2").Conn(context.TODO())
+	g.Expect(err).To(BeNil())
+	defer conn.Close()
+
+	g.Expect(conn.Raw(func(driverConn any) error {
+		rows, err := driverConn.(*Conn).SubmitRequest(context.TODO(), NewStatementRequest("SELECT * FROM orders;"))
+		g.Expect(err).To(BeNil())
+		defer rows.Close()
+
+		view := NewRowView(rows)
+		g.Expect(view.Columns()).To(Equal([]string{"id", "amount", "updated", "note"}))
+		g.Expect(view.Has("NOTE")).To(BeTrue())
+		g.Expect(view.Has("state")).To(BeFalse())
+		_, ok := view.Get("id")
+		g.Expect(ok).To(BeFalse())
+
+		g.Expect(view.Next()).To(Succeed())
+		g.Expect(view.MustInt64("id")).To(Equal(int64(1)))
+		g.Expect(view.MustFloat64("Amount")).To(Equal(10.25))
+		g.Expect(view.MustTime("updated")).To(Equal(time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)))
+		g.Expect(view.MustString("note")).To(Equal("a"))
+		_, ok = view.Bool("note")
+		g.Expect(ok).To(BeFalse())
+
+		g.Expect(view.Next()).To(Succeed())
+		g.Expect(view.MustInt64("id")).To(Equal(int64(2)))
+		// NULL values are found, but of no type
+		value, ok := view.Get("note")
+		g.Expect(ok).To(BeTrue())
+		g.Expect(value).To(BeNil())
+		_, ok = view.String("note")
+		g.Expect(ok).To(BeFalse())
+		g.Expect(func() { view.MustString("note") }).To(PanicWith("godeltastream: column note is <nil>, not string"))
+		g.Expect(func() { view.MustString("state") }).To(PanicWith("godeltastream: no column state"))
+
+		g.Expect(view.Next()).To(Equal(io.EOF))
+		return nil
+	})).To(Succeed())
+}
@@ -0,0 +1,73 @@
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package godeltastream
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// Compile time validation that our types implement the expected interfaces
+var (
+	_ RowsStatsProvider = &resultSetRows{}
+	_ RowsStatsProvider = &streamingRows{}
+)
+
+// RowsStats are the consumption statistics of the rows of a query, e.g. to warn about queries that fetch far more rows
+// than they consume.
+type RowsStats struct {
+	// RowsRead is the number of rows returned by Next.
+	RowsRead int64
+	// RowsUnconsumed is the number of rows never returned by Next: the remaining rows of result sets, including those of
+	// partitions not fetched, or the rows buffered by streaming results.
+	RowsUnconsumed int64
+	// PartitionsFetched is the number of result set partitions fetched, zero for streaming results.
+	PartitionsFetched int
+	// BytesReceived is the size of the result set responses and streaming messages received for the query.
+	BytesReceived int64
+}
+
+// RowsStatsProvider is implemented by the driver.Rows of queries. database/sql does not expose the driver.Rows it
+// wraps, use sql.Conn.Raw and Conn.QueryContext to access it, or WithRowsStats.
+type RowsStatsProvider interface {
+	RowsStats() RowsStats
+}
+
+// withBytesReceived returns a context counting the size of the result set responses received using it.
+func withBytesReceived(ctx context.Context) context.Context {
+	return context.WithValue(ctx, bytesReceivedKey, new(atomic.Int64))
+}
+
+func recordBytesReceived(ctx context.Context, n int) {
+	if v, ok := ctx.Value(bytesReceivedKey).(*atomic.Int64); ok {
+		v.Add(int64(n))
+	}
+}
+
+func bytesReceived(ctx context.Context) int64 {
+	if v, ok := ctx.Value(bytesReceivedKey).(*atomic.Int64); ok {
+		return v.Load()
+	}
+	return 0
+}
+
+// reportRowsStats stores stats into the RowsStats requested by ctx, if any.
+func reportRowsStats(ctx context.Context, stats RowsStats) {
+	if dest, ok := ctx.Value(rowsStatsKey).(*RowsStats); ok {
+		*dest = stats
+	}
+}
@@ -0,0 +1,124 @@
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package godeltastream
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+var scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+
+// ScanMismatch is a scan destination that cannot hold the values of its column.
+type ScanMismatch struct {
+	Column     string
+	ServerType string
+	GoType     string
+}
+
+// ErrScanMismatch is returned by ValidateScan when destinations do not match the columns of a result set.
+type ErrScanMismatch struct {
+	Mismatches []ScanMismatch
+}
+
+func (e *ErrScanMismatch) Error() string {
+	var b strings.Builder
+	b.WriteString("scan destinations do not match result columns:")
+	for _, m := range e.Mismatches {
+		fmt.Fprintf(&b, "\n  column %q of type %s cannot be scanned into %s", m.Column, m.ServerType, m.GoType)
+	}
+	return b.String()
+}
+
+// ValidateScan checks that dest can hold the values of the columns of rows, and returns an *ErrScanMismatch listing
+// every mismatch. database/sql does not hand scan destinations to drivers, so call it once before the first Scan
+// instead of discovering mismatches one column at a time.
+//
+// Validation is stricter than the conversions of database/sql: strings are only accepted for numeric, boolean or time
+// destinations if the column has that type. Destinations implementing sql.Scanner, *any, *string, *[]byte and
+// *sql.RawBytes are always accepted.
+func ValidateScan(rows *sql.Rows, dest ...any) error {
+	ctypes, err := rows.ColumnTypes()
+	if err != nil {
+		return err
+	}
+	if len(dest) != len(ctypes) {
+		return &ErrClientError{message: fmt.Sprintf("expected %d destination arguments in Scan, not %d", len(ctypes), len(dest))}
+	}
+
+	var mismatches []ScanMismatch
+	for i, ct := range ctypes {
+		serverType, _, _ := strings.Cut(ct.DatabaseTypeName(), ";")
+		if !scanCompatible(serverType, ct.ScanType(), reflect.TypeOf(dest[i])) {
+			mismatches = append(mismatches, ScanMismatch{Column: ct.Name(), ServerType: serverType, GoType: fmt.Sprintf("%T", dest[i])})
+		}
+	}
+	if len(mismatches) > 0 {
+		return &ErrScanMismatch{Mismatches: mismatches}
+	}
+	return nil
+}
+
+func scanCompatible(serverType string, scanType, destType reflect.Type) bool {
+	if destType == nil || destType.Kind() != reflect.Pointer {
+		return false
+	}
+	if destType.Implements(scannerType) {
+		return true
+	}
+	t := destType.Elem()
+	// pointer destinations receive nullable values
+	if t.Kind() == reflect.Pointer {
+		if t.Implements(scannerType) {
+			return true
+		}
+		t = t.Elem()
+	}
+
+	switch {
+	case t.Kind() == reflect.Interface && t.NumMethod() == 0,
+		t.Kind() == reflect.String,
+		t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8:
+		return true
+	case scanType == nil:
+		return false
+	// nullable time columns are scanned into sql.NullTime, but time.Time destinations hold their non null values
+	case scanType == nullTimeType:
+		scanType = typeMap["TIMESTAMP"]
+	// DATE values are sent as strings
+	case serverType == "DATE":
+		return false
+	}
+
+	switch scanType.Kind() {
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		switch t.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64:
+			return true
+		}
+		return false
+	case reflect.Float32, reflect.Float64:
+		return t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64
+	case reflect.Bool:
+		return t.Kind() == reflect.Bool
+	}
+	return t == scanType
+}
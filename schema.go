@@ -0,0 +1,85 @@
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package godeltastream
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/deltastreaminc/go-deltastream/apiv2"
+)
+
+// ErrSchemaChanged is returned by Next when the columns of a result change while it is read, e.g. between the
+// partitions of a result set or when a stream sends new metadata. Rows are not decoded with the new columns, as they
+// would no longer match the columns reported to the caller.
+type ErrSchemaChanged struct {
+	// Previous and Current describe the columns as "name TYPE" or "name TYPE NOT NULL".
+	Previous []string
+	Current  []string
+}
+
+func (e *ErrSchemaChanged) Error() string {
+	return fmt.Sprintf("result columns changed from (%s) to (%s)", strings.Join(e.Previous, ", "), strings.Join(e.Current, ", "))
+}
+
+// resultSchema is the description of the columns of a result, used to detect changes between fetches.
+type resultSchema struct {
+	columns     []string
+	fingerprint string
+}
+
+func newResultSchema(columns []string) resultSchema {
+	h := sha256.New()
+	for _, c := range columns {
+		h.Write([]byte(c))
+		h.Write([]byte{0})
+	}
+	return resultSchema{columns: columns, fingerprint: hex.EncodeToString(h.Sum(nil))}
+}
+
+func resultSetSchema(columns apiv2.ResultSetColumns) resultSchema {
+	desc := make([]string, len(columns))
+	for i, c := range columns {
+		desc[i] = describeColumn(c.Name, c.Type, c.Nullable)
+	}
+	return newResultSchema(desc)
+}
+
+func streamSchema(columns []PrintTopicColumn) resultSchema {
+	desc := make([]string, len(columns))
+	for i, c := range columns {
+		desc[i] = describeColumn(c.Name, c.Type, c.Nullable)
+	}
+	return newResultSchema(desc)
+}
+
+func describeColumn(name, colType string, nullable bool) string {
+	if nullable {
+		return name + " " + colType
+	}
+	return name + " " + colType + " NOT NULL"
+}
+
+// verify returns an *ErrSchemaChanged if current does not match s.
+func (s resultSchema) verify(current resultSchema) error {
+	if s.fingerprint == current.fingerprint {
+		return nil
+	}
+	return &ErrSchemaChanged{Previous: s.columns, Current: current.columns}
+}
@@ -0,0 +1,129 @@
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package godeltastream
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jarcoal/httpmock"
+	. "github.com/onsi/gomega"
+	"k8s.io/utils/ptr"
+
+	"github.com/deltastreaminc/go-deltastream/apiv2"
+	"github.com/deltastreaminc/go-deltastream/dstest"
+)
+
+// partitionsConn serves the partitions of a result set after the first one.
+type partitionsConn []*apiv2.ResultSet
+
+func (p partitionsConn) getStatement(ctx context.Context, statementID uuid.UUID, partitionID int32) (*apiv2.ResultSet, error) {
+	return p[partitionID], nil
+}
+
+func partition(columns apiv2.ResultSetColumns, partitionInfo []apiv2.ResultSetPartitionInfo, values ...string) *apiv2.ResultSet {
+	rs := &apiv2.ResultSet{}
+	rs.Metadata.Columns = columns
+	rs.Metadata.PartitionInfo = partitionInfo
+	data := [][]*string{}
+	for _, v := range values {
+		data = append(data, []*string{ptr.To(v)})
+	}
+	rs.Data = &data
+	return rs
+}
+
+func TestResultSetSchemaChanged(t *testing.T) {
+	g := NewWithT(t)
+
+	columns := apiv2.ResultSetColumns{{Name: "id", Type: "BIGINT"}}
+	changed := apiv2.ResultSetColumns{{Name: "id", Type: "VARCHAR", Nullable: true}}
+	partitionInfo := []apiv2.ResultSetPartitionInfo{{RowCount: 1}, {RowCount: 1}, {RowCount: 1}}
+
+	first := partition(columns, partitionInfo, "1")
+	rows := &resultSetRows{ctx: context.Background(), conn: partitionsConn{first, partition(nil, partitionInfo, "2"), partition(changed, partitionInfo, "x")}, currentRowIdx: -1, currentResultSet: first}
+
+	dest := make([]driver.Value, 1)
+	g.Expect(rows.Next(dest)).To(Succeed())
+	g.Expect(dest[0]).To(Equal(int64(1)))
+	// partitions without columns keep the columns of the first partition
+	g.Expect(rows.Next(dest)).To(Succeed())
+	g.Expect(dest[0]).To(Equal(int64(2)))
+	g.Expect(rows.Columns()).To(Equal([]string{"id"}))
+
+	err := rows.Next(dest)
+	var schemaErr *ErrSchemaChanged
+	g.Expect(errors.As(err, &schemaErr)).To(BeTrue())
+	g.Expect(schemaErr.Previous).To(Equal([]string{"id BIGINT NOT NULL"}))
+	g.Expect(schemaErr.Current).To(Equal([]string{"id VARCHAR"}))
+	g.Expect(err).To(MatchError("result columns changed from (id BIGINT NOT NULL) to (id VARCHAR)"))
+}
+
+func TestResultSetSchemaUnchanged(t *testing.T) {
+	g := NewWithT(t)
+
+	columns := apiv2.ResultSetColumns{{Name: "id", Type: "BIGINT"}}
+	partitionInfo := []apiv2.ResultSetPartitionInfo{{RowCount: 1}, {RowCount: 1}}
+	first := partition(columns, partitionInfo, "1")
+	rows := &resultSetRows{ctx: context.Background(), conn: partitionsConn{first, partition(columns, partitionInfo, "2")}, currentRowIdx: -1, currentResultSet: first}
+
+	dest := make([]driver.Value, 1)
+	g.Expect(rows.Next(dest)).To(Succeed())
+	g.Expect(rows.Next(dest)).To(Succeed())
+	g.Expect(dest[0]).To(Equal(int64(2)))
+	g.Expect(rows.Next(dest)).To(Equal(io.EOF))
+}
+
+func TestStreamingSchemaChanged(t *testing.T) {
+	g := NewWithT(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	server := dstest.NewStreamingServer(
+		dstest.Metadata(streamingColumns...),
+		dstest.Row("1", "a"),
+		// resent metadata with the same columns is ignored
+		dstest.Metadata(streamingColumns...),
+		dstest.Row("2", "b"),
+		dstest.Metadata(dstest.Column{Name: "id", Type: "VARCHAR"}),
+		dstest.Row("x"),
+	)
+	defer server.Close()
+
+	rows, err := queryStreamingServer(g, context.Background(), server)
+	g.Expect(err).To(BeNil())
+	defer rows.Close()
+
+	var (
+		id   int64
+		name string
+	)
+	for i := 1; i <= 2; i++ {
+		g.Expect(rows.Next()).To(BeTrue())
+		g.Expect(rows.Scan(&id, &name)).To(Succeed())
+		g.Expect(id).To(Equal(int64(i)))
+	}
+	g.Expect(rows.Next()).To(BeFalse())
+	var schemaErr *ErrSchemaChanged
+	g.Expect(errors.As(rows.Err(), &schemaErr)).To(BeTrue())
+	g.Expect(schemaErr.Previous).To(Equal([]string{"id BIGINT NOT NULL", "name VARCHAR"}))
+	g.Expect(schemaErr.Current).To(Equal([]string{"id VARCHAR NOT NULL"}))
+}
@@ -0,0 +1,106 @@
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package godeltastream
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/google/uuid"
+	"k8s.io/utils/ptr"
+)
+
+// SessionRecoveryHandler is called when a connection moved to a new session because the server invalidated the
+// session it was using, see WithSessionRecoveryHandler. conn runs in the new session, see Conn.SessionID, and can be
+// used to re-apply the state of the previous one, e.g. with USE or SET statements. Statements run by the handler are
+// not recovered again.
+type SessionRecoveryHandler func(ctx context.Context, conn *Conn, previousSessionID string) error
+
+// WithSessionRecoveryHandler re-establishes the session of connections when the server invalidates it, e.g. after the
+// access token was refreshed, and calls handler once the connection moved to the new session. handler may be nil.
+//
+// Sessions are detected as invalidated when a statement is rejected with an invalid parameter error about the session,
+// in which case the statement is submitted again in a new session if its attachments can be sent again, when polling a
+// statement fails the same way, in which case the statement is polled again, or when a streaming result is bound to a
+// session other than the one requested, see ErrSessionMismatch, in which case the connection adopts the session bound
+// by the server and the stream goes on. New sessions are identified by a new UUID unless bound by the server. Without
+// this option, these errors are returned as is.
+func WithSessionRecoveryHandler(handler SessionRecoveryHandler) func(*connectionOptions) {
+	return func(o *connectionOptions) {
+		o.sessionRecovery = true
+		o.sessionRecoveryHandler = handler
+	}
+}
+
+// sessionRejectedError is returned by sendStatement when the server rejected a statement because its session is not
+// valid anymore, on connections recovering their sessions. The statement did not run and may be submitted again.
+type sessionRejectedError struct {
+	err error
+}
+
+func (e *sessionRejectedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *sessionRejectedError) Unwrap() error {
+	return e.err
+}
+
+// isSessionLostError returns whether err reports that the session of the statement is not valid anymore.
+func isSessionLostError(err error) bool {
+	var sqlErr ErrSQLError
+	return errors.As(err, &sqlErr) && sqlErr.SQLCode == SqlStateInvalidParameter && strings.Contains(strings.ToLower(sqlErr.Message), "session")
+}
+
+// recovers returns whether the connection recovers its session from err, see WithSessionRecoveryHandler.
+func (c *Conn) recovers(ctx context.Context, err error) bool {
+	recovering, _ := ctx.Value(sessionRecoveryKey).(bool)
+	return c.sessionRecovery && !recovering && c.session() != nil && isSessionLostError(err)
+}
+
+// session returns the session statements of the connection run in, nil if they do not run in a session.
+func (c *Conn) session() *string {
+	c.RLock()
+	defer c.RUnlock()
+	return c.sessionID
+}
+
+// recoverSession moves the connection from the session lost to next, a new UUID if empty, and calls the recovery
+// handler. The connection is left as is if it already moved away from lost, e.g. recovered by its streaming results.
+func (c *Conn) recoverSession(ctx context.Context, lost, next string) error {
+	c.Lock()
+	if ptr.Deref(c.sessionID, "") != lost {
+		c.Unlock()
+		return nil
+	}
+	if next == "" {
+		next = uuid.NewString()
+	}
+	c.sessionID = &next
+	c.Unlock()
+
+	c.logDebug("session recovered", "previousSessionID", lost, "sessionID", next)
+	c.emit(ConnectionEvent{Type: ConnectionEventSessionRecovered, SessionID: next})
+	if c.sessionRecoveryHandler == nil {
+		return nil
+	}
+	if err := c.sessionRecoveryHandler(context.WithValue(ctx, sessionRecoveryKey, true), c, lost); err != nil {
+		return &ErrClientError{message: "unable to restore the state of session " + next, wrapErr: err}
+	}
+	return nil
+}
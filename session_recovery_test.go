@@ -0,0 +1,201 @@
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package godeltastream
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	. "github.com/onsi/gomega"
+
+	"github.com/deltastreaminc/go-deltastream/dstest"
+)
+
+// sessionLostResponse is the response to a statement submitted in a session the server invalidated.
+const sessionLostResponse = `{
+	"sqlState": "3D008",
+	"statementID": "d789687d-4e1b-4649-846e-4f10b722f3ad",
+	"createdOn": 1703907114,
+	"message": "invalid parameter sessionID: session s1 is not valid"
+}`
+
+// sessionResponder responds to statements submitted in session s1 with sessionLostResponse and to others with the
+// fixture, recording the statements and their sessions.
+func sessionResponder(g *WithT, submitted *[]string, fixture string) httpmock.Responder {
+	return func(r *http.Request) (*http.Response, error) {
+		_, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		g.Expect(err).To(BeNil())
+		p, err := multipart.NewReader(r.Body, params["boundary"]).NextPart()
+		g.Expect(err).To(BeNil())
+		req := struct {
+			Statement  string         `json:"statement"`
+			Parameters map[string]any `json:"parameters"`
+		}{}
+		g.Expect(json.NewDecoder(p).Decode(&req)).To(Succeed())
+		session, _ := req.Parameters["sessionID"].(string)
+		*submitted = append(*submitted, session+" "+req.Statement)
+		if session == "s1" {
+			return compareResponder(sessionLostResponse)(r)
+		}
+		return compareResponder(string(httpmock.File(fixture).Bytes()))(r)
+	}
+}
+
+func TestSessionRecovery(t *testing.T) {
+	g := NewWithT(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	var submitted []string
+	httpmock.RegisterResponder("POST", "https://api.deltastream.io/v2/statements", sessionResponder(g, &submitted, "fixtures/list-organizations-200-00000-1.json"))
+
+	var (
+		mu       sync.Mutex
+		events   []ConnectionEvent
+		previous []string
+	)
+	connector, err := ConnectorWithOptions(context.TODO(), WithServer("https://api.deltastream.io/v2"), WithStaticToken("sometoken"), WithSessionID("s1"),
+		WithConnectionEventHandler(func(e ConnectionEvent) {
+			mu.Lock()
+			defer mu.Unlock()
+			if e.Type == ConnectionEventSessionRecovered {
+				events = append(events, e)
+			}
+		}),
+		WithSessionRecoveryHandler(func(ctx context.Context, conn *Conn, previousSessionID string) error {
+			previous = append(previous, previousSessionID)
+			_, err := conn.ExecContext(ctx, "USE DATABASE analytics;", nil)
+			return err
+		}))
+	g.Expect(err).To(BeNil())
+	conn, err := sql.OpenDB(connector).Conn(context.TODO())
+	g.Expect(err).To(BeNil())
+	defer conn.Close()
+
+	rows, err := conn.QueryContext(context.TODO(), "LIST ORGANIZATIONS;")
+	g.Expect(err).To(BeNil())
+	g.Expect(rows.Close()).To(Succeed())
+
+	var sessionID string
+	g.Expect(conn.Raw(func(driverConn any) error {
+		sessionID = driverConn.(*Conn).SessionID()
+		return nil
+	})).To(Succeed())
+	g.Expect(sessionID).ToNot(BeEmpty())
+	g.Expect(sessionID).ToNot(Equal("s1"))
+
+	// the state of the session is restored before the statement is submitted again in the new session
+	g.Expect(submitted).To(Equal([]string{
+		"s1 LIST ORGANIZATIONS;",
+		sessionID + " USE DATABASE analytics;",
+		sessionID + " LIST ORGANIZATIONS;",
+	}))
+	g.Expect(previous).To(Equal([]string{"s1"}))
+	g.Expect(events).To(HaveLen(1))
+	g.Expect(events[0].SessionID).To(Equal(sessionID))
+
+	// failing to restore the state fails the statement
+	errRestore := errors.New("restore failed")
+	connector, err = ConnectorWithOptions(context.TODO(), WithServer("https://api.deltastream.io/v2"), WithStaticToken("sometoken"), WithSessionID("s1"),
+		WithSessionRecoveryHandler(func(context.Context, *Conn, string) error { return errRestore }))
+	g.Expect(err).To(BeNil())
+	db := sql.OpenDB(connector)
+	defer db.Close()
+	_, err = db.QueryContext(context.TODO(), "LIST ORGANIZATIONS;")
+	g.Expect(errors.Is(err, errRestore)).To(BeTrue())
+
+	// without recovery the error is returned as is
+	connector, err = ConnectorWithOptions(context.TODO(), WithServer("https://api.deltastream.io/v2"), WithStaticToken("sometoken"), WithSessionID("s1"))
+	g.Expect(err).To(BeNil())
+	db = sql.OpenDB(connector)
+	defer db.Close()
+	_, err = db.QueryContext(context.TODO(), "LIST ORGANIZATIONS;")
+	var sqlErr ErrSQLError
+	g.Expect(errors.As(err, &sqlErr)).To(BeTrue())
+	g.Expect(sqlErr.SQLCode).To(Equal(SqlStateInvalidParameter))
+}
+
+func TestSessionRecoveryWhilePolling(t *testing.T) {
+	g := NewWithT(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("POST", "https://api.deltastream.io/v2/statements", mockSubmitStatementsResponser(g, http.StatusAccepted, "sometoken", "LIST ORGANIZATIONS;", nil, "fixtures/list-organizations-202-03000.json"))
+	var polled []string
+	httpmock.RegisterResponder("GET", `=~^https://api\.deltastream\.io/v2/statements/d789687d-4e1b-4649-846e-4f10b722f3ad`, func(r *http.Request) (*http.Response, error) {
+		session := r.URL.Query().Get("sessionID")
+		polled = append(polled, session)
+		if session == "s1" {
+			return compareResponder(sessionLostResponse)(r)
+		}
+		return mockGetStatementResponser(g, http.StatusOK, "sometoken", "fixtures/list-organizations-200-00000-1.json")(r)
+	})
+
+	connector, err := ConnectorWithOptions(context.TODO(), WithServer("https://api.deltastream.io/v2"), WithStaticToken("sometoken"), WithSessionID("s1"), WithSessionRecoveryHandler(nil))
+	g.Expect(err).To(BeNil())
+	db := sql.OpenDB(connector)
+	defer db.Close()
+
+	// the statement is polled again in the new session rather than submitted again
+	rows, err := db.QueryContext(context.TODO(), "LIST ORGANIZATIONS;")
+	g.Expect(err).To(BeNil())
+	g.Expect(rows.Close()).To(Succeed())
+	g.Expect(httpmock.GetCallCountInfo()["POST https://api.deltastream.io/v2/statements"]).To(Equal(1))
+	g.Expect(polled).To(HaveLen(2))
+	g.Expect(polled[0]).To(Equal("s1"))
+	g.Expect(polled[1]).ToNot(BeElementOf("s1", ""))
+}
+
+func TestSessionRecoveryOfStreams(t *testing.T) {
+	g := NewWithT(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	server := dstest.NewStreamingServer(dstest.SessionAck("s2"), dstest.Metadata(streamingColumns...), dstest.Row("1", "a"))
+	defer server.Close()
+	httpmock.RegisterResponder("POST", "https://api.deltastream.io/v2/statements", httpmock.NewJsonResponderOrPanic(http.StatusOK, server.StatementResponse(nil)))
+
+	recovered := make(chan string, 1)
+	connector, err := ConnectorWithOptions(context.TODO(), WithServer("https://api.deltastream.io/v2"), WithStaticToken("sometoken"), WithSessionID("s1"),
+		WithSessionRecoveryHandler(func(_ context.Context, conn *Conn, previousSessionID string) error {
+			recovered <- previousSessionID + " -> " + conn.SessionID()
+			return nil
+		}))
+	g.Expect(err).To(BeNil())
+	conn, err := sql.OpenDB(connector).Conn(context.TODO())
+	g.Expect(err).To(BeNil())
+	defer conn.Close()
+
+	// the stream goes on in the session bound by the server
+	rows, err := conn.QueryContext(context.TODO(), "SELECT * FROM pageviews;")
+	g.Expect(err).To(BeNil())
+	g.Expect(rows.Next()).To(BeTrue())
+	g.Expect(rows.Close()).To(Succeed())
+	g.Expect(<-recovered).To(Equal("s1 -> s2"))
+	g.Expect(conn.Raw(func(driverConn any) error {
+		g.Expect(driverConn.(*Conn).SessionID()).To(Equal("s2"))
+		return nil
+	})).To(Succeed())
+}
@@ -0,0 +1,132 @@
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package godeltastream
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/utils/ptr"
+
+	"github.com/deltastreaminc/go-deltastream/apiv2"
+)
+
+// Compile time validation that our types implement the expected interfaces
+var (
+	_ fmt.Stringer = SessionContext{}
+	_ fmt.Stringer = SessionContextChange{}
+)
+
+// SessionContext is the organization, database, schema, role, store and compute pool statements of a connection run
+// in. Empty fields are not set.
+type SessionContext struct {
+	Organization string
+	Database     string
+	Schema       string
+	Role         string
+	Store        string
+	ComputePool  string
+}
+
+// NewSessionContext converts a result set context returned by the server into a SessionContext.
+func NewSessionContext(rsctx apiv2.ResultSetContext) SessionContext {
+	c := SessionContext{
+		Database:    ptr.Deref(rsctx.DatabaseName, ""),
+		Schema:      ptr.Deref(rsctx.SchemaName, ""),
+		Role:        ptr.Deref(rsctx.RoleName, ""),
+		Store:       ptr.Deref(rsctx.StoreName, ""),
+		ComputePool: ptr.Deref(rsctx.ComputePoolName, ""),
+	}
+	if rsctx.OrganizationID != nil {
+		c.Organization = rsctx.OrganizationID.String()
+	}
+	return c
+}
+
+// String returns the context in the form org/db.schema role=... store=... computePool=..., omitting empty fields.
+func (c SessionContext) String() string {
+	var parts []string
+
+	path := c.Organization
+	if c.Database != "" {
+		if path != "" {
+			path += "/"
+		}
+		path += c.Database
+		if c.Schema != "" {
+			path += "." + c.Schema
+		}
+	}
+	if path != "" {
+		parts = append(parts, path)
+	}
+	if c.Role != "" {
+		parts = append(parts, "role="+c.Role)
+	}
+	if c.Store != "" {
+		parts = append(parts, "store="+c.Store)
+	}
+	if c.ComputePool != "" {
+		parts = append(parts, "computePool="+c.ComputePool)
+	}
+	return strings.Join(parts, " ")
+}
+
+// Equal returns true if both contexts are the same.
+func (c SessionContext) Equal(o SessionContext) bool {
+	return c == o
+}
+
+// SessionContextChange is a field of a SessionContext that changed.
+type SessionContextChange struct {
+	Field string
+	Old   string
+	New   string
+}
+
+// String returns the change in the form field: old -> new.
+func (c SessionContextChange) String() string {
+	return fmt.Sprintf("%s: %s -> %s", c.Field, valueOrNone(c.Old), valueOrNone(c.New))
+}
+
+func valueOrNone(v string) string {
+	if v == "" {
+		return "<none>"
+	}
+	return v
+}
+
+// DiffSessionContext returns the fields that changed between old and new, e.g. to show the effect of a USE statement.
+func DiffSessionContext(old, new SessionContext) []SessionContextChange {
+	var changes []SessionContextChange
+	for _, f := range []struct {
+		name     string
+		old, new string
+	}{
+		{"organization", old.Organization, new.Organization},
+		{"database", old.Database, new.Database},
+		{"schema", old.Schema, new.Schema},
+		{"role", old.Role, new.Role},
+		{"store", old.Store, new.Store},
+		{"computePool", old.ComputePool, new.ComputePool},
+	} {
+		if f.old != f.new {
+			changes = append(changes, SessionContextChange{Field: f.name, Old: f.old, New: f.new})
+		}
+	}
+	return changes
+}
@@ -0,0 +1,99 @@
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package godeltastream
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jarcoal/httpmock"
+	. "github.com/onsi/gomega"
+	"k8s.io/utils/ptr"
+
+	"github.com/deltastreaminc/go-deltastream/apiv2"
+)
+
+func TestSessionContext(t *testing.T) {
+	g := NewWithT(t)
+
+	orgID := uuid.MustParse("0e0e3617-3cd6-4407-a189-97daf226c4d4")
+	old := NewSessionContext(apiv2.ResultSetContext{
+		OrganizationID: &orgID,
+		DatabaseName:   ptr.To("db"),
+		SchemaName:     ptr.To("public"),
+		RoleName:       ptr.To("sysadmin"),
+		StoreName:      ptr.To("kafka"),
+	})
+	g.Expect(old.String()).To(Equal("0e0e3617-3cd6-4407-a189-97daf226c4d4/db.public role=sysadmin store=kafka"))
+	g.Expect(SessionContext{}.String()).To(BeEmpty())
+	g.Expect(SessionContext{Role: "r"}.String()).To(Equal("role=r"))
+
+	new := old
+	g.Expect(old.Equal(new)).To(BeTrue())
+	g.Expect(DiffSessionContext(old, new)).To(BeEmpty())
+
+	new.Schema = "analytics"
+	new.Store = ""
+	g.Expect(old.Equal(new)).To(BeFalse())
+	changes := DiffSessionContext(old, new)
+	g.Expect(changes).To(Equal([]SessionContextChange{
+		{Field: "schema", Old: "public", New: "analytics"},
+		{Field: "store", Old: "kafka", New: ""},
+	}))
+	g.Expect(changes[1].String()).To(Equal("store: kafka -> <none>"))
+}
+
+func TestPinnedContext(t *testing.T) {
+	g := NewWithT(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("POST", "https://api.deltastream.io/v2/statements",
+		mockSubmitStatementsResponser(g, http.StatusOK, "sometoken", "USE DATABASE analytics;", map[string][]byte{}, "fixtures/use-database-200-00000.json"),
+	)
+
+	for _, pinned := range []bool{false, true} {
+		options := []ConnectionOption{WithServer("https://api.deltastream.io/v2"), WithStaticToken("sometoken")}
+		if pinned {
+			options = append(options, WithPinnedContext())
+		}
+		connector, err := ConnectorWithOptions(context.TODO(), options...)
+		g.Expect(err).To(BeNil())
+		conn, err := sql.OpenDB(connector).Conn(context.Background())
+		g.Expect(err).To(BeNil())
+
+		_, err = conn.ExecContext(context.Background(), "USE DATABASE analytics;")
+		g.Expect(err).To(BeNil())
+
+		g.Expect(conn.Raw(func(driverConn any) error {
+			c := driverConn.(*Conn)
+			if !pinned {
+				g.Expect(c.SessionContext()).To(Equal(SessionContext{Database: "analytics", Schema: "public"}))
+				return nil
+			}
+			g.Expect(c.SessionContext()).To(Equal(SessionContext{}))
+			g.Expect(c.AcceptServerContext()).To(BeTrue())
+			g.Expect(c.SessionContext()).To(Equal(SessionContext{Database: "analytics", Schema: "public"}))
+			g.Expect(c.AcceptServerContext()).To(BeFalse())
+			return nil
+		})).To(Succeed())
+		g.Expect(conn.Close()).To(Succeed())
+	}
+}
@@ -0,0 +1,153 @@
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package godeltastream
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// MultiStatementMode selects how queries of more than one statement are submitted, see WithMultiStatementMode.
+type MultiStatementMode int
+
+const (
+	// MultiStatementScript submits queries of several statements as a single script, leaving them to the server.
+	MultiStatementScript MultiStatementMode = iota
+	// MultiStatementReject fails queries of several statements with an *ErrMultiStatement, without submitting them.
+	MultiStatementReject
+	// MultiStatementSequential submits the statements of a query one at a time, stopping at the first failure with an
+	// *ErrSequentialStatement. Exec returns the result of the last statement and Query its rows.
+	MultiStatementSequential
+)
+
+// WithMultiStatementMode sets how queries of more than one statement are submitted, MultiStatementScript by default.
+// Statements are split by SplitStatements once arguments are bound and interceptors applied.
+//
+// With MultiStatementSequential, attachments are sent with the first statement referencing them, see Attachment, or
+// with the first statement if none does. Within a transaction, each statement is buffered on its own.
+func WithMultiStatementMode(mode MultiStatementMode) func(*connectionOptions) {
+	return func(o *connectionOptions) {
+		o.multiStatementMode = mode
+	}
+}
+
+// ErrMultiStatement is returned for queries of more than one statement on connections using MultiStatementReject.
+type ErrMultiStatement struct {
+	// Statements are the statements of the query, see SplitStatements.
+	Statements []string
+}
+
+func (e *ErrMultiStatement) Error() string {
+	return fmt.Sprintf("query has %d statements, only one is accepted", len(e.Statements))
+}
+
+// ErrSequentialStatement is returned when a statement of a query submitted with MultiStatementSequential fails.
+// Statements before it were applied.
+type ErrSequentialStatement struct {
+	// Index of the failed statement in the query, starting at 0.
+	Index int
+	// Statement is the failed statement.
+	Statement string
+	wrapErr   error
+}
+
+func (e *ErrSequentialStatement) Error() string {
+	return fmt.Sprintf("statement %d of query failed: %s", e.Index+1, e.wrapErr)
+}
+
+func (e *ErrSequentialStatement) Unwrap() error {
+	return e.wrapErr
+}
+
+// SplitStatements splits query into its statements at the semicolons outside of quoted strings, quoted identifiers and
+// comments. Statements are trimmed and keep their semicolon. Statements of nothing but whitespace and comments, e.g. a
+// comment after the last statement, are dropped.
+func SplitStatements(query string) []string {
+	var (
+		statements []string
+		start      int
+		content    bool // whether the current statement has more than whitespace and comments
+	)
+	split := func(end int) {
+		if content {
+			statements = append(statements, strings.TrimSpace(query[start:end]))
+		}
+		start, content = end, false
+	}
+	for _, seg := range lexSQL(query) {
+		switch seg.kind {
+		case sqlSegmentQuoted:
+			content = true
+		case sqlSegmentCode:
+			for i := seg.start; i < seg.end; i++ {
+				switch c := query[i]; {
+				case c == ';':
+					split(i + 1)
+				case !unicode.IsSpace(rune(c)):
+					content = true
+				}
+			}
+		}
+	}
+	split(len(query))
+	return statements
+}
+
+// splitQuery returns the statements of query to submit one at a time with their attachments, or nil if query is
+// submitted as is, see WithMultiStatementMode.
+func (c *Conn) splitQuery(query string, attachments []Attachment) ([]string, [][]Attachment, error) {
+	if c.multiStatementMode == MultiStatementScript {
+		return nil, nil, nil
+	}
+	statements := SplitStatements(query)
+	if len(statements) < 2 {
+		return nil, nil, nil
+	}
+	if c.multiStatementMode == MultiStatementReject {
+		return nil, nil, &ErrMultiStatement{Statements: statements}
+	}
+
+	split := make([][]Attachment, len(statements))
+	for _, a := range attachments {
+		i := 0
+		for j, s := range statements {
+			if slices.Contains(referencedAttachments(s), a.Name) {
+				i = j
+				break
+			}
+		}
+		split[i] = append(split[i], a)
+	}
+	return statements, split, nil
+}
+
+// execStatements executes statements one at a time with their attachments, stopping at the first failure, and returns
+// the result of the last one.
+func (c *Conn) execStatements(ctx context.Context, statements []string, attachments [][]Attachment) (driver.Result, error) {
+	var res driver.Result
+	for i, s := range statements {
+		var err error
+		if res, err = c.exec(ctx, s, attachments[i]); err != nil {
+			return nil, &ErrSequentialStatement{Index: i, Statement: s, wrapErr: err}
+		}
+	}
+	return res, nil
+}
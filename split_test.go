@@ -0,0 +1,161 @@
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package godeltastream
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	. "github.com/onsi/gomega"
+
+	"github.com/deltastreaminc/go-deltastream/apiv2"
+)
+
+func TestSplitStatements(t *testing.T) {
+	g := NewWithT(t)
+
+	for query, expected := range map[string][]string{
+		"":                               nil,
+		"  -- nothing\n ":                nil,
+		"LIST DATABASES":                 {"LIST DATABASES"},
+		"LIST DATABASES; ;\n":            {"LIST DATABASES;"},
+		"USE DATABASE db; LIST SCHEMAS;": {"USE DATABASE db;", "LIST SCHEMAS;"},
+		"INSERT INTO s VALUES ('a;b', 'it''s;');\nLIST STREAMS;":        {"INSERT INTO s VALUES ('a;b', 'it''s;');", "LIST STREAMS;"},
+		`SELECT "a;b", ` + "`c;d`" + ` FROM s; LIST STREAMS;`:           {`SELECT "a;b", ` + "`c;d`" + ` FROM s;`, "LIST STREAMS;"},
+		"LIST STREAMS; -- done; really\n":                               {"LIST STREAMS;"},
+		"LIST STREAMS; /* one; two */ LIST STORES;":                     {"LIST STREAMS;", "/* one; two */ LIST STORES;"},
+		"-- first; statement\nLIST STREAMS;\n-- second\nLIST STORES;  ": {"-- first; statement\nLIST STREAMS;", "-- second\nLIST STORES;"},
+	} {
+		g.Expect(SplitStatements(query)).To(Equal(expected), query)
+	}
+}
+
+func TestMultiStatementMode(t *testing.T) {
+	g := NewWithT(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	// records the statements submitted with the names of their attachments
+	var submitted []string
+	httpmock.RegisterResponder("POST", "https://api.deltastream.io/v2/statements", func(r *http.Request) (*http.Response, error) {
+		_, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		g.Expect(err).To(BeNil())
+		mr := multipart.NewReader(r.Body, params["boundary"])
+		p, err := mr.NextPart()
+		g.Expect(err).To(BeNil())
+		req := &apiv2.SubmitStatementJSONRequestBody{}
+		g.Expect(json.NewDecoder(p).Decode(req)).To(Succeed())
+		entry := req.Statement
+		for p, err = mr.NextPart(); err == nil; p, err = mr.NextPart() {
+			entry += " +" + p.FileName()
+		}
+		submitted = append(submitted, entry)
+
+		fixture := "fixtures/use-database-200-00000.json"
+		switch {
+		case strings.HasPrefix(req.Statement, "CREATE STREAM broken"):
+			fixture = "fixtures/create-stream-200-3E003.json"
+		case strings.HasPrefix(req.Statement, "LIST ORGANIZATIONS"):
+			fixture = "fixtures/list-organizations-200-00000-1.json"
+		}
+		f, err := os.Open(fixture)
+		g.Expect(err).To(BeNil())
+		return &http.Response{StatusCode: http.StatusOK, Body: f, Header: http.Header{"Content-Type": []string{"application/json"}}}, nil
+	})
+	open := func(mode MultiStatementMode) *sql.DB {
+		connector, err := ConnectorWithOptions(context.TODO(), WithServer("https://api.deltastream.io/v2"), WithStaticToken("sometoken"), WithMultiStatementMode(mode))
+		g.Expect(err).To(BeNil())
+		return sql.OpenDB(connector)
+	}
+	script := "USE DATABASE db;\nLIST ORGANIZATIONS; -- the organizations"
+
+	// scripts are submitted as is by default
+	db := open(MultiStatementScript)
+	defer db.Close()
+	_, err := db.Exec(script)
+	g.Expect(err).To(BeNil())
+	g.Expect(submitted).To(Equal([]string{script}))
+
+	// rejected without being submitted
+	submitted = nil
+	db = open(MultiStatementReject)
+	defer db.Close()
+	_, err = db.Query(script)
+	var multiErr *ErrMultiStatement
+	g.Expect(errors.As(err, &multiErr)).To(BeTrue())
+	g.Expect(multiErr.Statements).To(Equal([]string{"USE DATABASE db;", "LIST ORGANIZATIONS;"}))
+	g.Expect(err.Error()).To(Equal("query has 2 statements, only one is accepted"))
+	// single statements are accepted
+	_, err = db.Exec("USE DATABASE db; -- the database")
+	g.Expect(err).To(BeNil())
+	g.Expect(submitted).To(Equal([]string{"USE DATABASE db; -- the database"}))
+
+	// submitted one at a time, queries return the rows of the last statement
+	submitted = nil
+	db = open(MultiStatementSequential)
+	defer db.Close()
+	rows, err := db.Query(script)
+	g.Expect(err).To(BeNil())
+	g.Expect(rows.Next()).To(BeTrue())
+	var id, name string
+	var ignored any
+	g.Expect(rows.Scan(&id, &name, &ignored, &ignored, &ignored)).To(Succeed())
+	g.Expect(name).To(Equal("o1"))
+	g.Expect(rows.Close()).To(Succeed())
+	g.Expect(submitted).To(Equal([]string{"USE DATABASE db;", "LIST ORGANIZATIONS;"}))
+
+	// attachments are sent with the statements referencing them, and stop at the first failure
+	submitted = nil
+	ctx := WithAttachments(context.Background(),
+		Attachment{Name: "schema.desc", Reader: io.NopCloser(strings.NewReader("schema"))},
+		Attachment{Name: "other.desc", Reader: io.NopCloser(strings.NewReader("other"))},
+	)
+	_, err = db.ExecContext(ctx, `USE DATABASE db;
+		CREATE DESCRIPTOR_SOURCE ds WITH ('file' = 'schema.desc');
+		CREATE STREAM broken AS SELECT * FROM pageviews;
+		LIST STREAMS;`)
+	var seqErr *ErrSequentialStatement
+	g.Expect(errors.As(err, &seqErr)).To(BeTrue())
+	g.Expect(seqErr.Index).To(Equal(2))
+	g.Expect(seqErr.Statement).To(Equal("CREATE STREAM broken AS SELECT * FROM pageviews;"))
+	g.Expect(errors.Is(err, ErrSQLError{SQLCode: "3E003"})).To(BeTrue())
+	g.Expect(submitted).To(Equal([]string{
+		"USE DATABASE db; +other.desc",
+		"CREATE DESCRIPTOR_SOURCE ds WITH ('file' = 'schema.desc'); +schema.desc",
+		"CREATE STREAM broken AS SELECT * FROM pageviews;",
+	}))
+
+	// statements of a transaction are buffered one at a time
+	submitted = nil
+	tx, err := db.Begin()
+	g.Expect(err).To(BeNil())
+	_, err = tx.Exec("CREATE DATABASE analytics; CREATE SCHEMA analytics.reporting;")
+	g.Expect(err).To(BeNil())
+	g.Expect(submitted).To(BeEmpty())
+	g.Expect(tx.Commit()).To(Succeed())
+	g.Expect(submitted).To(Equal([]string{"CREATE DATABASE analytics;", "CREATE SCHEMA analytics.reporting;"}))
+}
@@ -16,8 +16,18 @@ limitations under the License.
 
 package godeltastream
 
+//go:generate go run sqlstates_gen.go
+
 type SqlState string
 
+// Class returns the two character class of the SqlState, e.g. "3E" for resource not ready errors.
+func (s SqlState) Class() string {
+	if len(s) < 2 {
+		return string(s)
+	}
+	return string(s[:2])
+}
+
 const (
 	SqlState00000  SqlState = "00000"
 	SqlState01000  SqlState = "01000"
@@ -0,0 +1,67 @@
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package godeltastream
+
+import "sync"
+
+// SqlStateInfo describes a SqlState, e.g. to render friendly titles of errors.
+type SqlStateInfo struct {
+	Code SqlState
+	// Name is the name of the SqlState constant without its prefix, e.g. InvalidApiToken, or Unknown.
+	Name string
+	// Class is the two character class of the code, e.g. 3D.
+	Class string
+	// ClassName describes the class, e.g. Invalid Objects. It is empty for unknown classes.
+	ClassName string
+	// Description is a human readable description of the code, e.g. Invalid API token.
+	Description string
+}
+
+// SqlStates returns the SqlStates known to this version of the driver, grouped by class. The registry is generated
+// from the named constants of sqlcode.go.
+func SqlStates() []SqlStateInfo {
+	return append([]SqlStateInfo(nil), sqlStateRegistry...)
+}
+
+var (
+	sqlStateIndexOnce sync.Once
+	sqlStateIndex     map[SqlState]SqlStateInfo
+	sqlStateClasses   map[string]string
+)
+
+// Info describes the SqlState. Codes unknown to this version of the driver, e.g. sent by a newer server, are described
+// by their class if it is known and as Unknown otherwise.
+func (s SqlState) Info() SqlStateInfo {
+	sqlStateIndexOnce.Do(func() {
+		sqlStateIndex = make(map[SqlState]SqlStateInfo, len(sqlStateRegistry))
+		sqlStateClasses = map[string]string{}
+		for _, info := range sqlStateRegistry {
+			sqlStateIndex[info.Code] = info
+			sqlStateClasses[info.Class] = info.ClassName
+		}
+	})
+	if info, ok := sqlStateIndex[s]; ok {
+		return info
+	}
+	info := SqlStateInfo{Code: s, Name: "Unknown", Class: s.Class(), ClassName: sqlStateClasses[s.Class()]}
+	if info.ClassName != "" {
+		info.Description = info.ClassName + " (SQLSTATE " + string(s) + ")"
+	} else {
+		info.Description = "Unknown error (SQLSTATE " + string(s) + ")"
+	}
+	return info
+}
@@ -0,0 +1,140 @@
+//go:build ignore
+
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// sqlstates_gen.go generates zz_generated.sqlstates.go, the registry of the named SqlStates of sqlcode.go returned by
+// SqlStates. Run it with go generate after changing sqlcode.go.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+var classCommentRegexp = regexp.MustCompile(`^//\s*Class (\w{2}) — (.+)$`)
+
+// classNoteRegexp matches the notes following class names, e.g. (not found errors).
+var classNoteRegexp = regexp.MustCompile(`\s*\(.*\)\s*$`)
+
+// acronyms are the words of SqlState names written in upper case in descriptions.
+var acronyms = map[string]string{"Api": "API", "Sql": "SQL"}
+
+type sqlState struct {
+	name      string
+	class     string
+	className string
+}
+
+func main() {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "sqlcode.go", nil, parser.ParseComments)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var states []sqlState
+	for _, decl := range f.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.CONST {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			vs := spec.(*ast.ValueSpec)
+			// the named SqlStates are aliases of the SqlStates declared by code
+			if len(vs.Values) != 1 || len(vs.Names) != 1 {
+				continue
+			}
+			if id, ok := vs.Values[0].(*ast.Ident); !ok || !strings.HasPrefix(id.Name, "SqlState") {
+				continue
+			}
+			class, className := classOf(f, vs.Pos())
+			if class == "" {
+				log.Fatalf("%s has no class comment", vs.Names[0].Name)
+			}
+			states = append(states, sqlState{name: vs.Names[0].Name, class: class, className: className})
+		}
+	}
+
+	var b bytes.Buffer
+	header, err := os.ReadFile("sqlcode.go")
+	if err != nil {
+		log.Fatal(err)
+	}
+	// reuse the license header of sqlcode.go
+	b.Write(header[:bytes.Index(header, []byte("*/"))+3])
+	b.WriteString("\n// Code generated by sqlstates_gen.go. DO NOT EDIT.\n\npackage godeltastream\n\n")
+	b.WriteString("var sqlStateRegistry = []SqlStateInfo{\n")
+	for _, s := range states {
+		name := strings.TrimPrefix(s.name, "SqlState")
+		fmt.Fprintf(&b, "\t{Code: %s, Name: %q, Class: %q, ClassName: %q, Description: %q},\n", s.name, name, s.class, s.className, describe(name))
+	}
+	b.WriteString("}\n")
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err = os.WriteFile("zz_generated.sqlstates.go", src, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// classOf returns the class of the constant at pos, from the last class comment preceding it.
+func classOf(f *ast.File, pos token.Pos) (string, string) {
+	var class, className string
+	for _, group := range f.Comments {
+		if group.Pos() > pos {
+			break
+		}
+		for _, c := range group.List {
+			if m := classCommentRegexp.FindStringSubmatch(c.Text); m != nil {
+				class, className = m[1], classNoteRegexp.ReplaceAllString(m[2], "")
+			}
+		}
+	}
+	return class, className
+}
+
+// describe turns a name such as InvalidApiToken into a description such as Invalid API token.
+func describe(name string) string {
+	var words []string
+	start := 0
+	for i, r := range name {
+		if i > 0 && unicode.IsUpper(r) {
+			words = append(words, name[start:i])
+			start = i
+		}
+	}
+	words = append(words, name[start:])
+	for i, w := range words {
+		switch {
+		case acronyms[w] != "":
+			words[i] = acronyms[w]
+		case i > 0:
+			words[i] = strings.ToLower(w)
+		}
+	}
+	return strings.Join(words, " ")
+}
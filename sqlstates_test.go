@@ -0,0 +1,60 @@
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package godeltastream
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestSqlStates(t *testing.T) {
+	g := NewWithT(t)
+
+	// the registry is in sync with the named SqlStates of sqlcode.go, run go generate otherwise
+	f, err := parser.ParseFile(token.NewFileSet(), "sqlcode.go", nil, 0)
+	g.Expect(err).To(BeNil())
+	var declared []string
+	ast.Inspect(f, func(n ast.Node) bool {
+		if vs, ok := n.(*ast.ValueSpec); ok && len(vs.Values) == 1 {
+			if _, ok := vs.Values[0].(*ast.Ident); ok {
+				declared = append(declared, vs.Names[0].Name[len("SqlState"):])
+			}
+		}
+		return true
+	})
+	var registered []string
+	for _, info := range SqlStates() {
+		registered = append(registered, info.Name)
+	}
+	g.Expect(registered).To(Equal(declared))
+
+	info := SqlStateInvalidApiToken.Info()
+	g.Expect(info).To(Equal(SqlStateInfo{Code: "3D012", Name: "InvalidApiToken", Class: "3D", ClassName: "Invalid Objects", Description: "Invalid API token"}))
+	g.Expect(SqlStateSqlStatementNotYetComplete.Info().Description).To(Equal("SQL statement not yet complete"))
+
+	// unknown codes are described by their class
+	g.Expect(SqlState("3D999").Info()).To(Equal(SqlStateInfo{Code: "3D999", Name: "Unknown", Class: "3D", ClassName: "Invalid Objects", Description: "Invalid Objects (SQLSTATE 3D999)"}))
+	g.Expect(SqlState("ZZ001").Info()).To(Equal(SqlStateInfo{Code: "ZZ001", Name: "Unknown", Class: "ZZ", Description: "Unknown error (SQLSTATE ZZ001)"}))
+
+	// the returned registry is a copy
+	SqlStates()[0].Name = "changed"
+	g.Expect(SqlStates()[0].Name).To(Equal("SuccessfulCompletion"))
+}
@@ -19,6 +19,9 @@ package godeltastream
 import (
 	"context"
 	"database/sql/driver"
+	"fmt"
+	"strconv"
+	"strings"
 )
 
 // Compile time validation that our types implement the expected interfaces
@@ -29,9 +32,10 @@ var (
 )
 
 type statement struct {
-	c      *Conn
-	query  string
-	isOpen bool
+	c        *Conn
+	query    string
+	numInput int
+	isOpen   bool
 }
 
 // Close implements driver.Stmt.
@@ -48,22 +52,6 @@ func (s *statement) Exec(args []driver.Value) (driver.Result, error) {
 	return s.c.Exec(s.query, args)
 }
 
-type result struct {
-}
-
-// LastInsertId returns the database's auto-generated ID
-// after, for example, an INSERT into a table with primary
-// key.
-func (r *result) LastInsertId() (int64, error) {
-	return -1, nil
-}
-
-// RowsAffected returns the number of rows affected by the
-// query.
-func (r *result) RowsAffected() (int64, error) {
-	return -1, nil
-}
-
 // ExecContext executes a query that doesn't return rows, such
 // as an INSERT or UPDATE.
 //
@@ -76,12 +64,13 @@ func (s *statement) ExecContext(ctx context.Context, args []driver.NamedValue) (
 	return s.c.ExecContext(ctx, s.query, args)
 }
 
-// NumInput implements driver.Stmt.
+// NumInput implements driver.Stmt. It returns the number of ? or $n placeholders of the statement, or -1 if it has
+// none so that attachments can still be passed as arguments.
 func (s *statement) NumInput() int {
 	if !s.isOpen {
 		return 0
 	}
-	return 0
+	return s.numInput
 }
 
 // Query implements driver.Stmt.
@@ -103,3 +92,109 @@ func (s *statement) QueryContext(ctx context.Context, args []driver.NamedValue)
 
 	return s.c.QueryContext(ctx, s.query, args)
 }
+
+// placeholder is a ? or $n placeholder at query[start:end]. n is 0 for ? placeholders.
+type placeholder struct {
+	start, end, n int
+}
+
+// findPlaceholders returns the ? and $n placeholders of query. Placeholders in string literals, quoted identifiers
+// and comments are ignored.
+func findPlaceholders(query string) []placeholder {
+	var placeholders []placeholder
+	for _, seg := range lexSQL(query) {
+		if seg.kind != sqlSegmentCode {
+			continue
+		}
+		for i := seg.start; i < seg.end; i++ {
+			switch query[i] {
+			case '?':
+				placeholders = append(placeholders, placeholder{start: i, end: i + 1})
+			case '$':
+				j := i + 1
+				for ; j < seg.end && query[j] >= '0' && query[j] <= '9'; j++ {
+				}
+				if n, err := strconv.Atoi(query[i+1 : j]); err == nil && n > 0 {
+					placeholders = append(placeholders, placeholder{start: i, end: j, n: n})
+				}
+				i = j - 1
+			}
+		}
+	}
+	return placeholders
+}
+
+// countPlaceholders returns the number of placeholders in query: the number of ? placeholders, or the highest n of
+// $n placeholders. It returns -1 if query has no placeholders.
+func countPlaceholders(query string) int {
+	count := 0
+	for _, p := range findPlaceholders(query) {
+		if p.n == 0 {
+			count++
+		} else if p.n > count {
+			count = p.n
+		}
+	}
+	if count == 0 {
+		return -1
+	}
+	return count
+}
+
+// bindArguments replaces the placeholders of query with the values of args, encoded with FormatLiteral. Attachments
+// are not bound, they are sent along the statement. Without arguments to bind, query is returned as is.
+func bindArguments(query string, args []driver.NamedValue) (string, error) {
+	var values []driver.NamedValue
+	for _, arg := range args {
+		switch arg.Value.(type) {
+		case Attachment, *Attachment:
+			continue
+		}
+		if arg.Name != "" {
+			return "", &ErrClientError{message: fmt.Sprintf("named argument %q is not an attachment, use ? or $n placeholders", arg.Name)}
+		}
+		values = append(values, arg)
+	}
+	if len(values) == 0 {
+		return query, nil
+	}
+
+	placeholders := findPlaceholders(query)
+	if expected := countPlaceholders(query); len(values) != max(expected, 0) {
+		return "", &ErrClientError{message: fmt.Sprintf("expected %d arguments, got %d", max(expected, 0), len(values))}
+	}
+
+	var (
+		b        strings.Builder
+		last     int
+		next     int
+		numbered bool
+		used     = make([]bool, len(values))
+	)
+	for i, p := range placeholders {
+		if i > 0 && numbered != (p.n > 0) {
+			return "", &ErrClientError{message: "cannot mix ? and $n placeholders"}
+		}
+		numbered = p.n > 0
+		idx := p.n - 1
+		if !numbered {
+			idx = next
+			next++
+		}
+		used[idx] = true
+		lit, err := FormatLiteral(values[idx].Value)
+		if err != nil {
+			return "", &ErrClientError{message: fmt.Sprintf("cannot bind argument %d", idx+1), wrapErr: err}
+		}
+		b.WriteString(query[last:p.start])
+		b.WriteString(string(lit))
+		last = p.end
+	}
+	b.WriteString(query[last:])
+	for i, u := range used {
+		if !u {
+			return "", &ErrClientError{message: fmt.Sprintf("argument %d is not used by the statement", i+1)}
+		}
+	}
+	return b.String(), nil
+}
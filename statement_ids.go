@@ -0,0 +1,66 @@
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package godeltastream
+
+import (
+	"context"
+)
+
+// StatementIDs identify the connection and the last statement of queries, see WithStatementIDs, so that application
+// logs can be correlated with the logs of the driver, see WithLogger, and with the query history of the server.
+type StatementIDs struct {
+	// ConnectionID identifies the connection for the lifetime of the process, as in ConnectionEvent.
+	ConnectionID string
+	// StatementID is the id of the last statement the server accepted, empty if it accepted none.
+	StatementID string
+}
+
+// LogArgs returns the ids as the key-value pairs of the logs of the driver, e.g. for logger.With(ids.LogArgs()...).
+// Empty ids are left out.
+func (ids StatementIDs) LogArgs() []any {
+	var args []any
+	if ids.ConnectionID != "" {
+		args = append(args, "connectionID", ids.ConnectionID)
+	}
+	if ids.StatementID != "" {
+		args = append(args, "statementID", ids.StatementID)
+	}
+	return args
+}
+
+// ID returns the id the driver assigned to the connection, which identifies it in connection events and logs.
+func (c *Conn) ID() string {
+	return c.id
+}
+
+// LastStatementID returns the id of the last statement of the connection the server accepted, empty if it accepted
+// none.
+func (c *Conn) LastStatementID() string {
+	c.RLock()
+	defer c.RUnlock()
+	return c.lastStatementID
+}
+
+// statementAccepted records the id of a statement the server accepted, for the connection and for ctx.
+func (c *Conn) statementAccepted(ctx context.Context, statementID string) {
+	c.Lock()
+	c.lastStatementID = statementID
+	c.Unlock()
+	if dest, ok := ctx.Value(statementIDsKey).(*StatementIDs); ok {
+		*dest = StatementIDs{ConnectionID: c.id, StatementID: statementID}
+	}
+}
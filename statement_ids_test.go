@@ -0,0 +1,80 @@
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package godeltastream
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"log/slog"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	. "github.com/onsi/gomega"
+)
+
+func TestStatementIDs(t *testing.T) {
+	g := NewWithT(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	var statements []string
+	httpmock.RegisterResponder("POST", "https://api.deltastream.io/v2/statements", mockStatementsResponder(g, &statements, map[string]string{
+		"CREATE STREAM broken AS SELECT * FROM pageviews;": "fixtures/create-stream-200-3E003.json",
+	}, "fixtures/list-organizations-200-00000-1.json"))
+
+	var events []ConnectionEvent
+	connector, err := ConnectorWithOptions(context.TODO(), WithServer("https://api.deltastream.io/v2"), WithStaticToken("sometoken"),
+		WithConnectionEventHandler(func(e ConnectionEvent) { events = append(events, e) }))
+	g.Expect(err).To(BeNil())
+	db := sql.OpenDB(connector)
+	defer db.Close()
+	conn, err := db.Conn(context.TODO())
+	g.Expect(err).To(BeNil())
+	defer conn.Close()
+
+	var connectionID string
+	g.Expect(conn.Raw(func(driverConn any) error {
+		c := driverConn.(*Conn)
+		connectionID = c.ID()
+		g.Expect(c.LastStatementID()).To(BeEmpty())
+		return nil
+	})).To(Succeed())
+	g.Expect(connectionID).To(Equal(events[0].ConnectionID))
+
+	var ids StatementIDs
+	rows, err := conn.QueryContext(WithStatementIDs(context.TODO(), &ids), "LIST ORGANIZATIONS;")
+	g.Expect(err).To(BeNil())
+	g.Expect(rows.Close()).To(Succeed())
+	g.Expect(ids).To(Equal(StatementIDs{ConnectionID: connectionID, StatementID: "d789687d-4e1b-4649-846e-4f10b722f3ad"}))
+
+	// the ids of statements that failed once accepted
+	ids = StatementIDs{}
+	_, err = conn.ExecContext(WithStatementIDs(context.TODO(), &ids), "CREATE STREAM broken AS SELECT * FROM pageviews;")
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(ids.StatementID).To(Equal("d789687d-4e1b-4649-846e-4f10b722f3ad"))
+	g.Expect(conn.Raw(func(driverConn any) error {
+		g.Expect(driverConn.(*Conn).LastStatementID()).To(Equal(ids.StatementID))
+		return nil
+	})).To(Succeed())
+
+	// logged with the keys of the logs of the driver
+	var logs bytes.Buffer
+	slog.New(slog.NewTextHandler(&logs, nil)).With(ids.LogArgs()...).Error("unable to create stream")
+	g.Expect(logs.String()).To(ContainSubstring("connectionID=" + connectionID + " statementID=d789687d-4e1b-4649-846e-4f10b722f3ad"))
+	g.Expect(StatementIDs{}.LogArgs()).To(BeEmpty())
+}
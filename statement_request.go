@@ -0,0 +1,168 @@
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package godeltastream
+
+import (
+	"time"
+
+	"k8s.io/utils/ptr"
+
+	"github.com/deltastreaminc/go-deltastream/apiv2"
+)
+
+// StatementRequest is a statement along with the parameters it is submitted with, see Conn.SubmitRequest. Parameters
+// left unset default to the context and options of the connection. Parameters not known to this version of the driver
+// can be sent with Parameter.
+type StatementRequest struct {
+	statement    string
+	organization *string
+	role         *string
+	database     *string
+	schema       *string
+	store        *string
+	computePool  *string
+	parameters   map[string]any
+	attachments  []Attachment
+}
+
+// NewStatementRequest returns a request submitting statement.
+func NewStatementRequest(statement string) *StatementRequest {
+	return &StatementRequest{statement: statement}
+}
+
+// Statement returns the statement submitted by the request.
+func (r *StatementRequest) Statement() string {
+	return r.statement
+}
+
+// Organization sets the name or id of the organization the statement runs in.
+func (r *StatementRequest) Organization(organization string) *StatementRequest {
+	r.organization = ptr.To(organization)
+	return r
+}
+
+// Role sets the role the statement runs as.
+func (r *StatementRequest) Role(role string) *StatementRequest {
+	r.role = ptr.To(role)
+	return r
+}
+
+// Database sets the database added to the search path of the statement.
+func (r *StatementRequest) Database(database string) *StatementRequest {
+	r.database = ptr.To(database)
+	return r
+}
+
+// Schema sets the schema added to the search path of the statement.
+func (r *StatementRequest) Schema(schema string) *StatementRequest {
+	r.schema = ptr.To(schema)
+	return r
+}
+
+// Store sets the store the statement uses.
+func (r *StatementRequest) Store(store string) *StatementRequest {
+	r.store = ptr.To(store)
+	return r
+}
+
+// ComputePool sets the compute pool the statement runs on.
+func (r *StatementRequest) ComputePool(computePool string) *StatementRequest {
+	r.computePool = ptr.To(computePool)
+	return r
+}
+
+// SessionID sets the session the statement runs in.
+func (r *StatementRequest) SessionID(sessionID string) *StatementRequest {
+	return r.Parameter("sessionID", sessionID)
+}
+
+// Timezone sets the session timezone of the statement.
+func (r *StatementRequest) Timezone(loc *time.Location) *StatementRequest {
+	return r.Parameter("timezone", loc.String())
+}
+
+// Parameter sets the statement parameter name to value, which must be encodable by the JSON codec of the connection.
+func (r *StatementRequest) Parameter(name string, value any) *StatementRequest {
+	if r.parameters == nil {
+		r.parameters = map[string]any{}
+	}
+	r.parameters[name] = value
+	return r
+}
+
+// Attachments adds attachments to the request, in addition to those added to the context of SubmitRequest.
+func (r *StatementRequest) Attachments(attachments ...Attachment) *StatementRequest {
+	r.attachments = append(r.attachments, attachments...)
+	return r
+}
+
+// statementRequestBody is the json part of a statement request. Its parameters are a map rather than the struct of
+// apiv2.StatementRequest, so that parameters not known to the api can be sent.
+type statementRequestBody struct {
+	Statement    string         `json:"statement"`
+	Organization *string        `json:"organization,omitempty"`
+	Role         *string        `json:"role,omitempty"`
+	Database     *string        `json:"database,omitempty"`
+	Schema       *string        `json:"schema,omitempty"`
+	Store        *string        `json:"store,omitempty"`
+	ComputePool  *string        `json:"computePool,omitempty"`
+	Parameters   map[string]any `json:"parameters"`
+}
+
+// body returns the json part of the request, defaulting unset parameters to the context rsctx and the session
+// sessionID and timezone of the connection.
+func (r *StatementRequest) body(rsctx *apiv2.ResultSetContext, sessionID *string, timezone *time.Location) statementRequestBody {
+	b := statementRequestBody{
+		Statement:    r.statement,
+		Organization: r.organization,
+		Role:         r.role,
+		Database:     r.database,
+		Schema:       r.schema,
+		Store:        r.store,
+		ComputePool:  r.computePool,
+		Parameters:   map[string]any{},
+	}
+	if rsctx != nil {
+		if b.Organization == nil && rsctx.OrganizationID != nil {
+			b.Organization = ptr.To(rsctx.OrganizationID.String())
+		}
+		b.Role = firstNonNil(b.Role, rsctx.RoleName)
+		b.Database = firstNonNil(b.Database, rsctx.DatabaseName)
+		b.Schema = firstNonNil(b.Schema, rsctx.SchemaName)
+		b.Store = firstNonNil(b.Store, rsctx.StoreName)
+		b.ComputePool = firstNonNil(b.ComputePool, rsctx.ComputePoolName)
+	}
+	if sessionID != nil {
+		b.Parameters["sessionID"] = *sessionID
+	}
+	if timezone != nil {
+		b.Parameters["timezone"] = timezone.String()
+	}
+	for k, v := range r.parameters {
+		b.Parameters[k] = v
+	}
+	return b
+}
+
+func firstNonNil(values ...*string) *string {
+	for _, v := range values {
+		if v != nil {
+			return v
+		}
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package godeltastream
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jarcoal/httpmock"
+	. "github.com/onsi/gomega"
+	"k8s.io/utils/ptr"
+
+	"github.com/deltastreaminc/go-deltastream/apiv2"
+)
+
+func TestSubmitRequest(t *testing.T) {
+	g := NewWithT(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	var requests []map[string]any
+	var attachments []string
+	httpmock.RegisterResponder("POST", "https://api.deltastream.io/v2/statements", func(r *http.Request) (*http.Response, error) {
+		_, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		g.Expect(err).To(BeNil())
+		mr := multipart.NewReader(r.Body, params["boundary"])
+		p, err := mr.NextPart()
+		g.Expect(err).To(BeNil())
+		req := map[string]any{}
+		g.Expect(json.NewDecoder(p).Decode(&req)).To(Succeed())
+		requests = append(requests, req)
+		for {
+			p, err := mr.NextPart()
+			if err == io.EOF {
+				break
+			}
+			g.Expect(err).To(BeNil())
+			attachments = append(attachments, p.FileName())
+		}
+		rsp := httpmock.NewBytesResponse(http.StatusOK, httpmock.File("fixtures/list-organizations-200-00000-1.json").Bytes())
+		rsp.Header.Set("Content-Type", "application/json")
+		return rsp, nil
+	})
+
+	connector, err := ConnectorWithOptions(context.TODO(), WithServer("https://api.deltastream.io/v2"), WithStaticToken("sometoken"), WithSessionID("s1"))
+	g.Expect(err).To(BeNil())
+	conn, err := sql.OpenDB(connector).Conn(context.TODO())
+	g.Expect(err).To(BeNil())
+	defer conn.Close()
+
+	g.Expect(conn.Raw(func(driverConn any) error {
+		c := driverConn.(*Conn)
+		c.SetContext(apiv2.ResultSetContext{DatabaseName: ptr.To("analytics"), RoleName: ptr.To("sysadmin")})
+
+		req := NewStatementRequest("LIST ORGANIZATIONS;").
+			Role("securityadmin").
+			Timezone(time.FixedZone("UTC+2", 2*60*60)).
+			Parameter("resultFormat", "compact").
+			Attachments(Attachment{Name: "test.blob", Reader: io.NopCloser(bytes.NewReader(attachmentData))})
+		rows, err := c.SubmitRequest(context.TODO(), req)
+		g.Expect(err).To(BeNil())
+		defer rows.Close()
+		g.Expect(rows.Next(make([]driver.Value, len(rows.Columns())))).To(Succeed())
+		return nil
+	})).To(Succeed())
+
+	// parameters of the request override those of the connection, others are kept
+	g.Expect(requests).To(Equal([]map[string]any{{
+		"statement": "LIST ORGANIZATIONS;",
+		"role":      "securityadmin",
+		"database":  "analytics",
+		"parameters": map[string]any{
+			"sessionID":    "s1",
+			"timezone":     "UTC+2",
+			"resultFormat": "compact",
+		},
+	}}))
+	g.Expect(attachments).To(Equal([]string{"test.blob"}))
+}
+
+func TestAutoSessionID(t *testing.T) {
+	g := NewWithT(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	var sessionIDs []any
+	httpmock.RegisterResponder("POST", "https://api.deltastream.io/v2/statements", func(r *http.Request) (*http.Response, error) {
+		_, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		g.Expect(err).To(BeNil())
+		p, err := multipart.NewReader(r.Body, params["boundary"]).NextPart()
+		g.Expect(err).To(BeNil())
+		req := struct {
+			Parameters map[string]any `json:"parameters"`
+		}{}
+		g.Expect(json.NewDecoder(p).Decode(&req)).To(Succeed())
+		sessionIDs = append(sessionIDs, req.Parameters["sessionID"])
+		rsp := httpmock.NewBytesResponse(http.StatusOK, httpmock.File("fixtures/list-organizations-200-00000-1.json").Bytes())
+		rsp.Header.Set("Content-Type", "application/json")
+		return rsp, nil
+	})
+
+	connector, err := ConnectorWithOptions(context.TODO(), WithServer("https://api.deltastream.io/v2"), WithStaticToken("sometoken"), WithSessionID("s1"), WithAutoSessionID())
+	g.Expect(err).To(BeNil())
+	db := sql.OpenDB(connector)
+	defer db.Close()
+
+	// every connection runs its statements in a session of its own
+	sessionID := func(conn *sql.Conn) (id string) {
+		g.Expect(conn.Raw(func(driverConn any) error {
+			id = driverConn.(*Conn).SessionID()
+			return nil
+		})).To(Succeed())
+		return id
+	}
+	conn1, err := db.Conn(context.TODO())
+	g.Expect(err).To(BeNil())
+	defer conn1.Close()
+	conn2, err := db.Conn(context.TODO())
+	g.Expect(err).To(BeNil())
+	defer conn2.Close()
+	id1, id2 := sessionID(conn1), sessionID(conn2)
+	g.Expect(uuid.Parse(id1)).Error().To(BeNil())
+	g.Expect(id2).ToNot(Equal(id1))
+
+	for _, conn := range []*sql.Conn{conn1, conn2, conn1} {
+		_, err = conn.ExecContext(context.TODO(), "LIST ORGANIZATIONS;")
+		g.Expect(err).To(BeNil())
+	}
+	g.Expect(sessionIDs).To(Equal([]any{id1, id2, id1}))
+
+	// without session
+	connector, err = ConnectorWithOptions(context.TODO(), WithServer("https://api.deltastream.io/v2"), WithStaticToken("sometoken"))
+	g.Expect(err).To(BeNil())
+	conn, err := sql.OpenDB(connector).Conn(context.TODO())
+	g.Expect(err).To(BeNil())
+	defer conn.Close()
+	g.Expect(sessionID(conn)).To(BeEmpty())
+}
@@ -0,0 +1,108 @@
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package godeltastream
+
+import (
+	"database/sql/driver"
+	"io"
+	"time"
+)
+
+// Compile time validation that our types implement the expected interfaces
+var (
+	_ StreamBatchReader = &streamingRows{}
+)
+
+// maxBatchPrealloc bounds the rows allocated ahead of time for a batch, whatever maxRows is.
+const maxBatchPrealloc = 1024
+
+// StreamBatchReader is implemented by the driver.Rows of streaming queries, to read rows in batches rather than one
+// at a time with Next. database/sql does not expose the driver.Rows it wraps, use sql.Conn.Raw and Conn.QueryContext
+// to access it.
+type StreamBatchReader interface {
+	// NextBatch waits for the next row of the stream, then returns it along with the rows that follow, in order, until
+	// maxRows rows were read or maxWait has elapsed since the first one. Rows already received are always returned
+	// without waiting. It returns io.EOF once the stream ended and all rows were returned. If a row cannot be decoded,
+	// the rows before it are returned along with the error.
+	NextBatch(maxRows int, maxWait time.Duration) ([][]driver.Value, error)
+}
+
+// NextBatch implements StreamBatchReader.
+func (r *streamingRows) NextBatch(maxRows int, maxWait time.Duration) ([][]driver.Value, error) {
+	if maxRows <= 0 {
+		return nil, &ErrClientError{message: "maxRows must be greater than zero"}
+	}
+	if r.closed.Load() {
+		return nil, &ErrStreamClosed{}
+	}
+	// rows buffered when the stream was stopped, e.g. by Conn.Close, are discarded
+	if r.closing() {
+		return nil, io.EOF
+	}
+
+	batch := make([][]driver.Value, 0, min(maxRows, maxBatchPrealloc))
+	var deadline <-chan time.Time
+	for len(batch) < maxRows {
+		var (
+			rowData  *PrintTopicDataMessage
+			open     bool
+			received bool
+		)
+		if len(batch) > 0 {
+			// rows already received are taken without waiting
+			select {
+			case rowData, open = <-r.dataChan:
+				received = true
+			default:
+			}
+		}
+		if !received {
+			select {
+			case <-r.ctx.Done():
+				if len(batch) > 0 {
+					return batch, nil
+				}
+				return nil, r.ctx.Err()
+			case <-deadline:
+				return batch, nil
+			case rowData, open = <-r.dataChan:
+			}
+		}
+		if !open {
+			// the end of the stream is reported by the next call
+			if len(batch) > 0 {
+				return batch, nil
+			}
+			if r.readErr != nil {
+				return nil, r.readErr
+			}
+			return nil, io.EOF
+		}
+
+		row := make([]driver.Value, len(r.metadata.Columns))
+		if err := r.decodeData(rowData, row); err != nil {
+			return batch, err
+		}
+		batch = append(batch, row)
+		if deadline == nil {
+			t := time.NewTimer(maxWait)
+			defer t.Stop()
+			deadline = t.C
+		}
+	}
+	return batch, nil
+}
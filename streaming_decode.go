@@ -0,0 +1,128 @@
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package godeltastream
+
+import (
+	"sync/atomic"
+
+	"github.com/gorilla/websocket"
+)
+
+// decodeJob is a frame read from the stream, numbered in the order it was received.
+type decodeJob struct {
+	seq   uint64
+	frame []byte
+	err   error
+}
+
+// decodeResult is a decoded frame, or the read or decode error of the frame.
+type decodeResult struct {
+	seq uint64
+	msg *PrintTopicMessage
+	err error
+}
+
+// decodePool decodes the frames of a stream on several workers. A reader goroutine numbers frames as they are
+// received, and next returns them in that order regardless of the order in which workers finish.
+type decodePool struct {
+	jobs    chan decodeJob
+	results chan decodeResult
+	done    chan struct{}
+	// slots bounds the number of frames read but not yet returned by next
+	slots   chan struct{}
+	pending map[uint64]decodeResult
+	nextSeq uint64
+	// exited is closed once the goroutines of the pool return
+	exited chan struct{}
+}
+
+// newDecodePool returns a pool decoding the frames read from conn.
+func newDecodePool(r *streamingRows, conn *websocket.Conn, workers int) *decodePool {
+	p := &decodePool{
+		jobs:    make(chan decodeJob, workers),
+		results: make(chan decodeResult, workers),
+		done:    make(chan struct{}),
+		slots:   make(chan struct{}, 2*workers),
+		pending: map[uint64]decodeResult{},
+		exited:  make(chan struct{}),
+	}
+
+	// the last goroutine to return reports that the pool exited
+	remaining := int32(workers + 1)
+	exit := func() {
+		if atomic.AddInt32(&remaining, -1) == 0 {
+			close(p.exited)
+		}
+	}
+	r.goBackground("streaming decode reader", func() {
+		defer exit()
+		defer close(p.jobs)
+		for seq := uint64(0); ; seq++ {
+			select {
+			case p.slots <- struct{}{}:
+			case <-p.done:
+				return
+			}
+			b, err := r.readFrame(conn)
+			select {
+			case p.jobs <- decodeJob{seq: seq, frame: b, err: err}:
+			case <-p.done:
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	})
+	for i := 0; i < workers; i++ {
+		r.goBackground("streaming decode worker", func() {
+			defer exit()
+			for j := range p.jobs {
+				res := decodeResult{seq: j.seq, err: j.err}
+				if j.err == nil {
+					res.msg, res.err = r.decodeFrame(j.frame)
+				}
+				select {
+				case p.results <- res:
+				case <-p.done:
+					return
+				}
+			}
+		})
+	}
+	return p
+}
+
+// next returns the next message of the stream, in the order it was received.
+func (p *decodePool) next() (*PrintTopicMessage, error) {
+	for {
+		if res, ok := p.pending[p.nextSeq]; ok {
+			delete(p.pending, p.nextSeq)
+			p.nextSeq++
+			<-p.slots
+			return res.msg, res.err
+		}
+		// the reader sends the error ending the stream through the workers, so a result is always coming
+		res := <-p.results
+		p.pending[res.seq] = res
+	}
+}
+
+// stop makes the goroutines of the pool return once the reader is unblocked by closing the connection.
+func (p *decodePool) stop() {
+	close(p.done)
+}
@@ -18,22 +18,23 @@ package godeltastream
 
 import (
 	"context"
-	"crypto/tls"
 	"database/sql/driver"
-	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"math/big"
+	"net"
 	"net/http"
 	"net/url"
 	"reflect"
-	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/deltastreaminc/go-deltastream/apiv2"
 
+	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 	"k8s.io/utils/ptr"
 )
@@ -46,21 +47,54 @@ var (
 	_ driver.RowsColumnTypeNullable         = &streamingRows{}
 	_ driver.RowsColumnTypeLength           = &streamingRows{}
 	_ driver.RowsColumnTypePrecisionScale   = &streamingRows{}
+	_ StreamingQueryIDProvider              = &streamingRows{}
 )
 
+// StreamingQueryIDProvider is implemented by the driver.Rows of streaming queries. database/sql does not expose the
+// driver.Rows it wraps, use sql.Conn.Raw and Conn.QueryContext to access it.
+type StreamingQueryIDProvider interface {
+	// StreamingQueryID returns the id of the continuous query producing the stream, as listed by LIST QUERIES, or an
+	// empty string if the stream is not produced by a query.
+	StreamingQueryID() string
+}
+
 type streamingRows struct {
-	conn *websocket.Conn
+	conn       *websocket.Conn // replaced by readMessages on reconnects, under connMu
+	connMu     sync.Mutex
+	req        apiv2.DataplaneRequest // dataplane request the stream was last dialed with
+	httpClient *http.Client
+	sessionID  *string
 
 	ctx                      context.Context
+	tap                      StreamMessageTap // see WithStreamMessageTap, nil if not tapped
 	metadata                 *PrintTopicMetadataMessage
-	readyChan                chan struct{}
-	dataChan                 chan *PrintTopicDataMessage
-	errChan                  chan error
+	readyChan                chan struct{}               // closed once metadata is received
+	dataChan                 chan *PrintTopicDataMessage // closed by readMessages when it returns
+	done                     chan struct{}               // closed by Close
+	exited                   chan struct{}               // closed when readMessages returns
+	poolExited               chan struct{}               // closed once the goroutines of the decode pool return, set by readMessages
+	decodeWorkers            int
+	readErr                  error // set by readMessages before closing dataChan
+	readyOnce                sync.Once
+	closeOnce                sync.Once
+	closed                   atomic.Bool // set by Close, unlike when the stream is stopped by Conn.Close
 	enableColumnDisplayHints bool
+	decodeOptions            decodeOptions
+	decoders                 []columnDecoder
 	queryID                  *string
 	dsConn                   *Conn
+	statementID              string
+	dataplane                string
+	stats                    streamStats
+	rowsRead                 int64              // rows returned by Next and NextBatch
+	collecting               bool               // set once the stream is reported to the metrics collector
+	since                    time.Time          // records with an earlier event time are skipped, see WithStreamSince
+	release                  context.CancelFunc // called once stopped, see releaseOnClose
 }
 
+// streamCloseTimeout bounds how long Close waits for the server to acknowledge the end of a stream.
+const streamCloseTimeout = 5 * time.Second
+
 type AuthMessage struct {
 	Type        string `json:"type"`
 	AccessToken string `json:"accessToken"`
@@ -72,6 +106,8 @@ type PrintTopicMessage struct {
 	Err      PrintTopicErrorMessage    `json:"-"`
 	Metadata PrintTopicMetadataMessage `json:"-"`
 	Data     PrintTopicDataMessage     `json:"-"`
+	Shutdown PrintTopicShutdownMessage `json:"-"`
+	Session  PrintTopicSessionMessage  `json:"-"`
 }
 
 func (m *PrintTopicMessage) UnmarshalJSON(b []byte) error {
@@ -95,6 +131,14 @@ func (m *PrintTopicMessage) UnmarshalJSON(b []byte) error {
 		if err := json.Unmarshal(b, &m.Metadata); err != nil {
 			return err
 		}
+	case "shutdown":
+		if err := json.Unmarshal(b, &m.Shutdown); err != nil {
+			return err
+		}
+	case "session":
+		if err := json.Unmarshal(b, &m.Session); err != nil {
+			return err
+		}
 	default:
 		return &ErrInterfaceError{message: "unexpected message type"}
 	}
@@ -129,11 +173,80 @@ type PrintTopicDataMessage struct {
 	Data    []*string         `json:"data"`
 }
 
+// PrintTopicShutdownMessage notifies that the server is shutting down and ends the stream.
+type PrintTopicShutdownMessage struct {
+	Type    string            `json:"type"`
+	Headers map[string]string `json:"headers"`
+	Message string            `json:"message"`
+}
+
+// PrintTopicSessionMessage acknowledges the session the server bound the stream to, in response to the authentication
+// message.
+type PrintTopicSessionMessage struct {
+	Type      string            `json:"type"`
+	Headers   map[string]string `json:"headers"`
+	SessionID string            `json:"sessionId"`
+}
+
 func newStreamingRows(ctx context.Context, c *Conn, req apiv2.DataplaneRequest, httpClient *http.Client, sessionID *string, enableDislayHints bool) (*streamingRows, error) {
+	conn, err := dialStream(ctx, c, req, httpClient, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := &streamingRows{
+		ctx:                      ctx,
+		tap:                      streamMessageTap(ctx),
+		conn:                     conn,
+		req:                      req,
+		httpClient:               httpClient,
+		sessionID:                sessionID,
+		dataChan:                 make(chan *PrintTopicDataMessage, 30),
+		readyChan:                make(chan struct{}),
+		done:                     make(chan struct{}),
+		exited:                   make(chan struct{}),
+		poolExited:               make(chan struct{}),
+		decodeWorkers:            c.decodeWorkers,
+		enableColumnDisplayHints: enableDislayHints,
+		decodeOptions:            c.decodeOptions(),
+		queryID:                  req.QueryID,
+		dsConn:                   c,
+		statementID:              req.StatementID,
+		dataplane:                req.Uri,
+		since:                    streamSince(ctx),
+	}
+	rows.stats.stats.QueryID = rows.StreamingQueryID()
+	rows.goBackground("streaming rows", rows.readMessages)
+	select {
+	case <-rows.readyChan:
+		rows.streamOpened()
+		return rows, nil
+	case <-rows.exited:
+		select {
+		case <-rows.readyChan:
+			rows.streamOpened()
+			return rows, nil
+		default:
+		}
+		_ = rows.Close()
+		if rows.readErr != nil {
+			return nil, rows.readErr
+		}
+		return nil, rows.streamError(&ErrInterfaceError{message: "stream ended before metadata was received"})
+	case <-ctx.Done():
+		_ = rows.Close()
+		return nil, ctx.Err()
+	}
+}
+
+// dialStream connects to the dataplane serving the streaming result of req and authenticates.
+func dialStream(ctx context.Context, c *Conn, req apiv2.DataplaneRequest, httpClient *http.Client, sessionID *string) (*websocket.Conn, error) {
 	u, err := url.Parse(req.Uri)
 	if err != nil {
 		return nil, err
 	}
+	// proxies are selected based on the http url of the dataplane
+	proxyReq := &http.Request{URL: &url.URL{Scheme: u.Scheme, Host: u.Host}}
 	switch u.Scheme {
 	case "http":
 		u.Scheme = "ws"
@@ -148,114 +261,448 @@ func newStreamingRows(ctx context.Context, c *Conn, req apiv2.DataplaneRequest,
 		HandshakeTimeout: 45 * time.Second,
 	}
 	if t, ok := httpClient.Transport.(*http.Transport); ok {
-		dialer.TLSClientConfig = &tls.Config{
-			InsecureSkipVerify: t.TLSClientConfig.InsecureSkipVerify,
+		if t.TLSClientConfig != nil {
+			dialer.TLSClientConfig = t.TLSClientConfig.Clone()
+			// websockets are served over HTTP/1.1, the transport may have added h2
+			dialer.TLSClientConfig.NextProtos = nil
+		}
+		dialer.NetDialContext = t.DialContext
+
+		// use the proxy of the transport, tunneling through it ourselves so that ProxyConnectHeader is honored
+		dialer.Proxy = nil
+		if t.Proxy != nil {
+			proxyURL, err := t.Proxy(proxyReq)
+			if err != nil {
+				return nil, &ErrClientError{message: "unable to determine proxy", wrapErr: err}
+			}
+			if proxyURL != nil {
+				dialer.NetDialContext = proxyConnectDialer(proxyURL, t.ProxyConnectHeader, t.DialContext)
+			}
 		}
 	}
 	h := http.Header{}
 	if sessionID != nil {
 		h.Add("ds-session-id", *sessionID)
 	}
+	if maintenanceMode(ctx, c.maintenanceMode) {
+		h.Set(maintenanceModeHeader, "true")
+	}
 
+	streamErr := func(err error) error {
+		return &ErrStreaming{StatementID: req.StatementID, QueryID: ptr.Deref(req.QueryID, ""), Err: err}
+	}
+	if err := c.authHeaderProvider.apply(ctx, h); err != nil {
+		return nil, streamErr(err)
+	}
 	conn, resp, err := dialer.DialContext(ctx, u.String(), h)
 	if err != nil {
-		if resp != nil && resp.StatusCode != 200 {
-			b, err := io.ReadAll(resp.Body)
-			if err != nil {
-				return nil, &ErrClientError{message: "unable to read dataplane response", wrapErr: err}
-			}
-			return nil, &ErrInterfaceError{message: string(b)}
+		handshakeErr := &ErrStreamHandshake{URL: redactURL(*u), Err: err}
+		if resp != nil {
+			handshakeErr.StatusCode = resp.StatusCode
+			handshakeErr.Headers = resp.Header.Clone()
+			handshakeErr.Headers.Del("Set-Cookie")
+			// the body only adds details, a partial body is reported as is
+			b, _ := io.ReadAll(resp.Body)
+			handshakeErr.Body = bodySnippet(b)
 		}
-		return nil, err
+		return nil, streamErr(&streamDialError{statusCode: handshakeErr.StatusCode, err: handshakeErr})
 	}
 
-	if err = conn.WriteJSON(&AuthMessage{
+	auth := &AuthMessage{
 		Type:        "auth",
 		AccessToken: req.Token,
 		SessionID:   ptr.Deref(sessionID, ""),
-	}); err != nil {
-		return nil, &ErrInterfaceError{message: "unable to send request", wrapErr: err}
 	}
+	if err = conn.WriteJSON(auth); err != nil {
+		return nil, streamErr(&ErrInterfaceError{message: "unable to send request", wrapErr: err})
+	}
+	if tap := streamMessageTap(ctx); tap != nil {
+		redacted := *auth
+		redacted.AccessToken = "redacted"
+		if b, err := json.Marshal(&redacted); err == nil {
+			tap(redacted.Type, b)
+		}
+	}
+	return conn, nil
+}
 
-	rows := &streamingRows{
-		ctx:                      ctx,
-		conn:                     conn,
-		dataChan:                 make(chan *PrintTopicDataMessage, 30),
-		readyChan:                make(chan struct{}),
-		errChan:                  make(chan error),
-		enableColumnDisplayHints: enableDislayHints,
-		queryID:                  req.QueryID,
-		dsConn:                   c,
+// redactURL returns u with the values of its query parameters redacted, since dataplane URLs may carry credentials.
+func redactURL(u url.URL) string {
+	if u.RawQuery != "" {
+		q := u.Query()
+		for k := range q {
+			q[k] = []string{"redacted"}
+		}
+		u.RawQuery = q.Encode()
 	}
-	go rows.readMessages()
+	return u.String()
+}
+
+// streamDialError is an error connecting to the dataplane of a streaming result, see WithStreamDialRetry. It is
+// transparent to callers.
+type streamDialError struct {
+	// statusCode is the status code of the response to the websocket handshake, zero if the connection failed.
+	statusCode int
+	err        error
+}
+
+func (e *streamDialError) Error() string {
+	return e.err.Error()
+}
+
+func (e *streamDialError) Unwrap() error {
+	return e.err
+}
+
+func (r *streamingRows) streamOpened() {
+	r.dsConn.emit(ConnectionEvent{Type: ConnectionEventStreamOpened, StatementID: r.statementID, QueryID: r.StreamingQueryID(), Dataplane: r.dataplane})
+	if r.dsConn.metricsCollector != nil {
+		r.collecting = true
+		r.dsConn.metricsCollector.StreamOpened(r.statementID, r.stats.snapshot)
+	}
+}
+
+// StreamingQueryID implements StreamingQueryIDProvider.
+func (r *streamingRows) StreamingQueryID() string {
+	return ptr.Deref(r.queryID, "")
+}
+
+// streamError wraps err with the ids of the statement and query of the stream.
+func (r *streamingRows) streamError(err error) error {
+	return &ErrStreaming{StatementID: r.statementID, QueryID: r.StreamingQueryID(), Err: err}
+}
+
+// StreamStats implements StreamStatsProvider.
+func (r *streamingRows) StreamStats() StreamStats {
+	return r.stats.snapshot()
+}
+
+// RowsStats implements RowsStatsProvider. The bytes received include the messages of the stream and the response of
+// the statement.
+func (r *streamingRows) RowsStats() RowsStats {
+	return RowsStats{
+		RowsRead:       r.rowsRead,
+		RowsUnconsumed: int64(len(r.dataChan)),
+		BytesReceived:  r.stats.snapshot().BytesReceived + bytesReceived(r.ctx),
+	}
+}
+
+// closing returns true once Close has been called.
+func (r *streamingRows) closing() bool {
 	select {
-	case <-rows.readyChan:
-	case <-ctx.Done():
-	case err = <-rows.errChan:
+	case <-r.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// printTopicFrame is the union of all message types, so that every frame is decoded in a single pass.
+type printTopicFrame struct {
+	Type    string             `json:"type"`
+	Headers map[string]string  `json:"headers"`
+	Message string             `json:"message"`
+	SqlCode SqlState           `json:"sqlCode"`
+	Columns []PrintTopicColumn `json:"columns"`
+	Data    []*string          `json:"data"`
+	// SessionID is the session bound by the server, in session messages
+	SessionID string `json:"sessionId"`
+}
+
+func (r *streamingRows) readMessage() (*PrintTopicMessage, error) {
+	b, err := r.readFrame(r.conn)
+	if err != nil {
 		return nil, err
 	}
+	return r.decodeFrame(b)
+}
 
-	return rows, nil
+// readFrame reads the next frame from conn. Close frames sent by the server end the stream with io.EOF, or with an
+// *ErrStreamEndedByServer unless the stream ended normally.
+func (r *streamingRows) readFrame(conn *websocket.Conn) ([]byte, error) {
+	_, b, err := conn.ReadMessage()
+	if err != nil {
+		return nil, closeFrameError(err)
+	}
+	r.stats.messageReceived(len(b))
+	if r.tap != nil {
+		var f struct {
+			Type string `json:"type"`
+		}
+		_ = json.Unmarshal(b, &f)
+		r.tap(f.Type, append([]byte(nil), b...))
+	}
+	return b, nil
+}
+
+func (r *streamingRows) decodeFrame(b []byte) (*PrintTopicMessage, error) {
+	var f printTopicFrame
+	if err := r.dsConn.jsonCodec.Unmarshal(b, &f); err != nil {
+		r.stats.decodeError()
+		return nil, err
+	}
+
+	msg := &PrintTopicMessage{Type: f.Type}
+	switch f.Type {
+	case "error":
+		msg.Err = PrintTopicErrorMessage{Type: f.Type, Headers: f.Headers, Message: f.Message, SqlCode: f.SqlCode}
+	case "metadata":
+		msg.Metadata = PrintTopicMetadataMessage{Type: f.Type, Headers: f.Headers, Columns: f.Columns}
+	case "data":
+		msg.Data = PrintTopicDataMessage{Type: f.Type, Headers: f.Headers, Data: f.Data}
+	case "shutdown":
+		msg.Shutdown = PrintTopicShutdownMessage{Type: f.Type, Headers: f.Headers, Message: f.Message}
+	case "session":
+		msg.Session = PrintTopicSessionMessage{Type: f.Type, Headers: f.Headers, SessionID: f.SessionID}
+	}
+	return msg, nil
+}
+
+// closeFrameError maps errors reporting close frames to io.EOF for normal closures and to *ErrStreamEndedByServer
+// otherwise. Other errors, including connections dropped without a close frame, are returned as is.
+func closeFrameError(err error) error {
+	var ce *websocket.CloseError
+	if !errors.As(err, &ce) || ce.Code == websocket.CloseAbnormalClosure {
+		return err
+	}
+	if ce.Code == websocket.CloseNormalClosure || ce.Code == websocket.CloseNoStatusReceived {
+		return io.EOF
+	}
+	return &ErrStreamEndedByServer{Code: ce.Code, Reason: ce.Text}
+}
+
+// goBackground runs f in a goroutine tracked by the connection as "<name> <statement id>", which closing the rows
+// stops.
+func (r *streamingRows) goBackground(name string, f func()) {
+	r.dsConn.goroutines.Go(name+" "+r.statementID, func() { _ = r.stop() }, f)
+}
+
+// enrichErrorMessage prepends the messages of the query history to message if the query errored, unless disabled with
+// WithQueryHistoryLookups. Failing to describe the query is logged and leaves message unchanged, the original error is
+// reported either way.
+func (r *streamingRows) enrichErrorMessage(message string) string {
+	if r.queryID == nil || r.dsConn.noQueryHistoryLookups {
+		return message
+	}
+	describe, err := r.dsConn.submitStatement(r.ctx, nil, fmt.Sprintf("DESCRIBE QUERY HISTORY %s;", *r.queryID))
+	if err != nil {
+		r.dsConn.logWarn("unable to describe query history of failed stream", "statementID", r.statementID, "queryID", *r.queryID, "error", err)
+		return message
+	}
+	rows, err := r.dsConn.queryRows(r.ctx, describe)
+	if err != nil {
+		r.dsConn.logWarn("unable to read query history of failed stream", "statementID", r.statementID, "queryID", *r.queryID, "error", err)
+		return message
+	}
+	defer rows.Close()
+	view := NewRowView(rows)
+	if err := view.Next(); err == io.EOF {
+		r.dsConn.logWarn("no query history for failed stream", "statementID", r.statementID, "queryID", *r.queryID)
+		return message
+	} else if err != nil {
+		r.dsConn.logWarn("unable to read query history of failed stream", "statementID", r.statementID, "queryID", *r.queryID, "error", err)
+		return message
+	}
+	state, _ := view.String("state")
+	history, ok := view.String("messages")
+	if !strings.EqualFold(state, QueryStateErrored) || !ok {
+		return message
+	}
+	return fmt.Sprintf("%s\n\n%s", history, message)
 }
 
 func (r *streamingRows) readMessages() {
-	defer close(r.readyChan)
+	defer close(r.exited)
+	defer close(r.dataChan)
+	defer func() {
+		if r.readErr != nil {
+			r.readErr = r.streamError(r.readErr)
+		}
+	}()
 
 	r.conn.SetReadDeadline(time.Time{})
+	var pool *decodePool
+	defer func() {
+		if pool != nil {
+			pool.stop()
+		}
+	}()
+	// read returns the function reading the messages of the current connection
+	read := func() func() (*PrintTopicMessage, error) {
+		if r.decodeWorkers <= 1 {
+			return r.readMessage
+		}
+		pool = newDecodePool(r, r.conn, r.decodeWorkers)
+		r.poolExited = pool.exited
+		return pool.next
+	}
+	next := read()
+	if pool == nil {
+		close(r.poolExited)
+	}
+	// resume reconnects the stream the server ended, see reconnect, and reports whether reading can go on
+	resume := func(ended *ErrStreamEndedByServer) bool {
+		if !r.reconnectable(ended) {
+			return false
+		}
+		// closing the connection unblocks the reader of the pool
+		r.closeConn()
+		if pool != nil {
+			pool.stop()
+			<-pool.exited
+			pool = nil
+		}
+		if !r.reconnect() {
+			return false
+		}
+		next = read()
+		return true
+	}
 	for {
-		var msg PrintTopicMessage
-		if err := r.conn.ReadJSON(&msg); err != nil {
-			r.errChan <- &ErrInterfaceError{message: "unable to read message from server", wrapErr: err}
+		msg, err := next()
+		if err != nil {
+			var ended *ErrStreamEndedByServer
+			switch {
+			case r.closing(), errors.Is(err, io.EOF):
+			case errors.As(err, &ended):
+				if resume(ended) {
+					continue
+				}
+				r.readErr = ended
+			default:
+				r.readErr = &ErrInterfaceError{message: "unable to read message from server", wrapErr: err}
+			}
 			return
 		}
+		if r.closing() {
+			// discard messages in flight until the server acknowledges the close frame
+			continue
+		}
 		switch msg.Type {
 		case "error":
-			message := msg.Err.Message
-			if r.queryID != nil {
-				describe, err := r.dsConn.submitStatement(r.ctx, nil, fmt.Sprintf("DESCRIBE QUERY HISTORY %s;", *r.queryID))
-				if err != nil {
-					_ = err
-				} else {
-					errd := false
-					var msg string
-					if describe.Data == nil {
-						continue
-					}
-					d := *describe.Data
-					if len(d) > 0 {
-						for i, col := range describe.Metadata.Columns {
-							if i > len(d[0]) {
-								continue
-							}
-							if strings.ToLower(col.Name) == "state" && strings.ToLower(*d[0][i]) == "errored" {
-								errd = true
-								continue
-							}
-							if strings.ToLower(col.Name) == "messages" {
-								msg = fmt.Sprintf("%s\n\n%s", *d[0][i], message)
-								continue
-							}
-						}
-					}
-					if errd {
-						message = msg
-					}
-				}
-			}
-			r.errChan <- &ErrSQLError{SQLCode: msg.Err.SqlCode, Message: message}
+			message := r.enrichErrorMessage(msg.Err.Message)
+			statementID, _ := uuid.Parse(r.statementID)
+			r.readErr = &ErrSQLError{SQLCode: msg.Err.SqlCode, Message: message, StatementID: statementID, strict: r.dsConn != nil && r.dsConn.strictSQLStates}
 			return
 		case "metadata":
+			if r.metadata != nil {
+				// metadata is resent on reconnects, it must not change the columns of rows already reported
+				if err := streamSchema(r.metadata.Columns).verify(streamSchema(msg.Metadata.Columns)); err != nil {
+					r.readErr = err
+					return
+				}
+				continue
+			}
 			r.metadata = &msg.Metadata
-			r.readyChan <- struct{}{}
+			r.readyOnce.Do(func() { close(r.readyChan) })
 		case "data":
-			r.dataChan <- &msg.Data
+			r.stats.recordReceived(msg.Data.Headers)
+			if t, ok := eventTime(msg.Data.Headers); ok && t.Before(r.since) {
+				continue
+			}
+			select {
+			case r.dataChan <- &msg.Data:
+				r.stats.buffered(len(r.dataChan))
+			case <-r.done:
+			}
+		case "shutdown":
+			// the server is going away, it may not send a close frame
+			ended := &ErrStreamEndedByServer{Code: websocket.CloseGoingAway, Reason: msg.Shutdown.Message}
+			if resume(ended) {
+				continue
+			}
+			r.readErr = ended
+			return
+		case "session":
+			// the server acknowledges the session of every connection, including reconnects
+			if requested := ptr.Deref(r.sessionID, ""); requested != "" && msg.Session.SessionID != requested {
+				mismatch := &ErrSessionMismatch{Requested: requested, Bound: msg.Session.SessionID}
+				if !r.dsConn.sessionRecovery {
+					r.readErr = mismatch
+					return
+				}
+				// the stream goes on in the session bound by the server, which the connection adopts
+				if err := r.dsConn.recoverSession(r.ctx, requested, mismatch.Bound); err != nil {
+					r.readErr = r.streamError(err)
+					return
+				}
+				r.sessionID = r.dsConn.session()
+			}
 		default:
-			r.errChan <- &ErrInterfaceError{message: "unexpected message type " + msg.Type}
+			r.readErr = &ErrInterfaceError{message: "unexpected message type " + msg.Type}
 			return
 		}
 	}
 }
 
+// reconnectable returns whether the stream may be resumed on a new connection after the server ended it: the server
+// went away, e.g. while the dataplane restarts, and WithStreamDialRetry is enabled.
+func (r *streamingRows) reconnectable(ended *ErrStreamEndedByServer) bool {
+	if r.dsConn.streamDialRetry == nil || r.dsConn.streamDialRetry.retries <= 0 {
+		return false
+	}
+	switch ended.Code {
+	case websocket.CloseGoingAway, websocket.CloseServiceRestart, websocket.CloseTryAgainLater:
+		return true
+	}
+	return false
+}
+
+// reconnect dials the dataplane of the stream again as configured with WithStreamDialRetry, requesting the dataplane
+// request again from the control plane before every attempt, and replaces the connection of the stream. The server
+// resends the metadata of the stream, which must match the columns already reported. It returns false if the stream
+// could not be reconnected or was closed meanwhile.
+func (r *streamingRows) reconnect() bool {
+	policy := r.dsConn.streamDialRetry
+	backoff := policy.backoff
+	if backoff <= 0 {
+		backoff = time.Second
+	}
+	statementID, _ := uuid.Parse(r.statementID)
+	for retry := 0; retry < policy.retries; retry++ {
+		t := time.NewTimer(backoff)
+		select {
+		case <-r.done:
+			t.Stop()
+			return false
+		case <-r.ctx.Done():
+			t.Stop()
+			return false
+		case <-t.C:
+		}
+		backoff *= 2
+
+		if rs, err := r.dsConn.getStatement(r.ctx, statementID, 0); err == nil && rs.Metadata.DataplaneRequest != nil {
+			r.req = *rs.Metadata.DataplaneRequest
+		}
+		conn, err := dialStream(r.ctx, r.dsConn, r.req, r.httpClient, r.sessionID)
+		if err != nil {
+			r.dsConn.logWarn("unable to reconnect stream", "statementID", r.statementID, "error", err)
+			if !policy.retryable(err) {
+				return false
+			}
+			continue
+		}
+
+		r.connMu.Lock()
+		defer r.connMu.Unlock()
+		if r.closing() {
+			_ = conn.Close()
+			return false
+		}
+		r.conn = conn
+		return true
+	}
+	return false
+}
+
+// closeConn closes the current connection of the stream.
+func (r *streamingRows) closeConn() {
+	r.connMu.Lock()
+	defer r.connMu.Unlock()
+	_ = r.conn.Close()
+}
+
 func (r *streamingRows) ColumnTypeNullable(index int) (nullable bool, ok bool) {
 	if r.metadata == nil {
 		return false, false
@@ -290,34 +737,55 @@ func (r *streamingRows) ColumnTypeScanType(index int) reflect.Type {
 		return nil
 	}
 	md := r.metadata.Columns[index]
-	switch {
-	case strings.HasPrefix(md.Type, "VARCHAR"):
-		return typeMap["VARCHAR"]
-	case strings.HasPrefix(md.Type, "DECIMAL"):
-		return typeMap["DECIMAL"]
-	case strings.HasPrefix(md.Type, "TIMESTAMP"):
-		return typeMap["TIMESTAMP"]
-	case strings.HasPrefix(md.Type, "TIME"):
-		return typeMap["TIME"]
-	case strings.HasPrefix(md.Type, "ARRAY"):
-		return typeMap["ARRAY"]
-	case strings.HasPrefix(md.Type, "STRUCT"):
-		return typeMap["STRUCT"]
-	case strings.HasPrefix(md.Type, "MAP"):
-		return typeMap["MAP"]
-	default:
-		return typeMap[md.Type]
-	}
+	return scanType(md.Type, md.Nullable, r.decodeOptions)
 }
 
+// Close stops the stream. A websocket close frame asks the server to stop streaming, and messages still in flight are
+// drained until the server acknowledges it (or streamCloseTimeout elapses), so that server side resources are released
+// by the time Close returns. Close may be called more than once, Next and NextBatch return an *ErrStreamClosed once it
+// was.
 func (r *streamingRows) Close() error {
-	r.metadata = nil
-	close(r.dataChan)
-	err := r.conn.Close()
-	if err != nil {
-		return &ErrInterfaceError{message: "error while closing connection", wrapErr: err}
-	}
-	return nil
+	r.closed.Store(true)
+	return r.stop()
+}
+
+// stop stops the stream, see Close. Streams stopped when their connection is closed end with io.EOF.
+func (r *streamingRows) stop() error {
+	var err error
+	r.closeOnce.Do(func() {
+		close(r.done)
+
+		// readMessages no longer replaces the connection once done is closed
+		r.connMu.Lock()
+		conn := r.conn
+		r.connMu.Unlock()
+
+		closeMsg := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+		if werr := conn.WriteControl(websocket.CloseMessage, closeMsg, time.Now().Add(streamCloseTimeout)); werr == nil {
+			t := time.NewTimer(streamCloseTimeout)
+			select {
+			case <-r.exited:
+			case <-t.C:
+			}
+			t.Stop()
+		}
+
+		if cerr := conn.Close(); cerr != nil && !errors.Is(cerr, net.ErrClosed) {
+			err = &ErrInterfaceError{message: "error while closing connection", wrapErr: cerr}
+		}
+		<-r.exited
+		<-r.poolExited
+		r.metadata = nil
+		reportRowsStats(r.ctx, r.RowsStats())
+		if r.collecting {
+			r.dsConn.metricsCollector.StreamClosed(r.statementID, r.stats.snapshot())
+		}
+		r.dsConn.emit(ConnectionEvent{Type: ConnectionEventStreamClosed, StatementID: r.statementID, QueryID: r.StreamingQueryID(), Dataplane: r.dataplane})
+		if r.release != nil {
+			r.release()
+		}
+	})
+	return err
 }
 
 func (r *streamingRows) Columns() []string {
@@ -357,76 +825,44 @@ func (r *streamingRows) ColumnTypeLength(index int) (length int64, ok bool) {
 func (r *streamingRows) Next(dest []driver.Value) error {
 	var rowData *PrintTopicDataMessage
 	var open bool
-	var err error
 
+	if r.closed.Load() {
+		return &ErrStreamClosed{}
+	}
+	// rows buffered when the stream was stopped, e.g. by Conn.Close, are discarded
+	if r.closing() {
+		return io.EOF
+	}
 	select {
 	case <-r.ctx.Done():
-		if err = r.conn.Close(); err != nil {
-			return &ErrInterfaceError{message: "error while closing connection", wrapErr: err}
-		}
-		return nil
+		return r.ctx.Err()
 	case rowData, open = <-r.dataChan:
 		if !open {
+			if r.readErr != nil {
+				return r.readErr
+			}
 			return io.EOF
 		}
-	case err = <-r.errChan:
-		return err
 	}
 
-	if len(rowData.Data) != len(dest) {
-		return &ErrClientError{message: fmt.Sprintf("number of columns does not match size of result slice. expected %d, got %d", len(rowData.Data), len(dest))}
-	}
+	return r.decodeData(rowData, dest)
+}
 
-	for idx, col := range r.metadata.Columns {
-		switch {
-		case rowData.Data[idx] == nil:
-			dest[idx] = nil
-		default:
-			fallthrough
-		case // as parsed by the server
-			strings.HasPrefix(col.Type, "VARCHAR"),
-			col.Type == "DATE",
-			strings.HasPrefix(col.Type, "ARRAY"),
-			strings.HasPrefix(col.Type, "MAP"),
-			strings.HasPrefix(col.Type, "STRUCT"):
-			dest[idx] = *rowData.Data[idx]
-		case
-			col.Type == "TINYINT",
-			col.Type == "SMALLINT",
-			col.Type == "INTEGER":
-			dest[idx], err = strconv.ParseInt(*rowData.Data[idx], 10, 64)
-			if err != nil {
-				return err
-			}
-		case col.Type == "BIGINT":
-			flt, _, err := big.ParseFloat(*rowData.Data[idx], 10, 0, big.ToNearestEven)
-			if err != nil {
-				return err
-			}
-			dest[idx], _ = flt.Int(new(big.Int))
-		case
-			col.Type == "FLOAT",
-			col.Type == "DOUBLE",
-			strings.HasPrefix(col.Type, "DECIMAL"):
-			dest[idx], err = strconv.ParseFloat(*rowData.Data[idx], 64)
-			if err != nil {
-				return err
-			}
-		case strings.HasPrefix(col.Type, "TIME"):
-			dest[idx], err = parseTime(*rowData.Data[idx], col.Type)
-			if err != nil {
-				return err
-			}
-		case
-			col.Type == "VARBINARY",
-			col.Type == "BYTES":
-			dest[idx], err = base64.StdEncoding.DecodeString(*rowData.Data[idx])
-			if err != nil {
-				return err
-			}
-		case col.Type == "BOOLEAN":
-			dest[idx] = strings.ToLower(*rowData.Data[idx]) == "true"
+// decodeData decodes the row of a data message into dest.
+func (r *streamingRows) decodeData(rowData *PrintTopicDataMessage, dest []driver.Value) error {
+	r.rowsRead++
+	if r.decoders == nil {
+		colNames := make([]string, len(r.metadata.Columns))
+		colTypes := make([]string, len(r.metadata.Columns))
+		for i, col := range r.metadata.Columns {
+			colNames[i] = col.Name
+			colTypes[i] = col.Type
 		}
+		r.decoders = newColumnDecoders(colNames, colTypes, r.decodeOptions)
+	}
+	if err := decodeRow(r.decoders, rowData.Data, dest); err != nil {
+		r.stats.decodeError()
+		return r.streamError(err)
 	}
 	return nil
 }
@@ -0,0 +1,124 @@
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package godeltastream
+
+import (
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Compile time validation that our types implement the expected interfaces
+var (
+	_ StreamStatsProvider = &streamingRows{}
+)
+
+// streamTimestampHeader is the message header carrying the event time of streamed records, in milliseconds since the
+// epoch.
+const streamTimestampHeader = "timestamp"
+
+// StreamStats are the counters of a streaming result.
+type StreamStats struct {
+	// QueryID is the id of the query producing the stream, empty if the stream is not produced by a query.
+	QueryID string
+	// MessagesReceived is the number of messages received from the server.
+	MessagesReceived int64
+	// BytesReceived is the size of the messages received from the server.
+	BytesReceived int64
+	// DecodeErrors is the number of messages or rows that could not be decoded.
+	DecodeErrors int64
+	// BufferHighWatermark is the largest number of rows buffered while waiting for Next.
+	BufferHighWatermark int
+	// LastMessageAt is when the last message was received, zero if none was.
+	LastMessageAt time.Time
+	// Lag is the delay between the event time of the last record and its reception, zero if records do not carry an
+	// event time.
+	Lag time.Duration
+}
+
+// StreamStatsProvider is implemented by the driver.Rows of streaming queries. database/sql does not expose the
+// driver.Rows it wraps, use sql.Conn.Raw and Conn.QueryContext to access it, or WithMetricsCollector.
+type StreamStatsProvider interface {
+	StreamStats() StreamStats
+}
+
+// MetricsCollector receives the counters of streaming results, e.g. to export them to a monitoring system.
+type MetricsCollector interface {
+	// StreamOpened is called when a stream starts. stats returns the current counters of the stream until it is
+	// closed.
+	StreamOpened(statementID string, stats func() StreamStats)
+	// StreamClosed is called with the final counters once the stream is closed.
+	StreamClosed(statementID string, stats StreamStats)
+}
+
+// streamStats are the counters of a stream, updated by the reader goroutine and read concurrently.
+type streamStats struct {
+	sync.Mutex
+	stats StreamStats
+}
+
+func (s *streamStats) messageReceived(size int) {
+	s.Lock()
+	defer s.Unlock()
+	s.stats.MessagesReceived++
+	s.stats.BytesReceived += int64(size)
+	s.stats.LastMessageAt = time.Now()
+}
+
+func (s *streamStats) decodeError() {
+	s.Lock()
+	defer s.Unlock()
+	s.stats.DecodeErrors++
+}
+
+func (s *streamStats) buffered(n int) {
+	s.Lock()
+	defer s.Unlock()
+	if n > s.stats.BufferHighWatermark {
+		s.stats.BufferHighWatermark = n
+	}
+}
+
+// recordReceived updates the lag from the event time in the headers of a data message, if any.
+func (s *streamStats) recordReceived(headers map[string]string) {
+	t, ok := eventTime(headers)
+	if !ok {
+		return
+	}
+	s.Lock()
+	defer s.Unlock()
+	s.stats.Lag = time.Since(t)
+}
+
+// eventTime returns the event time in the headers of a data message, if any.
+func eventTime(headers map[string]string) (time.Time, bool) {
+	v, ok := headers[streamTimestampHeader]
+	if !ok {
+		return time.Time{}, false
+	}
+	ms, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.UnixMilli(ms), true
+}
+
+func (s *streamStats) snapshot() StreamStats {
+	s.Lock()
+	defer s.Unlock()
+	return s.stats
+}
@@ -0,0 +1,146 @@
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package godeltastream
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Compile time validation that our types implement the expected interfaces
+var (
+	_ sql.Scanner   = &TimeOfDay{}
+	_ driver.Valuer = TimeOfDay{}
+	_ fmt.Stringer  = TimeOfDay{}
+)
+
+// TimeOfDay is the value of a TIME column: a wall clock time without a date.
+type TimeOfDay struct {
+	// Nanoseconds elapsed since midnight.
+	Nanoseconds int64
+	// Location is the fixed zone of TIME values carrying a UTC offset and nil otherwise.
+	Location *time.Location
+}
+
+// NewTimeOfDay returns the TimeOfDay for the given clock reading.
+func NewTimeOfDay(hour, min, sec, nsec int) TimeOfDay {
+	d := time.Duration(hour)*time.Hour + time.Duration(min)*time.Minute + time.Duration(sec)*time.Second + time.Duration(nsec)
+	return TimeOfDay{Nanoseconds: int64(d)}
+}
+
+// ParseTimeOfDay parses a TIME value in the form 15:04:05[.999999999][Z07:00].
+func ParseTimeOfDay(s string) (TimeOfDay, error) {
+	clock, zone := s, ""
+	if i := strings.IndexAny(s, "Z+-"); i >= 0 {
+		clock, zone = s[:i], s[i:]
+	}
+
+	layout := "15:04:05"
+	if strings.Contains(clock, ".") {
+		layout += ".999999999"
+	}
+	t, err := time.Parse(layout, clock)
+	if err != nil {
+		return TimeOfDay{}, err
+	}
+	tod := NewTimeOfDay(t.Hour(), t.Minute(), t.Second(), t.Nanosecond())
+
+	if zone != "" {
+		z, err := time.Parse("Z07:00", zone)
+		if err != nil {
+			if z, err = time.Parse("Z0700", zone); err != nil {
+				return TimeOfDay{}, err
+			}
+		}
+		_, offset := z.Zone()
+		tod.Location = time.FixedZone("", offset)
+	}
+	return tod, nil
+}
+
+// Hour returns the hour within the day, in the range [0, 23].
+func (t TimeOfDay) Hour() int {
+	return int(time.Duration(t.Nanoseconds) / time.Hour)
+}
+
+// Minute returns the minute offset within the hour, in the range [0, 59].
+func (t TimeOfDay) Minute() int {
+	return int(time.Duration(t.Nanoseconds) % time.Hour / time.Minute)
+}
+
+// Second returns the second offset within the minute, in the range [0, 59].
+func (t TimeOfDay) Second() int {
+	return int(time.Duration(t.Nanoseconds) % time.Minute / time.Second)
+}
+
+// Nanosecond returns the nanosecond offset within the second, in the range [0, 999999999].
+func (t TimeOfDay) Nanosecond() int {
+	return int(time.Duration(t.Nanoseconds) % time.Second)
+}
+
+// On returns the time at which the time of day occurs on the date of d. The location of the TimeOfDay is used when
+// set, otherwise the location of d.
+func (t TimeOfDay) On(d time.Time) time.Time {
+	loc := t.Location
+	if loc == nil {
+		loc = d.Location()
+	}
+	return time.Date(d.Year(), d.Month(), d.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), loc)
+}
+
+// String returns the time in the form 15:04:05.999999999, followed by the UTC offset if the time carries one.
+func (t TimeOfDay) String() string {
+	s := fmt.Sprintf("%02d:%02d:%02d", t.Hour(), t.Minute(), t.Second())
+	if ns := t.Nanosecond(); ns != 0 {
+		s += strings.TrimRight(fmt.Sprintf(".%09d", ns), "0")
+	}
+	if t.Location != nil {
+		s += time.Date(0, 1, 1, 0, 0, 0, 0, t.Location).Format("Z07:00")
+	}
+	return s
+}
+
+// Value implements driver.Valuer.
+func (t TimeOfDay) Value() (driver.Value, error) {
+	return t.String(), nil
+}
+
+// Scan implements sql.Scanner.
+func (t *TimeOfDay) Scan(src any) error {
+	var err error
+	switch v := src.(type) {
+	case TimeOfDay:
+		*t = v
+	case time.Time:
+		*t = NewTimeOfDay(v.Hour(), v.Minute(), v.Second(), v.Nanosecond())
+	case string:
+		*t, err = ParseTimeOfDay(v)
+	case []byte:
+		*t, err = ParseTimeOfDay(string(v))
+	default:
+		return fmt.Errorf("unsupported Scan, storing driver.Value type %T into type *TimeOfDay", src)
+	}
+	return err
+}
+
+// isTimeOfDayColumn returns true for TIME columns, which are decoded into TimeOfDay values.
+func isTimeOfDayColumn(colType string) bool {
+	return colType == "TIME" || strings.HasPrefix(colType, "TIME(")
+}
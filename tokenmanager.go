@@ -19,6 +19,8 @@ package godeltastream
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"sync"
 	"time"
 
 	"golang.org/x/oauth2"
@@ -32,6 +34,31 @@ type TokenManager interface {
 	Token() (*oauth2.Token, error)
 }
 
+// TokenRefresher is implemented by TokenManagers that can replace a token the server rejected before it expires.
+// Statements failing with SqlStateInvalidApiToken or an HTTP 401 response are retried once after ForceRefresh.
+type TokenRefresher interface {
+	ForceRefresh(context.Context) error
+}
+
+// AuthHeaderProvider returns the headers authenticating a request, e.g. the API key expected by a gateway in front of
+// the API, see WithAuthHeaderProvider.
+type AuthHeaderProvider func(ctx context.Context) (http.Header, error)
+
+// apply sets the headers of p on h, replacing headers of the same name.
+func (p AuthHeaderProvider) apply(ctx context.Context, h http.Header) error {
+	if p == nil {
+		return nil
+	}
+	headers, err := p(ctx)
+	if err != nil {
+		return &ErrClientError{message: "unable to get authentication headers", wrapErr: err}
+	}
+	for k, v := range headers {
+		h[http.CanonicalHeaderKey(k)] = v
+	}
+	return nil
+}
+
 type AuthClient interface {
 	Login(context.Context) (*TokenInfo, error)
 	RefreshToken(ctx context.Context, refreshToken string) (*TokenInfo, error)
@@ -46,12 +73,16 @@ type TokenInfo struct {
 	ExpiresAt uint64 `json:"expires_at"`
 }
 
-var _ oauth2.TokenSource = &tokenManager{}
+var (
+	_ oauth2.TokenSource = &tokenManager{}
+	_ TokenRefresher     = &tokenManager{}
+)
 
 type tokenManager struct {
 	authClient AuthClient
 	tokenInfo  *TokenInfo
 	ctx        context.Context
+	mu         sync.Mutex
 }
 
 func NewStaticTokenManager(ctx context.Context, token string) TokenManager {
@@ -76,6 +107,8 @@ func (t *tokenManager) Token() (*oauth2.Token, error) {
 	if err != nil {
 		return nil, err
 	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
 	return &oauth2.Token{
 		AccessToken:  t.tokenInfo.AccessToken,
 		RefreshToken: t.tokenInfo.RefreshToken,
@@ -84,6 +117,8 @@ func (t *tokenManager) Token() (*oauth2.Token, error) {
 }
 
 func (t *tokenManager) GetToken(ctx context.Context) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
 	if t.tokenInfo.AccessToken == "" {
 		ti, err := t.authClient.Login(ctx)
 		if err != nil {
@@ -108,3 +143,27 @@ func (t *tokenManager) GetToken(ctx context.Context) (string, error) {
 
 	return t.tokenInfo.AccessToken, nil
 }
+
+// ForceRefresh implements TokenRefresher. The access token is refreshed with the refresh token if there is one, or
+// through the login flow otherwise. Static tokens cannot be refreshed.
+func (t *tokenManager) ForceRefresh(ctx context.Context) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.authClient == nil {
+		return ErrNotSupported
+	}
+	var (
+		ti  *TokenInfo
+		err error
+	)
+	if t.tokenInfo.RefreshToken != "" {
+		ti, err = t.authClient.RefreshToken(ctx, t.tokenInfo.RefreshToken)
+	} else {
+		ti, err = t.authClient.Login(ctx)
+	}
+	if err != nil {
+		return err
+	}
+	t.tokenInfo = ti
+	return nil
+}
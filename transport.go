@@ -0,0 +1,163 @@
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package godeltastream
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+)
+
+// WithIdleConnTimeout closes idle connections to the control plane and dataplanes after timeout.
+func WithIdleConnTimeout(timeout time.Duration) func(*connectionOptions) {
+	return func(o *connectionOptions) {
+		o.idleConnTimeout = timeout
+	}
+}
+
+// WithMaxIdleConnsPerHost keeps up to n idle connections to each of the control plane and dataplanes.
+func WithMaxIdleConnsPerHost(n int) func(*connectionOptions) {
+	return func(o *connectionOptions) {
+		o.maxIdleConnsPerHost = n
+	}
+}
+
+// WithTLSHandshakeTimeout fails connections to the control plane and dataplanes whose TLS handshake takes longer than
+// timeout.
+func WithTLSHandshakeTimeout(timeout time.Duration) func(*connectionOptions) {
+	return func(o *connectionOptions) {
+		o.tlsHandshakeTimeout = timeout
+	}
+}
+
+// WithDataplaneTLSConfig uses config for the TLS connections to dataplanes, both for result sets and streaming results,
+// instead of the TLS settings of the http client used for the control plane. This is needed for deployments whose
+// dataplanes use certificates of an internal authority or hostnames, while the control plane uses public certificates.
+// Set ServerName to verify dataplane certificates against another hostname.
+func WithDataplaneTLSConfig(config *tls.Config) func(*connectionOptions) {
+	return func(o *connectionOptions) {
+		o.dataplaneTLSConfig = config
+	}
+}
+
+// newDefaultTransport returns a copy of http.DefaultTransport, keeping its timeouts, idle connection limits and
+// HTTP/2 support. The equivalent settings are used if http.DefaultTransport was replaced, e.g. by a mock.
+func newDefaultTransport() *http.Transport {
+	if t, ok := http.DefaultTransport.(*http.Transport); ok {
+		return t.Clone()
+	}
+	return &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           (&net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}).DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+}
+
+// customizesTransport returns whether any of the options apply to the transport of the http client.
+func (o *connectionOptions) customizesTransport() bool {
+	return o.insecureTLS || o.unixSocket != "" || o.proxyURL != nil || o.proxyConnectHeader != nil ||
+		o.idleConnTimeout != 0 || o.maxIdleConnsPerHost != 0 || o.tlsHandshakeTimeout != 0
+}
+
+// configureTransport applies the transport options to a copy of the http client and its transport, so that
+// caller-owned clients and http.DefaultClient are never modified.
+func (o *connectionOptions) configureTransport() error {
+	var transport *http.Transport
+	switch t := o.httpClient.Transport.(type) {
+	case nil:
+		transport = newDefaultTransport()
+	case *http.Transport:
+		transport = t.Clone()
+	default:
+		return &ErrClientError{message: fmt.Sprintf("cannot apply transport options to httpClient.Transport of type %T", t)}
+	}
+
+	if o.proxyConnectHeader != nil {
+		transport.ProxyConnectHeader = o.proxyConnectHeader
+	}
+	switch {
+	case o.proxyURL != nil:
+		transport.Proxy = http.ProxyURL(o.proxyURL)
+	case o.unixSocket != "":
+		transport.Proxy = nil
+	}
+	if o.unixSocket != "" {
+		transport.DialContext = unixSocketDialer(o.unixSocket)
+	}
+	if transport.TLSClientConfig == nil {
+		transport.TLSClientConfig = &tls.Config{}
+	}
+	// resume TLS sessions when reconnecting, e.g. after idle connections were closed
+	if transport.TLSClientConfig.ClientSessionCache == nil {
+		transport.TLSClientConfig.ClientSessionCache = tls.NewLRUClientSessionCache(0)
+	}
+	if o.insecureTLS {
+		transport.TLSClientConfig.InsecureSkipVerify = true
+	}
+	if o.idleConnTimeout != 0 {
+		transport.IdleConnTimeout = o.idleConnTimeout
+	}
+	if o.maxIdleConnsPerHost != 0 {
+		transport.MaxIdleConnsPerHost = o.maxIdleConnsPerHost
+	}
+	if o.tlsHandshakeTimeout != 0 {
+		transport.TLSHandshakeTimeout = o.tlsHandshakeTimeout
+	}
+
+	client := *o.httpClient
+	client.Transport = transport
+	o.httpClient = &client
+	return nil
+}
+
+// dataplaneHTTPClient returns a copy of the http client using the TLS config set with WithDataplaneTLSConfig, and
+// verifying the certificates pinned with WithPinnedCertificates.
+func (o *connectionOptions) dataplaneHTTPClient() (*http.Client, error) {
+	pins, err := parsePinnedCertificates(o.pinnedCertificates)
+	if err != nil {
+		return nil, err
+	}
+
+	var transport *http.Transport
+	switch t := o.httpClient.Transport.(type) {
+	case nil:
+		transport = newDefaultTransport()
+	case *http.Transport:
+		transport = t.Clone()
+	default:
+		return nil, &ErrClientError{message: fmt.Sprintf("cannot apply dataplane TLS settings to httpClient.Transport of type %T", t)}
+	}
+	if o.dataplaneTLSConfig != nil {
+		transport.TLSClientConfig = o.dataplaneTLSConfig.Clone()
+	}
+	if len(pins) > 0 {
+		if transport.TLSClientConfig == nil {
+			transport.TLSClientConfig = &tls.Config{}
+		}
+		pinCertificates(transport.TLSClientConfig, pins)
+	}
+
+	client := *o.httpClient
+	client.Transport = transport
+	return &client, nil
+}
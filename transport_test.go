@@ -0,0 +1,145 @@
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package godeltastream
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"database/sql"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+	. "github.com/onsi/gomega"
+
+	"github.com/deltastreaminc/go-deltastream/dstest"
+)
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestTransportOptionsKeepDefaultClient(t *testing.T) {
+	g := NewWithT(t)
+
+	connector, err := ConnectorWithOptions(context.TODO(), WithStaticToken("sometoken"), WithInsecureTLS(), WithIdleConnTimeout(time.Minute))
+	g.Expect(err).To(BeNil())
+	g.Expect(http.DefaultClient.Transport).To(BeNil())
+	g.Expect(connector.opts.httpClient).NotTo(BeIdenticalTo(http.DefaultClient))
+
+	transport, ok := connector.opts.httpClient.Transport.(*http.Transport)
+	g.Expect(ok).To(BeTrue())
+	g.Expect(transport.TLSClientConfig.InsecureSkipVerify).To(BeTrue())
+	g.Expect(transport.IdleConnTimeout).To(Equal(time.Minute))
+	g.Expect(transport.Proxy).NotTo(BeNil())
+	// defaults of http.DefaultTransport are kept
+	g.Expect(transport.ForceAttemptHTTP2).To(BeTrue())
+	g.Expect(transport.TLSHandshakeTimeout).To(Equal(10 * time.Second))
+	g.Expect(transport.MaxIdleConns).To(Equal(100))
+	g.Expect(transport.DialContext).NotTo(BeNil())
+}
+
+func TestTransportOptionsCloneCustomTransport(t *testing.T) {
+	g := NewWithT(t)
+
+	owned := &http.Transport{
+		TLSClientConfig:     &tls.Config{ServerName: "api.deltastream.io"},
+		MaxIdleConnsPerHost: 4,
+	}
+	client := &http.Client{Transport: owned, Timeout: time.Minute}
+	connector, err := ConnectorWithOptions(context.TODO(),
+		WithStaticToken("sometoken"),
+		WithHTTPClient(client),
+		WithProxy(&url.URL{Scheme: "http", Host: "proxy:3128"}),
+		WithInsecureTLS(),
+		WithTLSHandshakeTimeout(5*time.Second),
+	)
+	g.Expect(err).To(BeNil())
+
+	// the caller's client and transport are left untouched
+	g.Expect(client.Transport).To(BeIdenticalTo(owned))
+	g.Expect(owned.Proxy).To(BeNil())
+	g.Expect(owned.TLSClientConfig.InsecureSkipVerify).To(BeFalse())
+	g.Expect(owned.TLSClientConfig.ClientSessionCache).To(BeNil())
+	g.Expect(owned.TLSHandshakeTimeout).To(BeZero())
+
+	g.Expect(connector.opts.httpClient).NotTo(BeIdenticalTo(client))
+	g.Expect(connector.opts.httpClient.Timeout).To(Equal(time.Minute))
+	transport, ok := connector.opts.httpClient.Transport.(*http.Transport)
+	g.Expect(ok).To(BeTrue())
+	g.Expect(transport).NotTo(BeIdenticalTo(owned))
+	g.Expect(transport.TLSClientConfig.ServerName).To(Equal("api.deltastream.io"))
+	g.Expect(transport.TLSClientConfig.InsecureSkipVerify).To(BeTrue())
+	g.Expect(transport.TLSHandshakeTimeout).To(Equal(5 * time.Second))
+	g.Expect(transport.MaxIdleConnsPerHost).To(Equal(4))
+	proxyURL, err := transport.Proxy(&http.Request{URL: &url.URL{Scheme: "https", Host: "api.deltastream.io"}})
+	g.Expect(err).To(BeNil())
+	g.Expect(proxyURL.Host).To(Equal("proxy:3128"))
+}
+
+func TestTransportOptionsUnsupportedTransport(t *testing.T) {
+	g := NewWithT(t)
+
+	_, err := ConnectorWithOptions(context.TODO(),
+		WithStaticToken("sometoken"),
+		WithHTTPClient(&http.Client{Transport: roundTripperFunc(http.DefaultTransport.RoundTrip)}),
+		WithProxy(&url.URL{Scheme: "http", Host: "proxy:3128"}),
+	)
+	g.Expect(err).To(MatchError(&ErrClientError{message: "cannot apply transport options to httpClient.Transport of type godeltastream.roundTripperFunc"}))
+}
+
+func TestDataplaneTLSConfig(t *testing.T) {
+	g := NewWithT(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	// the dataplane uses a certificate of an internal authority, the control plane is mocked
+	stream := dstest.NewStreamingServer(dstest.Metadata(streamingColumns...), dstest.Row("1", "a"))
+	defer stream.Close()
+	dataplane := httptest.NewUnstartedServer(stream.Config.Handler)
+	dataplane.Config.ErrorLog = log.New(io.Discard, "", 0)
+	dataplane.StartTLS()
+	defer dataplane.Close()
+	rs := stream.StatementResponse(nil)
+	rs.Metadata.DataplaneRequest.Uri = dataplane.URL
+	httpmock.RegisterResponder("POST", "https://api.deltastream.io/v2/statements", httpmock.NewJsonResponderOrPanic(http.StatusOK, rs))
+
+	roots := x509.NewCertPool()
+	roots.AddCert(dataplane.Certificate())
+	connector, err := ConnectorWithOptions(context.TODO(), WithServer("https://api.deltastream.io/v2"), WithStaticToken("sometoken"), WithDataplaneTLSConfig(&tls.Config{RootCAs: roots, ServerName: "example.com"}))
+	g.Expect(err).To(BeNil())
+	rows, err := sql.OpenDB(connector).Query("SELECT * FROM pageviews;")
+	g.Expect(err).To(BeNil())
+	defer rows.Close()
+	g.Expect(rows.Next()).To(BeTrue())
+
+	// the settings of the control plane do not trust the dataplane
+	connector, err = ConnectorWithOptions(context.TODO(), WithServer("https://api.deltastream.io/v2"), WithStaticToken("sometoken"))
+	g.Expect(err).To(BeNil())
+	_, err = sql.OpenDB(connector).Query("SELECT * FROM pageviews;")
+	var certErr *tls.CertificateVerificationError
+	g.Expect(errors.As(err, &certErr)).To(BeTrue())
+}
@@ -0,0 +1,326 @@
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package godeltastream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultValueWriterMaxRows       = 500
+	defaultValueWriterMaxBytes      = 1 << 20
+	defaultValueWriterFlushInterval = time.Second
+	defaultValueWriterRetryBackoff  = 100 * time.Millisecond
+)
+
+// ValueWriterOptions configure a ValueWriter.
+type ValueWriterOptions struct {
+	// Columns are the columns of the relation the values of rows are written to, in order. Rows have a value for every
+	// column of the relation, in the order of its definition, if empty.
+	Columns []string
+	// MaxRows is the number of rows of an INSERT statement, 500 if zero. Rows are flushed once that many are buffered.
+	MaxRows int
+	// MaxBytes bounds the size of an INSERT statement, 1MiB if zero. Rows are flushed before the statement would grow
+	// larger, a single row larger than MaxBytes is written on its own.
+	MaxBytes int
+	// FlushInterval is the delay after which buffered rows are flushed even if MaxRows and MaxBytes are not reached,
+	// 1s if zero. Negative intervals disable time based flushing.
+	FlushInterval time.Duration
+	// Retries is the number of times an INSERT statement is submitted again if the service is unavailable or the
+	// relation is not ready. Other errors, e.g. invalid values, are not retried.
+	Retries int
+	// RetryBackoff is the delay before the first retry, doubled for every retry, 100ms if zero.
+	RetryBackoff time.Duration
+}
+
+// ValueWriterStats are the counters of a ValueWriter.
+type ValueWriterStats struct {
+	// RowsAppended is the number of rows passed to Append.
+	RowsAppended int64
+	// RowsWritten is the number of rows of INSERT statements that succeeded.
+	RowsWritten int64
+	// RowsFailed is the number of rows of INSERT statements that failed after their retries.
+	RowsFailed int64
+	// Statements is the number of INSERT statements that succeeded.
+	Statements int64
+	// Retries is the number of INSERT statements submitted again.
+	Retries int64
+	// LastFlushDuration is how long the last INSERT statement took, including its retries.
+	LastFlushDuration time.Duration
+}
+
+// ErrValueWrite is returned when the INSERT statement of a batch of rows of a ValueWriter fails after its retries. The
+// rows of the batch are dropped.
+type ErrValueWrite struct {
+	// Rows is the number of rows of the batch.
+	Rows    int
+	wrapErr error
+}
+
+func (e *ErrValueWrite) Error() string {
+	return fmt.Sprintf("unable to write %d rows: %s", e.Rows, e.wrapErr)
+}
+
+func (e *ErrValueWrite) Unwrap() error {
+	return e.wrapErr
+}
+
+// ValueWriter batches rows into INSERT statements written to a relation, see NewValueWriter. Its methods are safe for
+// concurrent use.
+type ValueWriter struct {
+	ctx    context.Context
+	db     Execer
+	opts   ValueWriterOptions
+	prefix string // INSERT INTO relation (columns) VALUES
+
+	// mu is held while rows are buffered and flushed, so that Append waits for the flush in progress
+	mu     sync.Mutex
+	batch  strings.Builder
+	rows   int
+	err    error // error of the last time based flush, returned by the next call
+	closed bool
+
+	statsMu sync.Mutex
+	stats   ValueWriterStats
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewValueWriter returns a writer batching the rows passed to Append into INSERT statements executed on db, e.g. to
+// produce records into a stream. relation and the columns of opts are used as is, so they must be quoted if needed.
+// Statements are executed with ctx, cancelling it stops time based flushing. Close the writer to write the remaining
+// rows.
+//
+// Append blocks while rows are flushed, so producers are slowed down to the rate the server accepts rows at.
+func NewValueWriter(ctx context.Context, db Execer, relation string, opts ValueWriterOptions) *ValueWriter {
+	if opts.MaxRows <= 0 {
+		opts.MaxRows = defaultValueWriterMaxRows
+	}
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = defaultValueWriterMaxBytes
+	}
+	if opts.FlushInterval == 0 {
+		opts.FlushInterval = defaultValueWriterFlushInterval
+	}
+	if opts.RetryBackoff <= 0 {
+		opts.RetryBackoff = defaultValueWriterRetryBackoff
+	}
+	prefix := "INSERT INTO " + relation
+	if len(opts.Columns) > 0 {
+		prefix += " (" + strings.Join(opts.Columns, ", ") + ")"
+	}
+
+	w := &ValueWriter{ctx: ctx, db: db, opts: opts, prefix: prefix + " VALUES ", stop: make(chan struct{}), done: make(chan struct{})}
+	if opts.FlushInterval > 0 {
+		go w.flushPeriodically()
+	} else {
+		close(w.done)
+	}
+	return w
+}
+
+// Append buffers rows, each the values of the columns of the relation, and flushes them once MaxRows or MaxBytes is
+// reached. Values are encoded as statement arguments are, see FormatLiteral. It returns the error of the first flush
+// that failed, including time based flushes since the last call, in which case rows of the call may have been written.
+func (w *ValueWriter) Append(rows ...[]any) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return &ErrClientError{message: "value writer is closed"}
+	}
+	if err := w.takeErr(); err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		tuple, err := w.formatRow(row)
+		if err != nil {
+			return err
+		}
+		w.updateStats(func(s *ValueWriterStats) { s.RowsAppended++ })
+		if w.rows > 0 && w.batch.Len()+len(", ")+len(tuple)+len(";") > w.opts.MaxBytes {
+			if err := w.flush(); err != nil {
+				return err
+			}
+		}
+		if w.rows == 0 {
+			w.batch.WriteString(w.prefix)
+		} else {
+			w.batch.WriteString(", ")
+		}
+		w.batch.WriteString(tuple)
+		w.rows++
+		if w.rows >= w.opts.MaxRows {
+			if err := w.flush(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// formatRow returns the values of row as the tuple of an INSERT statement.
+func (w *ValueWriter) formatRow(row []any) (string, error) {
+	if len(w.opts.Columns) > 0 && len(row) != len(w.opts.Columns) {
+		return "", &ErrClientError{message: fmt.Sprintf("expected %d values, got %d", len(w.opts.Columns), len(row))}
+	}
+	var b strings.Builder
+	b.WriteString("(")
+	for i, v := range row {
+		lit, err := FormatLiteral(v)
+		if err != nil {
+			return "", &ErrClientError{message: fmt.Sprintf("cannot write value %d", i+1), wrapErr: err}
+		}
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(string(lit))
+	}
+	b.WriteString(")")
+	return b.String(), nil
+}
+
+// Flush writes the buffered rows.
+func (w *ValueWriter) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.takeErr(); err != nil {
+		return err
+	}
+	return w.flush()
+}
+
+// Close writes the buffered rows and stops the writer. It returns the error of the last time based flush, if it was
+// not returned yet, or of writing the buffered rows.
+func (w *ValueWriter) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	w.mu.Unlock()
+	close(w.stop)
+	<-w.done
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.takeErr(); err != nil {
+		return errors.Join(err, w.flush())
+	}
+	return w.flush()
+}
+
+// Stats returns the current counters of the writer.
+func (w *ValueWriter) Stats() ValueWriterStats {
+	w.statsMu.Lock()
+	defer w.statsMu.Unlock()
+	return w.stats
+}
+
+func (w *ValueWriter) updateStats(update func(s *ValueWriterStats)) {
+	w.statsMu.Lock()
+	defer w.statsMu.Unlock()
+	update(&w.stats)
+}
+
+func (w *ValueWriter) takeErr() error {
+	err := w.err
+	w.err = nil
+	return err
+}
+
+// flushPeriodically flushes the buffered rows every FlushInterval until the writer is closed or its context is done.
+func (w *ValueWriter) flushPeriodically() {
+	defer close(w.done)
+	t := time.NewTicker(w.opts.FlushInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-w.ctx.Done():
+			return
+		case <-t.C:
+			w.mu.Lock()
+			if err := w.flush(); err != nil && w.err == nil {
+				w.err = err
+			}
+			w.mu.Unlock()
+		}
+	}
+}
+
+// flush executes the INSERT statement of the buffered rows, retrying it as configured. The rows are dropped either
+// way. w.mu must be held.
+func (w *ValueWriter) flush() error {
+	if w.rows == 0 {
+		return nil
+	}
+	statement := w.batch.String() + ";"
+	rows := w.rows
+	w.batch.Reset()
+	w.rows = 0
+
+	start := time.Now()
+	backoff := w.opts.RetryBackoff
+	var err error
+	for attempt := 0; ; attempt++ {
+		if _, err = w.db.ExecContext(w.ctx, statement); err == nil || attempt == w.opts.Retries || !retryableWriteError(err) {
+			break
+		}
+		w.updateStats(func(s *ValueWriterStats) { s.Retries++ })
+		t := time.NewTimer(backoff)
+		select {
+		case <-w.ctx.Done():
+			t.Stop()
+			err = w.ctx.Err()
+		case <-t.C:
+		}
+		if w.ctx.Err() != nil {
+			break
+		}
+		backoff *= 2
+	}
+
+	w.updateStats(func(s *ValueWriterStats) {
+		s.LastFlushDuration = time.Since(start)
+		if err != nil {
+			s.RowsFailed += int64(rows)
+		} else {
+			s.RowsWritten += int64(rows)
+			s.Statements++
+		}
+	})
+	if err != nil {
+		return &ErrValueWrite{Rows: rows, wrapErr: err}
+	}
+	return nil
+}
+
+// retryableWriteError returns whether an INSERT statement may be submitted again without writing its rows twice: the
+// service was unavailable or the relation is not ready yet.
+func retryableWriteError(err error) bool {
+	var sqlErr ErrSQLError
+	return errors.Is(err, ErrServiceUnavailable) || errors.As(err, &sqlErr) && sqlErr.SQLCode.Class() == sqlStateClassResourceNotReady
+}
@@ -0,0 +1,65 @@
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package godeltastream
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// WarmUp establishes connections to the control plane ahead of the first statement, e.g. during the cold start of a
+// serverless function, and obtains an api token from the token manager. dataplaneURLs are also connected to, if the
+// dataplanes serving results are known in advance. Connections are kept in the idle pool of the http client for the
+// first statements to reuse.
+func (c *connector) WarmUp(ctx context.Context, dataplaneURLs ...string) error {
+	rsp, err := c.client.GetVersion(ctx)
+	if err != nil {
+		return &ErrInterfaceError{wrapErr: err, message: "unable to send request to server"}
+	}
+	drainBody(rsp)
+	c.opts.attachmentCompression.observe(rsp)
+	if rsp.StatusCode != http.StatusOK {
+		return &ErrClientError{message: fmt.Sprintf("unable to warm up connection to server. status code: %d", rsp.StatusCode)}
+	}
+
+	for _, dp := range dataplaneURLs {
+		u, err := url.Parse(dp)
+		if err != nil {
+			return &ErrClientError{message: "invalid dataplane url", wrapErr: err}
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodHead, u.Scheme+"://"+u.Host, nil)
+		if err != nil {
+			return &ErrClientError{message: "invalid dataplane url", wrapErr: err}
+		}
+		// requests are not authenticated, any response means the connection is established
+		rsp, err := httpClientOverride(ctx, c.opts.httpClient).Do(req)
+		if err != nil {
+			return &ErrInterfaceError{wrapErr: err, message: "unable to connect to dataplane"}
+		}
+		drainBody(rsp)
+	}
+	return nil
+}
+
+// drainBody reads the remaining body of rsp and closes it, so that its connection can be reused.
+func drainBody(rsp *http.Response) {
+	_, _ = io.Copy(io.Discard, rsp.Body)
+	_ = rsp.Body.Close()
+}
@@ -0,0 +1,141 @@
+/*
+Copyright (c) 2024-present, DeltaStream Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package godeltastream
+
+import (
+	"context"
+	"database/sql"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+// connCountingServer is a tls server counting the connections it accepts.
+type connCountingServer struct {
+	*httptest.Server
+	mu    sync.Mutex
+	conns int
+}
+
+func newConnCountingServer(handler http.HandlerFunc) *connCountingServer {
+	s := &connCountingServer{}
+	s.Server = httptest.NewUnstartedServer(handler)
+	s.Config.ConnState = func(c net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			s.mu.Lock()
+			s.conns++
+			s.mu.Unlock()
+		}
+	}
+	s.StartTLS()
+	return s
+}
+
+func (s *connCountingServer) connections() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.conns
+}
+
+func TestWarmUp(t *testing.T) {
+	g := NewWithT(t)
+
+	controlPlane := newConnCountingServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/v2/version":
+			_, _ = w.Write([]byte(`{ "major": 1, "minor": 0, "patch": 0 }`))
+		case "/v2/statements":
+			b, _ := os.ReadFile("fixtures/list-organizations-200-00000-1.json")
+			_, _ = w.Write(b)
+		}
+	})
+	defer controlPlane.Close()
+	dataplane := newConnCountingServer(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+	defer dataplane.Close()
+
+	client := controlPlane.Client()
+	client.Transport.(*http.Transport).TLSClientConfig.RootCAs.AddCert(dataplane.Certificate())
+	connector, err := ConnectorWithOptions(context.TODO(), WithServer(controlPlane.URL+"/v2"), WithStaticToken("sometoken"), WithHTTPClient(client))
+	g.Expect(err).To(BeNil())
+
+	g.Expect(connector.WarmUp(context.Background(), dataplane.URL+"/v2")).To(Succeed())
+	g.Expect(controlPlane.connections()).To(Equal(1))
+	g.Expect(dataplane.connections()).To(Equal(1))
+
+	// the first statement reuses the warm connection
+	db := sql.OpenDB(connector)
+	_, err = db.Exec("LIST ORGANIZATIONS;")
+	g.Expect(err).To(BeNil())
+	g.Expect(db.Ping()).To(Succeed())
+	g.Expect(controlPlane.connections()).To(Equal(1))
+}
+
+func TestWarmUpError(t *testing.T) {
+	g := NewWithT(t)
+
+	controlPlane := newConnCountingServer(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	defer controlPlane.Close()
+
+	connector, err := ConnectorWithOptions(context.TODO(), WithServer(controlPlane.URL+"/v2"), WithStaticToken("sometoken"), WithHTTPClient(controlPlane.Client()))
+	g.Expect(err).To(BeNil())
+	g.Expect(connector.WarmUp(context.Background())).To(MatchError("unable to warm up connection to server. status code: 503"))
+}
+
+func TestTLSSessionResumption(t *testing.T) {
+	g := NewWithT(t)
+
+	var (
+		mu      sync.Mutex
+		resumed []bool
+	)
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		resumed = append(resumed, r.TLS.DidResume)
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{ "major": 1, "minor": 0, "patch": 0 }`))
+	}))
+	defer server.Close()
+
+	// the default transport trusts the server, as it would a server with a public certificate
+	defaultTransport := http.DefaultTransport
+	defer func() { http.DefaultTransport = defaultTransport }()
+	http.DefaultTransport = server.Client().Transport.(*http.Transport).Clone()
+
+	connector, err := ConnectorWithOptions(context.TODO(), WithServer(server.URL+"/v2"), WithStaticToken("sometoken"))
+	g.Expect(err).To(BeNil())
+	db := sql.OpenDB(connector)
+	defer db.Close()
+
+	g.Expect(db.Ping()).To(Succeed())
+	connector.opts.httpClient.CloseIdleConnections()
+	g.Expect(db.Ping()).To(Succeed())
+	g.Expect(resumed).To(Equal([]bool{false, true}))
+	// the default transport and client are left unchanged
+	g.Expect(http.DefaultTransport.(*http.Transport).TLSClientConfig.ClientSessionCache).To(BeNil())
+	g.Expect(http.DefaultClient.Transport).To(BeNil())
+}